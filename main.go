@@ -1,10 +1,16 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"github.com/pdok/goas/pkg/models"
 	"github.com/urfave/cli/v2"
+	"io"
 	"log"
 	"os"
+	"os/signal"
+	"strings"
+	"time"
 
 	"github.com/pdok/goas/pkg"
 	"github.com/pdok/goas/util"
@@ -13,7 +19,7 @@ import (
 func main() {
 	app := cli.NewApp()
 	app.Name = "Go OGC Api Styles Generator"
-	app.Usage = "Generates OGC API styles to local disk or remote object storage (S3 or Azure Blob)"
+	app.Usage = "Generates OGC API styles to local disk, an archive or remote object storage (S3 or Azure Blob)"
 
 	app.Flags = []cli.Flag{
 		&cli.StringFlag{
@@ -66,12 +72,61 @@ func main() {
 			Usage:   "Path where the styles land on disk (optional)",
 			EnvVars: []string{"FILE_DESTINATION"},
 		},
+		&cli.StringFlag{
+			Name:    "archive-destination",
+			Usage:   "Path of a .tar.gz, .tgz or .zip archive where the styles land (optional)",
+			EnvVars: []string{"ARCHIVE_DESTINATION"},
+		},
 		&cli.StringFlag{
 			Name:        "formats",
-			Usage:       "(stub) comma seperated list of rendered formats. Choose from: [json,]",
+			Usage:       fmt.Sprintf("comma seperated list of rendered formats. Choose from: [%s]", strings.Join(models.RenderFormatNames(), ",")),
 			EnvVars:     []string{"API_FORMATS"},
 			DefaultText: models.JsonFormat.Name,
 		},
+		&cli.BoolFlag{
+			Name:    "sync",
+			Usage:   "delete the files at the destination (under the prefix) that are not generated by this run (optional)",
+			EnvVars: []string{"SYNC"},
+		},
+		&cli.Float64Flag{
+			Name:    "sync-threshold",
+			Usage:   "the maximum fraction of the existing files --sync may delete, the run fails when more files would be deleted (optional)",
+			Value:   util.DefaultSyncThreshold,
+			EnvVars: []string{"SYNC_THRESHOLD"},
+		},
+		&cli.BoolFlag{
+			Name:    "release",
+			Usage:   "write to a new release under _releases/ and promote it (make it live) when all files are written (optional)",
+			EnvVars: []string{"RELEASE"},
+		},
+		&cli.IntFlag{
+			Name:    "keep-releases",
+			Usage:   "the number of releases kept at the destination, older releases are deleted after a release (optional)",
+			Value:   util.DefaultKeepReleases,
+			EnvVars: []string{"KEEP_RELEASES"},
+		},
+		&cli.IntFlag{
+			Name:    "concurrency",
+			Usage:   "the number of files written at the same time (optional)",
+			Value:   util.DefaultConcurrency,
+			EnvVars: []string{"CONCURRENCY"},
+		},
+		&cli.IntFlag{
+			Name:    "retries",
+			Usage:   "the number of retries, with exponential backoff, of a write that failed with a transient error (optional)",
+			Value:   util.DefaultRetries,
+			EnvVars: []string{"RETRIES"},
+		},
+		&cli.StringFlag{
+			Name:    "environment",
+			Usage:   "the environment of the config to rewrite the sprite, glyphs and source urls of Mapbox stylesheets for (optional)",
+			EnvVars: []string{"ENVIRONMENT"},
+		},
+		&cli.BoolFlag{
+			Name:    "dry-run",
+			Usage:   "print what a run would add, change and remove at the destination, without writing anything (optional)",
+			EnvVars: []string{"DRY_RUN"},
+		},
 	}
 	app.ArgsUsage = "[arguments]\n\nARGUMENTS:\n  [ASSET_DIR]: path that points to directory where the assets (styles, thumbnails) are provided\n  [CONFIG]: path to the configuration.yaml for the style generation"
 
@@ -83,6 +138,9 @@ func main() {
 			return err
 		}
 
+		if c.Bool("dry-run") {
+			return diff(context)
+		}
 		err = generate(context)
 		if err != nil {
 			return err
@@ -91,37 +149,174 @@ func main() {
 		return nil
 	}
 
-	err := app.Run(os.Args)
+	app.Commands = []*cli.Command{
+		{
+			Name:      "diff",
+			Usage:     "print what a run would add, change and remove at the destination, without writing anything",
+			ArgsUsage: "[ASSET_DIR] [CONFIG]",
+			Action: func(c *cli.Context) error {
+				context, err := util.CreateContext(c)
+				if err != nil {
+					return err
+				}
+				return diff(context)
+			},
+		},
+		{
+			Name:      "rollback",
+			Usage:     "promote (make live) a previous release, written with --release",
+			ArgsUsage: "[CONFIG]: optional, to publish the files with the headers and metadata of its publish rules",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "to",
+					Usage: "the release to promote, defaults to the release before the current one (optional)",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				context, err := util.CreateStorageContext(c)
+				if err != nil {
+					return err
+				}
+				if c.NArg() > 0 {
+					config, err := pkg.ParseConfig(c.Args().Get(0))
+					if err != nil {
+						return err
+					}
+					context.Publish = config.Publish
+				}
+				store, err := util.NewStore(context)
+				if err != nil {
+					return err
+				}
+				return util.Rollback(store, c.String("to"), context.Sync, context.SyncThreshold)
+			},
+		},
+	}
+
+	// cancel the writes on SIGINT, the files not written yet are reported
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err := app.RunContext(ctx, os.Args)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
-func generate(ctx *util.Context) error {
+// generateDocuments parses and validates the config and generates the (validated) documents, with their pre-compressed
+// variants and the manifest
+func generateDocuments(ctx *util.Context) ([]models.Document, error) {
 	config, err := pkg.ParseConfig(ctx.ConfigPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	config.Environment = ctx.Environment
 
-	err = pkg.Validate(config)
+	err = pkg.Validate(config, ctx.AssetDir)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	documents, err := pkg.GenerateDocuments(config, ctx.AssetDir, ctx.Formats)
+	if err != nil {
+		return nil, err
+	}
+	err = pkg.ValidateDocuments(documents)
+	if err != nil {
+		return nil, err
+	}
+	// the writers apply the headers and metadata of the publish rules
+	ctx.Publish = config.Publish
+	documents, err = pkg.CompressDocuments(config.Publish, documents)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := pkg.GenerateManifest(documents)
+	if err != nil {
+		return nil, err
+	}
+	return append(documents, *manifest), nil
+}
+
+func generate(ctx *util.Context) error {
+	documents, err := generateDocuments(ctx)
 	if err != nil {
 		return err
 	}
+	if ctx.Release {
+		return release(ctx, documents)
+	}
 	writer, err := util.NewWriter(ctx)
 	if err != nil {
 		return err
 	}
+	generated, err := write(ctx, writer, documents)
+	if err != nil {
+		return err
+	}
+
+	if ctx.Sync {
+		return util.Sync(writer, generated, ctx.SyncThreshold)
+	}
+	return nil
+}
+
+func write(ctx *util.Context, writer util.Writer, documents []models.Document) ([]string, error) {
+	err := util.WriteDocuments(ctx.Ctx, writer, documents,
+		util.WriteOptions{Concurrency: ctx.Concurrency, Retries: ctx.Retries, Backoff: util.DefaultBackoff})
+	// an archive is only written when it is closed
+	if closer, ok := writer.(io.Closer); ok && err == nil {
+		err = closer.Close()
+	}
+	stats := writer.Stats()
+	log.Printf("written %d files, skipped %d unchanged files", stats.Written, stats.Skipped)
+	if err != nil {
+		return nil, err
+	}
+
+	var generated []string
 	for _, document := range documents {
-		err = writer.Write(document.Path, document.Content, document.MediaType)
-		if err != nil {
-			return err
+		generated = append(generated, document.Path)
+	}
+	return generated, nil
+}
+
+// release writes the documents to a new release, which is only promoted when all documents are written
+func release(ctx *util.Context, documents []models.Document) error {
+	store, err := util.NewStore(ctx)
+	if err != nil {
+		return err
+	}
+	releaseId := util.NewReleaseId(time.Now())
+	releases, _, err := util.Releases(store)
+	if err != nil {
+		return err
+	}
+	for _, existing := range releases {
+		if existing == releaseId {
+			return fmt.Errorf("release %s already exists", releaseId)
 		}
 	}
+	log.Printf("writing release %s", releaseId)
+	_, err = write(ctx, util.NewReleaseWriter(store, releaseId), documents)
+	if err != nil {
+		return fmt.Errorf("release %s is not promoted, the live files are unchanged: %s", releaseId, err)
+	}
+	err = util.Promote(store, releaseId, ctx.Sync, ctx.SyncThreshold)
+	if err != nil {
+		return err
+	}
+	return util.PruneReleases(store, ctx.KeepReleases)
+}
 
-	return nil
+func diff(ctx *util.Context) error {
+	documents, err := generateDocuments(ctx)
+	if err != nil {
+		return err
+	}
+	reader, err := util.NewReader(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = util.Diff(reader, documents, os.Stdout)
+	return err
 }
@@ -0,0 +1,445 @@
+package convert
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pdok/goas/pkg/sld"
+)
+
+var comparisonOperators = map[string]string{
+	"==": "PropertyIsEqualTo",
+	"!=": "PropertyIsNotEqualTo",
+	"<":  "PropertyIsLessThan",
+	"<=": "PropertyIsLessThanOrEqualTo",
+	">":  "PropertyIsGreaterThan",
+	">=": "PropertyIsGreaterThanOrEqualTo",
+}
+
+// mapboxFilterToOgc converts a Mapbox filter, either the legacy filter syntax or an expression, to the operator of an
+// ogc:Filter. A nil node means the filter matches all features, conditions on the geometry type are dropped since
+// the symbolizer determines which geometries are rendered.
+func mapboxFilterToOgc(w sld.Writer, filter interface{}) (*sld.Node, error) {
+	if filter == nil {
+		return nil, nil
+	}
+	if b, ok := filter.(bool); ok {
+		if b {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("filter never matches")
+	}
+	expression, ok := filter.([]interface{})
+	if !ok || len(expression) == 0 {
+		return nil, fmt.Errorf("unsupported filter: %v", filter)
+	}
+	operator, ok := expression[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("unsupported filter: %v", filter)
+	}
+	args := expression[1:]
+	switch operator {
+	case "all":
+		return logicalFilter(w, "And", args)
+	case "any":
+		return logicalFilter(w, "Or", args)
+	case "none":
+		return noneFilter(w, args)
+	case "!":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("unsupported filter: %v", filter)
+		}
+		if alternatives, ok := args[0].([]interface{}); ok && len(alternatives) > 0 && alternatives[0] == "any" {
+			return noneFilter(w, alternatives[1:])
+		}
+		node, err := mapboxFilterToOgc(w, args[0])
+		return negate(w, node, err)
+	case "has", "!has":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("unsupported filter: %v", filter)
+		}
+		property, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("unsupported filter: %v", filter)
+		}
+		isNull := w.Ogc("PropertyIsNull", w.OgcText("PropertyName", property))
+		if operator == "has" {
+			return w.Ogc("Not", isNull), nil
+		}
+		return isNull, nil
+	case "in", "!in":
+		if len(args) < 1 {
+			return nil, fmt.Errorf("unsupported filter: %v", filter)
+		}
+		property, values, err := inArguments(args)
+		if err != nil {
+			return nil, err
+		}
+		if property == "" {
+			return nil, nil
+		}
+		var comparisons []interface{}
+		for _, value := range values {
+			comparisons = append(comparisons, []interface{}{"==", property, value})
+		}
+		node, err := logicalFilter(w, "Or", comparisons)
+		if operator == "!in" {
+			return negate(w, node, err)
+		}
+		return node, err
+	case "match":
+		// ["match", ["get", property], [values] | value, true, false]
+		if len(args) != 4 || args[2] != true || args[3] != false {
+			return nil, fmt.Errorf("unsupported filter: %v", filter)
+		}
+		property, ok := getProperty(args[0])
+		if !ok {
+			return nil, fmt.Errorf("unsupported filter: %v", filter)
+		}
+		values, ok := args[1].([]interface{})
+		if !ok {
+			values = []interface{}{args[1]}
+		}
+		return mapboxFilterToOgc(w, append([]interface{}{"in", property}, values...))
+	default:
+		comparison, ok := comparisonOperators[operator]
+		if !ok || len(args) != 2 {
+			return nil, fmt.Errorf("unsupported filter: %v", filter)
+		}
+		property, value, err := comparisonArguments(args)
+		if err != nil {
+			return nil, err
+		}
+		if property == "" {
+			return nil, nil
+		}
+		var literal string
+		switch v := value.(type) {
+		case nil:
+			isNull := w.Ogc("PropertyIsNull", w.OgcText("PropertyName", property))
+			if operator == "==" {
+				return isNull, nil
+			} else if operator == "!=" {
+				return w.Ogc("Not", isNull), nil
+			}
+			return nil, fmt.Errorf("unsupported filter: %v", filter)
+		case float64:
+			literal = formatNumber(v)
+		default:
+			literal = fmt.Sprint(v)
+		}
+		return w.Ogc(comparison, w.OgcText("PropertyName", property), w.OgcText("Literal", literal)), nil
+	}
+}
+
+func logicalFilter(w sld.Writer, operator string, args []interface{}) (*sld.Node, error) {
+	var operands []*sld.Node
+	for _, arg := range args {
+		operand, err := mapboxFilterToOgc(w, arg)
+		if err != nil {
+			return nil, err
+		}
+		if operand == nil {
+			if operator == "Or" {
+				// one of the alternatives always matches
+				return nil, nil
+			}
+			continue
+		}
+		operands = append(operands, operand)
+	}
+	switch len(operands) {
+	case 0:
+		if operator == "Or" {
+			return nil, fmt.Errorf("filter never matches")
+		}
+		return nil, nil
+	case 1:
+		return operands[0], nil
+	default:
+		return w.Ogc(operator, operands...), nil
+	}
+}
+
+// noneFilter negates the alternatives that are converted, a dropped (geometry type) alternative is left out instead of
+// making the alternatives match all features, which would make none of them match no feature
+func noneFilter(w sld.Writer, args []interface{}) (*sld.Node, error) {
+	var operands []*sld.Node
+	for _, arg := range args {
+		if arg == true {
+			return nil, fmt.Errorf("filter never matches")
+		}
+		operand, err := mapboxFilterToOgc(w, arg)
+		if err != nil {
+			return nil, err
+		}
+		if operand != nil {
+			operands = append(operands, operand)
+		}
+	}
+	switch len(operands) {
+	case 0:
+		return nil, nil
+	case 1:
+		return negate(w, operands[0], nil)
+	default:
+		return w.Ogc("Not", w.Ogc("Or", operands...)), nil
+	}
+}
+
+func negate(w sld.Writer, node *sld.Node, err error) (*sld.Node, error) {
+	if err != nil {
+		return nil, err
+	}
+	if node == nil {
+		// negating a dropped (geometry type) condition drops it as well
+		return nil, nil
+	}
+	if node.XMLName.Local == "Not" {
+		return node.Elements()[0], nil
+	}
+	return w.Ogc("Not", node), nil
+}
+
+// comparisonArguments returns the property and the value compared, an empty property for geometry type comparisons
+func comparisonArguments(args []interface{}) (string, interface{}, error) {
+	if key, ok := args[0].(string); ok {
+		// legacy filter: [operator, key, value]
+		if isGeometryTypeKey(key) {
+			return "", nil, nil
+		}
+		return key, args[1], nil
+	}
+	if isGeometryTypeExpression(args[0]) || isGeometryTypeExpression(args[1]) {
+		return "", nil, nil
+	}
+	if property, ok := getProperty(args[0]); ok {
+		return property, unwrapLiteral(args[1]), nil
+	}
+	if property, ok := getProperty(args[1]); ok {
+		return property, unwrapLiteral(args[0]), nil
+	}
+	return "", nil, fmt.Errorf("unsupported comparison: %v", args)
+}
+
+// inArguments returns the property and the values of both the legacy [in, key, values...] and the expression
+// [in, [get, key], [literal, [values...]]] syntax, an empty property for geometry type comparisons
+func inArguments(args []interface{}) (string, []interface{}, error) {
+	if key, ok := args[0].(string); ok {
+		if isGeometryTypeKey(key) {
+			return "", nil, nil
+		}
+		return key, args[1:], nil
+	}
+	if property, ok := getProperty(args[0]); ok && len(args) == 2 {
+		if values, ok := unwrapLiteral(args[1]).([]interface{}); ok {
+			return property, values, nil
+		}
+	}
+	return "", nil, fmt.Errorf("unsupported in filter: %v", args)
+}
+
+// getProperty the property name of a ["get", property] expression, or of a ["to-string", ["get", property]] expression
+// comparing a property as text
+func getProperty(value interface{}) (string, bool) {
+	expression, ok := value.([]interface{})
+	if ok && len(expression) == 2 && expression[0] == "to-string" {
+		expression, ok = expression[1].([]interface{})
+	}
+	if !ok || len(expression) != 2 || expression[0] != "get" {
+		return "", false
+	}
+	property, ok := expression[1].(string)
+	return property, ok
+}
+
+func unwrapLiteral(value interface{}) interface{} {
+	if expression, ok := value.([]interface{}); ok && len(expression) == 2 && expression[0] == "literal" {
+		return expression[1]
+	}
+	return value
+}
+
+func isGeometryTypeKey(key string) bool {
+	return strings.EqualFold(key, "$type")
+}
+
+func isGeometryTypeExpression(value interface{}) bool {
+	expression, ok := value.([]interface{})
+	return ok && len(expression) == 1 && expression[0] == "geometry-type"
+}
+
+// ogcFilterToMapbox converts an ogc:Filter with comparison and logical operators to a Mapbox filter expression. SLD
+// literals are untyped: they are compared as numbers by the ordering comparisons and PropertyIsBetween, and as text by
+// PropertyIsEqualTo and PropertyIsNotEqualTo.
+func ogcFilterToMapbox(filter *sld.Node) (interface{}, error) {
+	operators := filter.Elements()
+	if len(operators) != 1 {
+		return nil, fmt.Errorf("filter should have a single operator")
+	}
+	return ogcOperatorToMapbox(operators[0])
+}
+
+func ogcOperatorToMapbox(operator *sld.Node) (interface{}, error) {
+	operands := operator.Elements()
+	switch local := operator.XMLName.Local; local {
+	case "And", "Or":
+		expression := []interface{}{"all"}
+		if local == "Or" {
+			expression[0] = "any"
+		}
+		for _, operand := range operands {
+			converted, err := ogcOperatorToMapbox(operand)
+			if err != nil {
+				return nil, err
+			}
+			expression = append(expression, converted)
+		}
+		return expression, nil
+	case "Not":
+		if len(operands) != 1 {
+			return nil, fmt.Errorf("Not should have a single operand")
+		}
+		converted, err := ogcOperatorToMapbox(operands[0])
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{"!", converted}, nil
+	case "PropertyIsNull":
+		property, err := propertyName(operands)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{"!", []interface{}{"has", property}}, nil
+	case "PropertyIsLike":
+		return likeToMapbox(operator, operands)
+	case "PropertyIsBetween":
+		property, err := propertyName(operands)
+		if err != nil {
+			return nil, err
+		}
+		lower, err := boundary(operator, "LowerBoundary")
+		if err != nil {
+			return nil, err
+		}
+		upper, err := boundary(operator, "UpperBoundary")
+		if err != nil {
+			return nil, err
+		}
+		get := []interface{}{"get", property}
+		return []interface{}{"all", []interface{}{">=", get, lower}, []interface{}{"<=", get, upper}}, nil
+	default:
+		var comparison string
+		for mapboxOperator, ogcOperator := range comparisonOperators {
+			if ogcOperator == local {
+				comparison = mapboxOperator
+			}
+		}
+		if comparison == "" {
+			return nil, fmt.Errorf("unsupported filter operator: %s", local)
+		}
+		if len(operands) != 2 {
+			return nil, fmt.Errorf("%s should have two operands", local)
+		}
+		if operands[0].XMLName.Local == "Literal" && operands[1].XMLName.Local == "PropertyName" {
+			operands[0], operands[1] = operands[1], operands[0]
+			comparison = mirroredComparisons[comparison]
+		}
+		if operands[0].XMLName.Local != "PropertyName" || operands[1].XMLName.Local != "Literal" {
+			return nil, fmt.Errorf("%s should compare a PropertyName with a Literal", local)
+		}
+		get := []interface{}{"get", operands[0].Text()}
+		text := operands[1].Text()
+		if comparison != "==" && comparison != "!=" {
+			return []interface{}{comparison, get, literal(text)}, nil
+		}
+		if _, ok := literal(text).(float64); ok {
+			// a numeric text matches both numbers and strings with the same text (e.g. 0123 only matches a string)
+			return []interface{}{comparison, []interface{}{"to-string", get}, text}, nil
+		}
+		return []interface{}{comparison, get, text}, nil
+	}
+}
+
+// likeToMapbox converts the PropertyIsLike patterns a Mapbox expression can match: a text without wildcards, a prefix
+// (abc*) and a substring (*abc*). Other patterns, e.g. with a single character wildcard, are not supported.
+func likeToMapbox(operator *sld.Node, operands []*sld.Node) (interface{}, error) {
+	if len(operands) != 2 || operands[0].XMLName.Local != "PropertyName" || operands[1].XMLName.Local != "Literal" {
+		return nil, fmt.Errorf("PropertyIsLike should compare a PropertyName with a Literal")
+	}
+	pattern := operands[1].Text()
+	wildCard, _ := operator.Attr("wildCard")
+	singleChar, _ := operator.Attr("singleChar")
+	escape, ok := operator.Attr("escapeChar")
+	if !ok {
+		escape, _ = operator.Attr("escape")
+	}
+	// the texts between the wildcards
+	parts := []string{""}
+	for rest := pattern; rest != ""; {
+		switch {
+		case escape != "" && strings.HasPrefix(rest, escape) && len(rest) > len(escape):
+			_, size := utf8.DecodeRuneInString(rest[len(escape):])
+			parts[len(parts)-1] += rest[len(escape) : len(escape)+size]
+			rest = rest[len(escape)+size:]
+		case wildCard != "" && strings.HasPrefix(rest, wildCard):
+			parts = append(parts, "")
+			rest = rest[len(wildCard):]
+		case singleChar != "" && strings.HasPrefix(rest, singleChar):
+			return nil, fmt.Errorf("PropertyIsLike pattern %s is not supported, single character wildcards cannot be converted", pattern)
+		default:
+			_, size := utf8.DecodeRuneInString(rest)
+			parts[len(parts)-1] += rest[:size]
+			rest = rest[size:]
+		}
+	}
+
+	text := []interface{}{"to-string", []interface{}{"get", operands[0].Text()}}
+	if matchCase, _ := operator.Attr("matchCase"); matchCase == "false" {
+		text = []interface{}{"downcase", text}
+		for i := range parts {
+			parts[i] = strings.ToLower(parts[i])
+		}
+	}
+	switch {
+	case len(parts) == 1:
+		return []interface{}{"==", text, parts[0]}, nil
+	case len(parts) == 2 && parts[0] == "" && parts[1] == "":
+		return []interface{}{"has", operands[0].Text()}, nil
+	case len(parts) == 2 && parts[1] == "":
+		return []interface{}{"==", []interface{}{"index-of", parts[0], text}, 0.0}, nil
+	case len(parts) == 3 && parts[0] == "" && parts[2] == "":
+		return []interface{}{">=", []interface{}{"index-of", parts[1], text}, 0.0}, nil
+	default:
+		return nil, fmt.Errorf("PropertyIsLike pattern %s is not supported, only a prefix (abc%s) or a substring (%sabc%s) can be converted",
+			pattern, wildCard, wildCard, wildCard)
+	}
+}
+
+var mirroredComparisons = map[string]string{"==": "==", "!=": "!=", "<": ">", "<=": ">=", ">": "<", ">=": "<="}
+
+func propertyName(operands []*sld.Node) (string, error) {
+	if len(operands) == 0 || operands[0].XMLName.Local != "PropertyName" {
+		return "", fmt.Errorf("operator should have a PropertyName")
+	}
+	return operands[0].Text(), nil
+}
+
+func boundary(operator *sld.Node, local string) (interface{}, error) {
+	node := operator.Child(local)
+	if node == nil || node.Child("Literal") == nil {
+		return nil, fmt.Errorf("PropertyIsBetween should have a %s with a Literal", local)
+	}
+	return literal(node.Child("Literal").Text()), nil
+}
+
+// literal a number when the text is a finite number, the text otherwise (e.g. NaN, Inf)
+func literal(text string) interface{} {
+	if number, err := strconv.ParseFloat(text, 64); err == nil && !math.IsNaN(number) && !math.IsInf(number, 0) {
+		return number
+	}
+	return text
+}
@@ -0,0 +1,286 @@
+package convert
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/pdok/goas/pkg/mapbox"
+	"github.com/pdok/goas/pkg/models"
+	"github.com/pdok/goas/pkg/sld"
+)
+
+// DataLayer a layer of the data a stylesheet renders, with the attributes its filters and expressions refer to
+type DataLayer struct {
+	Id           string
+	GeometryType *models.GeometryType // nil when the stylesheet does not tell
+	Attributes   []Attribute          // sorted by name
+}
+
+// Attribute a property of the features of a data layer, with the literal values the filters compare it with
+type Attribute struct {
+	Name   string
+	Values []interface{} // strings, numbers or booleans, in order of appearance
+}
+
+// tokenPattern the {property} tokens of the legacy text-field and icon-image syntax
+var tokenPattern = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// MapboxDataLayers the data layers (source-layers, or sources without source-layer) of the layers of a Mapbox style, in
+// the order of the layers. The geometry type follows from the layer types: polygons when a fill is rendered (lines and
+// symbols may be outlines and labels), the single type of the line, circle and heatmap layers otherwise, symbols alone
+// are points (or lines with a line placement).
+func MapboxDataLayers(style *mapbox.Style) []DataLayer {
+	var ids []string
+	layerTypes := make(map[string]map[models.GeometryType]bool)
+	symbolTypes := make(map[string]map[models.GeometryType]bool)
+	attributes := make(map[string]map[string][]interface{})
+	for _, layer := range style.Layers {
+		var geometryType models.GeometryType
+		switch layer.Type {
+		case mapbox.Fill, mapbox.FillExtrusion:
+			geometryType = models.Polygons
+		case mapbox.Line:
+			geometryType = models.Lines
+		case mapbox.Circle, mapbox.Heatmap:
+			geometryType = models.Points
+		case mapbox.Symbol:
+			geometryType = models.Points
+			if placement, _ := layer.Layout["symbol-placement"].(string); placement == "line" || placement == "line-center" {
+				geometryType = models.Lines
+			}
+		default:
+			// background, raster, hillshade and sky layers do not render features
+			continue
+		}
+		id := layer.SourceLayerName()
+		if id == "" {
+			continue
+		}
+		if _, ok := attributes[id]; !ok {
+			ids = append(ids, id)
+			layerTypes[id] = make(map[models.GeometryType]bool)
+			symbolTypes[id] = make(map[models.GeometryType]bool)
+			attributes[id] = make(map[string][]interface{})
+		}
+		if layer.Type == mapbox.Symbol {
+			symbolTypes[id][geometryType] = true
+		} else {
+			layerTypes[id][geometryType] = true
+		}
+		add := func(name string, values ...interface{}) {
+			if name != "" && !isGeometryTypeKey(name) && name != "$id" {
+				attributes[id][name] = appendValues(attributes[id][name], values...)
+			}
+		}
+		filterProperties(layer.Filter, add)
+		for _, properties := range []map[string]interface{}{layer.Layout, layer.Paint} {
+			for name, value := range properties {
+				expressionProperties(value, name == "text-field" || name == "icon-image", add)
+			}
+		}
+	}
+
+	var layers []DataLayer
+	for _, id := range ids {
+		geometryTypes := layerTypes[id]
+		if len(geometryTypes) == 0 {
+			geometryTypes = symbolTypes[id]
+		}
+		layers = append(layers, DataLayer{Id: id, GeometryType: combinedGeometryType(geometryTypes), Attributes: sortedAttributes(attributes[id])})
+	}
+	return layers
+}
+
+// SldDataLayers the data layers (NamedLayers) of an SLD 1.0 or SE 1.1 document, with the geometry type of their polygon,
+// line and point symbolizers (text symbolizers alone are points) and the properties their filters and symbolizers refer to
+func SldDataLayers(root *sld.Node) []DataLayer {
+	var layers []DataLayer
+	for _, namedLayer := range root.ChildrenNamed("NamedLayer") {
+		symbolizerTypes := make(map[models.GeometryType]bool)
+		textTypes := make(map[models.GeometryType]bool)
+		attributes := make(map[string][]interface{})
+		walkSld(namedLayer, func(node *sld.Node, parent *sld.Node) {
+			switch node.XMLName.Local {
+			case "PolygonSymbolizer":
+				symbolizerTypes[models.Polygons] = true
+			case "LineSymbolizer":
+				symbolizerTypes[models.Lines] = true
+			case "PointSymbolizer":
+				symbolizerTypes[models.Points] = true
+			case "TextSymbolizer":
+				textTypes[models.Points] = true
+			case "PropertyName":
+				// the geometry property a symbolizer renders is not an attribute
+				if parent.XMLName.Local != "Geometry" && node.Text() != "" {
+					attributes[node.Text()] = appendValues(attributes[node.Text()])
+				}
+			case "PropertyIsEqualTo", "PropertyIsNotEqualTo", "PropertyIsLessThan", "PropertyIsLessThanOrEqualTo",
+				"PropertyIsGreaterThan", "PropertyIsGreaterThanOrEqualTo":
+				if name := node.Child("PropertyName").Text(); name != "" && node.Child("Literal") != nil {
+					attributes[name] = appendValues(attributes[name], literal(node.Child("Literal").Text()))
+				}
+			case "PropertyIsBetween":
+				name := node.Child("PropertyName").Text()
+				for _, boundary := range []string{"LowerBoundary", "UpperBoundary"} {
+					if bound := node.Child(boundary); name != "" && bound != nil && bound.Child("Literal") != nil {
+						attributes[name] = appendValues(attributes[name], literal(bound.Child("Literal").Text()))
+					}
+				}
+			case "PropertyIsLike":
+				// the pattern is not a value, but tells the property is a string
+				if name := node.Child("PropertyName").Text(); name != "" && node.Child("Literal") != nil {
+					attributes[name] = appendValues(attributes[name], node.Child("Literal").Text())
+				}
+			}
+		})
+		if len(symbolizerTypes) == 0 {
+			symbolizerTypes = textTypes
+		}
+		layers = append(layers, DataLayer{
+			Id:           namedLayer.Child("Name").Text(),
+			GeometryType: combinedGeometryType(symbolizerTypes),
+			Attributes:   sortedAttributes(attributes),
+		})
+	}
+	return layers
+}
+
+func walkSld(node *sld.Node, visit func(node *sld.Node, parent *sld.Node)) {
+	for _, child := range node.Elements() {
+		visit(child, node)
+		walkSld(child, visit)
+	}
+}
+
+// combinedGeometryType polygons when polygons are rendered, the single geometry type, or any for a mix
+func combinedGeometryType(geometryTypes map[models.GeometryType]bool) *models.GeometryType {
+	var combined models.GeometryType
+	switch {
+	case len(geometryTypes) == 0:
+		return nil
+	case geometryTypes[models.Polygons]:
+		combined = models.Polygons
+	case len(geometryTypes) > 1:
+		combined = models.Any
+	default:
+		for geometryType := range geometryTypes {
+			combined = geometryType
+		}
+	}
+	return &combined
+}
+
+// filterProperties the properties of a filter, either the legacy filter syntax or an expression, with the values they
+// are compared with
+func filterProperties(filter interface{}, add func(string, ...interface{})) {
+	expression, ok := filter.([]interface{})
+	if !ok || len(expression) < 2 {
+		return
+	}
+	switch expression[0] {
+	case "all", "any", "none", "!":
+		for _, operand := range expression[1:] {
+			filterProperties(operand, add)
+		}
+		return
+	case "==", "!=", "<", "<=", ">", ">=", "in", "!in", "has", "!has":
+		if key, ok := expression[1].(string); ok {
+			// legacy filter: [operator, key, values...]
+			add(key, expression[2:]...)
+			return
+		}
+	}
+	expressionProperties(filter, false, add)
+}
+
+// expressionProperties the properties an expression gets, or a legacy function (or text-field token) refers to, with
+// the values comparisons, in and match expressions compare them with
+func expressionProperties(value interface{}, withTokens bool, add func(string, ...interface{})) {
+	switch typed := value.(type) {
+	case []interface{}:
+		if len(typed) == 0 || typed[0] == "literal" {
+			return
+		}
+		operator, _ := typed[0].(string)
+		switch {
+		case len(typed) == 2 && (operator == "get" || operator == "has"):
+			if property, ok := typed[1].(string); ok {
+				add(property)
+				return
+			}
+		case len(typed) == 3 && (operator == "==" || operator == "!=" || operator == "<" || operator == "<=" || operator == ">" || operator == ">="):
+			if property, value, err := comparisonArguments(typed[1:]); err == nil && property != "" {
+				add(property, value)
+				return
+			}
+		case len(typed) == 3 && operator == "in":
+			if property, values, err := inArguments(typed[1:]); err == nil && property != "" {
+				add(property, values...)
+				return
+			}
+		case len(typed) >= 5 && operator == "match":
+			// [match, input, label, output, label, output, ..., fallback], labels are values or arrays of values
+			if property, ok := getProperty(typed[1]); ok {
+				for i := 2; i < len(typed)-1; i += 2 {
+					if labels, ok := typed[i].([]interface{}); ok {
+						add(property, labels...)
+					} else {
+						add(property, typed[i])
+					}
+					expressionProperties(typed[i+1], withTokens, add)
+				}
+				expressionProperties(typed[len(typed)-1], withTokens, add)
+				return
+			}
+		}
+		for _, argument := range typed[1:] {
+			expressionProperties(argument, withTokens, add)
+		}
+	case map[string]interface{}:
+		// legacy function: {"property": name, "stops": [...]}
+		if property, ok := typed["property"].(string); ok {
+			add(property)
+		}
+	case string:
+		if withTokens {
+			for _, match := range tokenPattern.FindAllStringSubmatch(typed, -1) {
+				add(match[1])
+			}
+		}
+	}
+}
+
+// appendValues appends the string, number and boolean values that are not in the list yet
+func appendValues(list []interface{}, values ...interface{}) []interface{} {
+	for _, value := range values {
+		switch value.(type) {
+		case string, float64, bool:
+		default:
+			continue
+		}
+		found := false
+		for _, existing := range list {
+			if existing == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+func sortedAttributes(attributes map[string][]interface{}) []Attribute {
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sorted := make([]Attribute, 0, len(names))
+	for _, name := range names {
+		sorted = append(sorted, Attribute{Name: name, Values: attributes[name]})
+	}
+	return sorted
+}
@@ -0,0 +1,84 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/pdok/goas/pkg/mapbox"
+	"github.com/pdok/goas/pkg/models"
+	"github.com/pdok/goas/pkg/sld"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapboxDataLayers(t *testing.T) {
+	style, err := mapbox.Parse([]byte(`{
+	  "version": 8,
+	  "sources": {"osm": {"type": "vector"}, "relief": {"type": "raster"}},
+	  "layers": [
+		{"id": "relief", "type": "raster", "source": "relief"},
+		{"id": "road-labels", "type": "symbol", "source": "osm", "source-layer": "roads",
+		 "layout": {"symbol-placement": "line", "text-field": "{name} {ref}"}},
+		{"id": "pois", "type": "circle", "source": "osm", "source-layer": "pois",
+		 "filter": ["all", ["==", "$type", "Point"], ["in", "class", "shop", "cafe"], [">=", ["get", "rank"], 2]],
+		 "paint": {"circle-radius": {"property": "rank", "stops": [[1, 2], [10, 6]]}}},
+		{"id": "poi-lines", "type": "line", "source": "osm", "source-layer": "pois",
+		 "paint": {"line-color": ["match", ["get", "kind"], "path", "#000", ["literal", "#fff"]]}},
+		{"id": "places", "type": "symbol", "source": "osm", "source-layer": "places",
+		 "filter": ["!", ["has", "disused"]], "layout": {"text-field": ["get", "name"]}},
+		{"id": "capitals", "type": "symbol", "source": "osm", "source-layer": "places",
+		 "filter": ["match", ["get", "capital"], [true], true, false]}
+	  ]
+	}`))
+	require.Nil(t, err)
+
+	layers := MapboxDataLayers(style)
+	require.Len(t, layers, 3)
+	require.Equal(t, "roads", layers[0].Id)
+	require.Equal(t, models.Lines, *layers[0].GeometryType, "symbols placed along lines")
+	require.Equal(t, []string{"name", "ref"}, attributeNames(layers[0].Attributes))
+	require.Equal(t, "pois", layers[1].Id)
+	require.Equal(t, models.Any, *layers[1].GeometryType, "circles and lines")
+	require.Equal(t, []Attribute{
+		{Name: "class", Values: []interface{}{"shop", "cafe"}},
+		{Name: "kind", Values: []interface{}{"path"}},
+		{Name: "rank", Values: []interface{}{2.0}},
+	}, layers[1].Attributes)
+	require.Equal(t, models.Points, *layers[2].GeometryType)
+	require.Equal(t, []Attribute{{Name: "capital", Values: []interface{}{true}}, {Name: "disused"}, {Name: "name"}}, layers[2].Attributes)
+}
+
+func TestSldDataLayers(t *testing.T) {
+	root, err := sld.Parse([]byte(`<StyledLayerDescriptor xmlns="http://www.opengis.net/sld" xmlns:ogc="http://www.opengis.net/ogc">
+	  <NamedLayer>
+		<Name>roads</Name>
+		<UserStyle><FeatureTypeStyle><Rule>
+		  <ogc:Filter><ogc:And>
+			<ogc:PropertyIsEqualTo><ogc:PropertyName>class</ogc:PropertyName><ogc:Literal>motorway</ogc:Literal></ogc:PropertyIsEqualTo>
+			<ogc:PropertyIsBetween><ogc:PropertyName>lanes</ogc:PropertyName>
+			  <ogc:LowerBoundary><ogc:Literal>2</ogc:Literal></ogc:LowerBoundary>
+			  <ogc:UpperBoundary><ogc:Literal>4</ogc:Literal></ogc:UpperBoundary>
+			</ogc:PropertyIsBetween>
+		  </ogc:And></ogc:Filter>
+		  <LineSymbolizer><Geometry><ogc:PropertyName>geom</ogc:PropertyName></Geometry></LineSymbolizer>
+		  <TextSymbolizer><Label><ogc:PropertyName>name</ogc:PropertyName></Label></TextSymbolizer>
+		</Rule></FeatureTypeStyle></UserStyle>
+	  </NamedLayer>
+	</StyledLayerDescriptor>`))
+	require.Nil(t, err)
+
+	layers := SldDataLayers(root)
+	require.Len(t, layers, 1)
+	require.Equal(t, "roads", layers[0].Id)
+	require.Equal(t, models.Lines, *layers[0].GeometryType, "labels along the lines")
+	require.Equal(t, []Attribute{
+		{Name: "class", Values: []interface{}{"motorway"}},
+		{Name: "lanes", Values: []interface{}{2.0, 4.0}},
+		{Name: "name"},
+	}, layers[0].Attributes)
+}
+
+func attributeNames(attributes []Attribute) (names []string) {
+	for _, attribute := range attributes {
+		names = append(names, attribute.Name)
+	}
+	return names
+}
@@ -0,0 +1,433 @@
+package convert
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/pdok/goas/pkg/mapbox"
+	"github.com/pdok/goas/pkg/sld"
+)
+
+// DefaultZoom the zoom level zoom dependent Mapbox properties are evaluated at (clamped to the zoom range of the layer),
+// since SLD symbolizers have a single value per rule
+const DefaultZoom = 12
+
+var tokenRegex = regexp.MustCompile(`\{([^{}]+)}`)
+
+// SldOptions the options of a Mapbox to SLD conversion
+type SldOptions struct {
+	Version string  // the SLD version to generate, sld.Version10 or sld.Version11 (symbology encoding)
+	Name    string  // the name of the user styles
+	Title   string  // the title of the user styles (optional)
+	Zoom    float64 // the zoom level zoom dependent properties are evaluated at
+}
+
+// MapboxToSld converts the fill, line, circle and symbol (text) layers of a Mapbox style to an SLD. Each Mapbox layer
+// becomes a FeatureTypeStyle (to keep the drawing order) with a single rule in the NamedLayer of its source layer.
+// Layers that cannot be converted (e.g. with data driven properties or unsupported filters) are skipped with a warning.
+func MapboxToSld(style *mapbox.Style, options SldOptions) (*sld.Node, error) {
+	if options.Version == "" {
+		options.Version = sld.Version10
+	}
+	w := sld.Writer{Version: options.Version}
+	userStyles := make(map[string]*sld.Node)
+	var namedLayers []*sld.Node
+	for _, layer := range style.Layers {
+		if layer.Layout != nil && layer.Layout["visibility"] == "none" {
+			continue
+		}
+		rule, err := layerToRule(w, layer, options.Zoom)
+		if err != nil {
+			log.Printf("warning: skipping layer %s of mapbox style %s in the sld: %s", layer.Id, style.Name, err)
+			continue
+		}
+		name := layer.SourceLayerName()
+		userStyle, ok := userStyles[name]
+		if !ok {
+			userStyle = w.Sld("UserStyle", w.SeText("Name", options.Name))
+			if options.Title != "" {
+				if options.Version == sld.Version11 {
+					userStyle.Add(w.Se("Description", w.SeText("Title", options.Title)))
+				} else {
+					userStyle.Add(w.SeText("Title", options.Title))
+				}
+			}
+			userStyles[name] = userStyle
+			namedLayers = append(namedLayers, w.Sld("NamedLayer", w.SeText("Name", name), userStyle))
+		}
+		userStyle.Add(w.Se("FeatureTypeStyle", rule))
+	}
+	if namedLayers == nil {
+		return nil, fmt.Errorf("none of the layers of mapbox style %s can be converted to sld", style.Name)
+	}
+	return w.NewStyledLayerDescriptor(namedLayers...), nil
+}
+
+func layerToRule(w sld.Writer, layer mapbox.Layer, zoom float64) (*sld.Node, error) {
+	if layer.MinZoom != nil {
+		zoom = math.Max(zoom, *layer.MinZoom)
+	}
+	if layer.MaxZoom != nil {
+		zoom = math.Min(zoom, *layer.MaxZoom)
+	}
+	properties := layerProperties{layer, zoom}
+	var symbolizers []*sld.Node
+	var err error
+	switch layer.Type {
+	case mapbox.Fill:
+		symbolizers, err = polygonSymbolizer(w, properties)
+	case mapbox.Line:
+		symbolizers, err = lineSymbolizer(w, properties)
+	case mapbox.Circle:
+		symbolizers, err = circleSymbolizer(w, properties)
+	case mapbox.Symbol:
+		symbolizers, err = textSymbolizer(w, properties)
+	default:
+		return nil, fmt.Errorf("layers of type %s are not supported", layer.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rule := w.Se("Rule", w.SeText("Name", layer.Id))
+	filter, err := mapboxFilterToOgc(w, layer.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if filter != nil {
+		rule.Add(w.Ogc("Filter", filter))
+	}
+	// a higher zoom level is a smaller scale denominator
+	if layer.MaxZoom != nil {
+		rule.Add(w.SeText("MinScaleDenominator", formatNumber(zoomToScaleDenominator(*layer.MaxZoom))))
+	}
+	if layer.MinZoom != nil {
+		rule.Add(w.SeText("MaxScaleDenominator", formatNumber(zoomToScaleDenominator(*layer.MinZoom))))
+	}
+	return rule.Add(symbolizers...), nil
+}
+
+func polygonSymbolizer(w sld.Writer, properties layerProperties) ([]*sld.Node, error) {
+	if _, ok := properties.layer.Paint["fill-pattern"]; ok {
+		log.Printf("warning: fill-pattern of layer %s is not converted to sld", properties.layer.Id)
+	}
+	fillColor, err := properties.color("fill-color", "#000000")
+	if err != nil {
+		return nil, err
+	}
+	opacity, err := properties.number("fill-opacity", 1)
+	if err != nil {
+		return nil, err
+	}
+	symbolizer := w.Se("PolygonSymbolizer", fill(w, "fill", fillColor, opacity))
+	if _, ok := properties.layer.Paint["fill-outline-color"]; ok {
+		outlineColor, err := properties.color("fill-outline-color", "#000000")
+		if err != nil {
+			return nil, err
+		}
+		symbolizer.Add(w.Se("Stroke",
+			w.Parameter("stroke", mapbox.ToHex(outlineColor)),
+			w.Parameter("stroke-opacity", formatNumber(opacity*mapbox.Opacity(outlineColor)))))
+	}
+	return []*sld.Node{symbolizer}, nil
+}
+
+func lineSymbolizer(w sld.Writer, properties layerProperties) ([]*sld.Node, error) {
+	lineColor, err := properties.color("line-color", "#000000")
+	if err != nil {
+		return nil, err
+	}
+	opacity, err := properties.number("line-opacity", 1)
+	if err != nil {
+		return nil, err
+	}
+	width, err := properties.number("line-width", 1)
+	if err != nil {
+		return nil, err
+	}
+	stroke := w.Se("Stroke",
+		w.Parameter("stroke", mapbox.ToHex(lineColor)),
+		w.Parameter("stroke-opacity", formatNumber(opacity*mapbox.Opacity(lineColor))),
+		w.Parameter("stroke-width", formatNumber(width)))
+	if join, ok := properties.layout("line-join"); ok {
+		stroke.Add(w.Parameter("stroke-linejoin", fmt.Sprint(join)))
+	}
+	if lineCap, ok := properties.layout("line-cap"); ok {
+		stroke.Add(w.Parameter("stroke-linecap", fmt.Sprint(lineCap)))
+	}
+	if dashes, ok := properties.paint("line-dasharray"); ok {
+		dashArray, ok := dashes.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unsupported line-dasharray: %v", dashes)
+		}
+		var lengths []string
+		for _, dash := range dashArray {
+			length, ok := dash.(float64)
+			if !ok {
+				return nil, fmt.Errorf("unsupported line-dasharray: %v", dashes)
+			}
+			// mapbox dashes are in line widths
+			lengths = append(lengths, formatNumber(length*width))
+		}
+		stroke.Add(w.Parameter("stroke-dasharray", strings.Join(lengths, " ")))
+	}
+	symbolizer := w.Se("LineSymbolizer", stroke)
+	if offset, err := properties.number("line-offset", 0); err == nil && offset != 0 {
+		if w.Version == sld.Version11 {
+			symbolizer.Add(w.SeText("PerpendicularOffset", formatNumber(-offset)))
+		} else {
+			log.Printf("warning: line-offset of layer %s is not supported by sld 1.0", properties.layer.Id)
+		}
+	}
+	return []*sld.Node{symbolizer}, nil
+}
+
+func circleSymbolizer(w sld.Writer, properties layerProperties) ([]*sld.Node, error) {
+	radius, err := properties.number("circle-radius", 5)
+	if err != nil {
+		return nil, err
+	}
+	circleColor, err := properties.color("circle-color", "#000000")
+	if err != nil {
+		return nil, err
+	}
+	opacity, err := properties.number("circle-opacity", 1)
+	if err != nil {
+		return nil, err
+	}
+	mark := w.Se("Mark", w.SeText("WellKnownName", "circle"), fill(w, "fill", circleColor, opacity))
+	strokeWidth, err := properties.number("circle-stroke-width", 0)
+	if err != nil {
+		return nil, err
+	}
+	if strokeWidth > 0 {
+		strokeColor, err := properties.color("circle-stroke-color", "#000000")
+		if err != nil {
+			return nil, err
+		}
+		strokeOpacity, err := properties.number("circle-stroke-opacity", 1)
+		if err != nil {
+			return nil, err
+		}
+		mark.Add(w.Se("Stroke",
+			w.Parameter("stroke", mapbox.ToHex(strokeColor)),
+			w.Parameter("stroke-opacity", formatNumber(strokeOpacity*mapbox.Opacity(strokeColor))),
+			w.Parameter("stroke-width", formatNumber(strokeWidth))))
+	}
+	graphic := w.Se("Graphic", mark, w.SeText("Size", formatNumber(radius*2)))
+	return []*sld.Node{w.Se("PointSymbolizer", graphic)}, nil
+}
+
+func textSymbolizer(w sld.Writer, properties layerProperties) ([]*sld.Node, error) {
+	if _, ok := properties.layout("icon-image"); ok {
+		log.Printf("warning: icon-image of layer %s is not converted to sld", properties.layer.Id)
+	}
+	textField, ok := properties.layer.Layout["text-field"]
+	if !ok {
+		return nil, fmt.Errorf("symbol layer without text-field")
+	}
+	label, err := labelContent(w, textField)
+	if err != nil {
+		return nil, err
+	}
+	size, err := properties.number("text-size", 16)
+	if err != nil {
+		return nil, err
+	}
+	font := w.Se("Font")
+	fontStack, ok := properties.layout("text-font")
+	if !ok {
+		fontStack = []interface{}{"Open Sans Regular", "Arial Unicode MS Regular"}
+	}
+	if fonts, ok := fontStack.([]interface{}); ok && len(fonts) > 0 {
+		family, style, weight := splitFontName(fmt.Sprint(fonts[0]))
+		font.Add(w.Parameter("font-family", family), w.Parameter("font-style", style), w.Parameter("font-weight", weight))
+	}
+	font.Add(w.Parameter("font-size", formatNumber(size)))
+
+	symbolizer := w.Se("TextSymbolizer", w.Se("Label").Add(label...), font)
+	placement, _ := properties.layout("symbol-placement")
+	if placement == "line" || placement == "line-center" {
+		symbolizer.Add(w.Se("LabelPlacement", w.Se("LinePlacement")))
+	} else {
+		anchor, ok := properties.layout("text-anchor")
+		if !ok {
+			anchor = "center"
+		}
+		anchorX, anchorY := anchorPoint(fmt.Sprint(anchor))
+		pointPlacement := w.Se("PointPlacement", w.Se("AnchorPoint",
+			w.SeText("AnchorPointX", formatNumber(anchorX)), w.SeText("AnchorPointY", formatNumber(anchorY))))
+		if offset, ok := properties.layout("text-offset"); ok {
+			if xy, ok := offset.([]interface{}); ok && len(xy) == 2 {
+				x, xOk := xy[0].(float64)
+				y, yOk := xy[1].(float64)
+				if xOk && yOk && (x != 0 || y != 0) {
+					// mapbox offsets are in ems pointing down, sld displacements in pixels pointing up
+					pointPlacement.Add(w.Se("Displacement",
+						w.SeText("DisplacementX", formatNumber(x*size)), w.SeText("DisplacementY", formatNumber(-y*size))))
+				}
+			}
+		}
+		symbolizer.Add(w.Se("LabelPlacement", pointPlacement))
+	}
+	haloWidth, err := properties.number("text-halo-width", 0)
+	if err != nil {
+		return nil, err
+	}
+	if haloWidth > 0 {
+		haloColor, err := properties.color("text-halo-color", "rgba(0, 0, 0, 0)")
+		if err != nil {
+			return nil, err
+		}
+		symbolizer.Add(w.Se("Halo", w.SeText("Radius", formatNumber(haloWidth)), fill(w, "fill", haloColor, 1)))
+	}
+	textColor, err := properties.color("text-color", "#000000")
+	if err != nil {
+		return nil, err
+	}
+	opacity, err := properties.number("text-opacity", 1)
+	if err != nil {
+		return nil, err
+	}
+	symbolizer.Add(fill(w, "fill", textColor, opacity))
+	return []*sld.Node{symbolizer}, nil
+}
+
+// labelContent converts a text-field, "{name}" tokens or a get, to-string or concat expression, to mixed content
+func labelContent(w sld.Writer, textField interface{}) ([]*sld.Node, error) {
+	switch field := textField.(type) {
+	case string:
+		var content []*sld.Node
+		last := 0
+		for _, match := range tokenRegex.FindAllStringSubmatchIndex(field, -1) {
+			if match[0] > last {
+				content = append(content, sld.NewText(field[last:match[0]]))
+			}
+			content = append(content, w.OgcText("PropertyName", field[match[2]:match[3]]))
+			last = match[1]
+		}
+		if last < len(field) {
+			content = append(content, sld.NewText(field[last:]))
+		}
+		return content, nil
+	case []interface{}:
+		if property, ok := getProperty(field); ok {
+			return []*sld.Node{w.OgcText("PropertyName", property)}, nil
+		}
+		if len(field) == 2 && field[0] == "to-string" {
+			return labelContent(w, field[1])
+		}
+		if len(field) > 1 && field[0] == "concat" {
+			var content []*sld.Node
+			for _, part := range field[1:] {
+				if literal, ok := part.(string); ok {
+					content = append(content, sld.NewText(literal))
+					continue
+				}
+				partContent, err := labelContent(w, part)
+				if err != nil {
+					return nil, err
+				}
+				content = append(content, partContent...)
+			}
+			return content, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported text-field: %v", textField)
+}
+
+// splitFontName splits a font name like "Open Sans Bold Italic" in family, style and weight
+func splitFontName(name string) (family string, style string, weight string) {
+	style, weight = "normal", "normal"
+	var familyParts []string
+	for _, part := range strings.Fields(name) {
+		switch strings.ToLower(part) {
+		case "italic", "oblique":
+			style = strings.ToLower(part)
+		case "bold", "semibold", "extrabold", "black", "heavy":
+			weight = "bold"
+		case "regular", "medium", "book":
+		default:
+			familyParts = append(familyParts, part)
+		}
+	}
+	return strings.Join(familyParts, " "), style, weight
+}
+
+func anchorPoint(anchor string) (float64, float64) {
+	x, y := 0.5, 0.5
+	if strings.Contains(anchor, "left") {
+		x = 0
+	} else if strings.Contains(anchor, "right") {
+		x = 1
+	}
+	if strings.Contains(anchor, "top") {
+		y = 1
+	} else if strings.Contains(anchor, "bottom") {
+		y = 0
+	}
+	return x, y
+}
+
+func fill(w sld.Writer, prefix string, fillColor color.NRGBA, opacity float64) *sld.Node {
+	return w.Se("Fill",
+		w.Parameter(prefix, mapbox.ToHex(fillColor)),
+		w.Parameter(prefix+"-opacity", formatNumber(opacity*mapbox.Opacity(fillColor))))
+}
+
+// layerProperties resolves the paint and layout properties of a layer at a zoom level
+type layerProperties struct {
+	layer mapbox.Layer
+	zoom  float64
+}
+
+func (p layerProperties) paint(name string) (interface{}, bool) {
+	return p.resolve(p.layer.Paint, name)
+}
+
+func (p layerProperties) layout(name string) (interface{}, bool) {
+	return p.resolve(p.layer.Layout, name)
+}
+
+func (p layerProperties) resolve(properties map[string]interface{}, name string) (interface{}, bool) {
+	raw, ok := properties[name]
+	if !ok {
+		return nil, false
+	}
+	value, ok := literalValue(raw, p.zoom)
+	if !ok {
+		log.Printf("warning: %s of layer %s is data driven, using its default in the sld", name, p.layer.Id)
+	}
+	return value, ok
+}
+
+func (p layerProperties) number(name string, defaultValue float64) (float64, error) {
+	value, ok := p.paint(name)
+	if !ok {
+		value, ok = p.layout(name)
+	}
+	if !ok {
+		return defaultValue, nil
+	}
+	number, ok := value.(float64)
+	if !ok {
+		return 0, fmt.Errorf("%s should be a number, found: %v", name, value)
+	}
+	return number, nil
+}
+
+func (p layerProperties) color(name string, defaultValue string) (color.NRGBA, error) {
+	value, ok := p.paint(name)
+	if !ok {
+		value = defaultValue
+	}
+	colorString, ok := value.(string)
+	if !ok {
+		return color.NRGBA{}, fmt.Errorf("%s should be a color, found: %v", name, value)
+	}
+	return mapbox.ParseColor(colorString)
+}
@@ -0,0 +1,209 @@
+package convert
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/pdok/goas/pkg/mapbox"
+	"github.com/pdok/goas/pkg/sld"
+	"github.com/stretchr/testify/require"
+)
+
+func comparable(content *bytes.Buffer) string {
+	result := content.String()
+	for _, space := range []string{"\n", "\t", " "} {
+		result = strings.Replace(result, space, "", -1)
+	}
+	return result
+}
+
+func TestZoomToScaleDenominator(t *testing.T) {
+	require.InDelta(t, 279541132.0143589, zoomToScaleDenominator(0), 0.001)
+	require.InDelta(t, 68247.347, zoomToScaleDenominator(12), 0.001)
+	require.InDelta(t, 12, scaleDenominatorToZoom(zoomToScaleDenominator(12)), 0.000001)
+}
+
+func TestLiteralValue(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		zoom     float64
+		expected interface{}
+		ok       bool
+	}{
+		{value: "#ff0000", zoom: 12, expected: "#ff0000", ok: true},
+		{value: map[string]interface{}{"stops": []interface{}{[]interface{}{10.0, 1.0}, []interface{}{14.0, 5.0}}}, zoom: 12, expected: 3.0, ok: true},
+		{value: []interface{}{"interpolate", []interface{}{"linear"}, []interface{}{"zoom"}, 8.0, 1.0, 16.0, 3.0}, zoom: 20, expected: 3.0, ok: true},
+		{value: []interface{}{"step", []interface{}{"zoom"}, "a", 10.0, "b"}, zoom: 12, expected: "b", ok: true},
+		{value: []interface{}{"get", "width"}, zoom: 12, expected: nil, ok: false},
+		{value: map[string]interface{}{"property": "width", "stops": []interface{}{}}, zoom: 12, expected: nil, ok: false},
+	}
+	for _, test := range tests {
+		value, ok := literalValue(test.value, test.zoom)
+		require.Equal(t, test.ok, ok)
+		require.Equal(t, test.expected, value)
+	}
+}
+
+// outline summarizes a filter as Operator(Child,Child[text])
+func outline(node *sld.Node) string {
+	elements := node.Elements()
+	if len(elements) == 0 {
+		return node.XMLName.Local + "[" + node.Text() + "]"
+	}
+	var children []string
+	for _, child := range elements {
+		children = append(children, outline(child))
+	}
+	return node.XMLName.Local + "(" + strings.Join(children, ",") + ")"
+}
+
+func TestMapboxFilterToOgc(t *testing.T) {
+	w := sld.Writer{Version: sld.Version10}
+	tests := []struct {
+		filter   interface{}
+		expected string
+	}{
+		{
+			filter:   []interface{}{"==", "class", "river"},
+			expected: "PropertyIsEqualTo(PropertyName[class],Literal[river])",
+		},
+		{
+			filter:   []interface{}{"==", []interface{}{"get", "class"}, "river"},
+			expected: "PropertyIsEqualTo(PropertyName[class],Literal[river])",
+		},
+		{
+			filter:   []interface{}{"all", []interface{}{"==", "$type", "Polygon"}, []interface{}{"in", "class", "a", "b"}},
+			expected: "Or(PropertyIsEqualTo(PropertyName[class],Literal[a]),PropertyIsEqualTo(PropertyName[class],Literal[b]))",
+		},
+		{
+			filter:   []interface{}{"==", []interface{}{"to-string", []interface{}{"get", "code"}}, "0123"},
+			expected: "PropertyIsEqualTo(PropertyName[code],Literal[0123])",
+		},
+		{
+			filter:   []interface{}{"!has", "name"},
+			expected: "PropertyIsNull(PropertyName[name])",
+		},
+		{
+			filter:   []interface{}{">=", []interface{}{"get", "population"}, 1000.0},
+			expected: "PropertyIsGreaterThanOrEqualTo(PropertyName[population],Literal[1000])",
+		},
+		{
+			filter:   []interface{}{"==", []interface{}{"geometry-type"}, "Point"},
+			expected: "",
+		},
+		{
+			filter:   []interface{}{"none", []interface{}{"==", "$type", "Point"}, []interface{}{"==", "a", 1.0}},
+			expected: "Not(PropertyIsEqualTo(PropertyName[a],Literal[1]))",
+		},
+		{
+			filter:   []interface{}{"!", []interface{}{"any", []interface{}{"==", "$type", "Point"}, []interface{}{"==", "a", 1.0}, []interface{}{"==", "b", 2.0}}},
+			expected: "Not(Or(PropertyIsEqualTo(PropertyName[a],Literal[1]),PropertyIsEqualTo(PropertyName[b],Literal[2])))",
+		},
+		{
+			filter:   []interface{}{"any", []interface{}{"==", "$type", "Point"}, []interface{}{"==", "a", 1.0}},
+			expected: "",
+		},
+	}
+	for _, test := range tests {
+		node, err := mapboxFilterToOgc(w, test.filter)
+		require.Nil(t, err)
+		if test.expected == "" {
+			require.Nil(t, node)
+			continue
+		}
+		require.Equal(t, test.expected, outline(node))
+	}
+
+	_, err := mapboxFilterToOgc(w, []interface{}{"within", map[string]interface{}{}})
+	require.NotNil(t, err)
+}
+
+func TestMapboxToSld(t *testing.T) {
+	style, err := mapbox.Parse([]byte( //language=json
+		`{
+		  "version": 8,
+		  "name": "test",
+		  "sources": {"test": {"type": "vector"}},
+		  "layers": [
+			{"id": "background", "type": "background"},
+			{
+			  "id": "water",
+			  "type": "fill",
+			  "source": "test",
+			  "source-layer": "water",
+			  "maxzoom": 14,
+			  "filter": ["==", "class", "lake"],
+			  "paint": {"fill-color": "rgba(0, 0, 255, 0.5)"}
+			},
+			{
+			  "id": "roads",
+			  "type": "line",
+			  "source": "test",
+			  "source-layer": "roads",
+			  "minzoom": 10,
+			  "paint": {"line-color": "red", "line-width": {"stops": [[10, 1], [14, 5]]}}
+			}
+		  ]
+		}`))
+	require.Nil(t, err)
+
+	root, err := MapboxToSld(style, SldOptions{Version: sld.Version10, Name: "test", Zoom: DefaultZoom})
+	require.Nil(t, err)
+	content, err := root.Encode()
+	require.Nil(t, err)
+	require.Equal(t, comparable(bytes.NewBuffer([]byte( //language=xml
+		`<?xml version="1.0" encoding="UTF-8"?>
+		<sld:StyledLayerDescriptor xmlns:ogc="http://www.opengis.net/ogc" xmlns:sld="http://www.opengis.net/sld" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" version="1.0.0" xsi:schemaLocation="http://www.opengis.net/sld http://schemas.opengis.net/sld/1.0.0/StyledLayerDescriptor.xsd">
+		  <sld:NamedLayer>
+			<sld:Name>water</sld:Name>
+			<sld:UserStyle>
+			  <sld:Name>test</sld:Name>
+			  <sld:FeatureTypeStyle>
+				<sld:Rule>
+				  <sld:Name>water</sld:Name>
+				  <ogc:Filter>
+					<ogc:PropertyIsEqualTo>
+					  <ogc:PropertyName>class</ogc:PropertyName>
+					  <ogc:Literal>lake</ogc:Literal>
+					</ogc:PropertyIsEqualTo>
+				  </ogc:Filter>
+				  <sld:MinScaleDenominator>17061.837</sld:MinScaleDenominator>
+				  <sld:PolygonSymbolizer>
+					<sld:Fill>
+					  <sld:CssParameter name="fill">#0000ff</sld:CssParameter>
+					  <sld:CssParameter name="fill-opacity">0.502</sld:CssParameter>
+					</sld:Fill>
+				  </sld:PolygonSymbolizer>
+				</sld:Rule>
+			  </sld:FeatureTypeStyle>
+			</sld:UserStyle>
+		  </sld:NamedLayer>
+		  <sld:NamedLayer>
+			<sld:Name>roads</sld:Name>
+			<sld:UserStyle>
+			  <sld:Name>test</sld:Name>
+			  <sld:FeatureTypeStyle>
+				<sld:Rule>
+				  <sld:Name>roads</sld:Name>
+				  <sld:MaxScaleDenominator>272989.387</sld:MaxScaleDenominator>
+				  <sld:LineSymbolizer>
+					<sld:Stroke>
+					  <sld:CssParameter name="stroke">#ff0000</sld:CssParameter>
+					  <sld:CssParameter name="stroke-opacity">1</sld:CssParameter>
+					  <sld:CssParameter name="stroke-width">3</sld:CssParameter>
+					</sld:Stroke>
+				  </sld:LineSymbolizer>
+				</sld:Rule>
+			  </sld:FeatureTypeStyle>
+			</sld:UserStyle>
+		  </sld:NamedLayer>
+		</sld:StyledLayerDescriptor>`))), comparable(content))
+	require.Empty(t, sld.Validate(content.Bytes()))
+}
+
+func TestMapboxToSldNothingToConvert(t *testing.T) {
+	style := &mapbox.Style{Version: 8, Name: "empty", Layers: []mapbox.Layer{{Id: "background", Type: mapbox.Background}}}
+	_, err := MapboxToSld(style, SldOptions{})
+	require.NotNil(t, err)
+}
@@ -0,0 +1,642 @@
+package convert
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pdok/goas/pkg/mapbox"
+	"github.com/pdok/goas/pkg/sld"
+)
+
+// maxZoom the highest zoom level of a Mapbox style
+const maxZoom = 24
+
+// MapboxOptions the options of an SLD to Mapbox conversion
+type MapboxOptions struct {
+	Name    string                   // the name of the style
+	Sprite  string                   // the sprite url (optional)
+	Glyphs  string                   // the glyphs url (optional, required for text)
+	Sources map[string]mapbox.Source // the sources of the style
+	Layers  map[string]LayerSource   // the source (layer) per NamedLayer name
+}
+
+// LayerSource the source and source-layer the layers of a NamedLayer are rendered from
+type LayerSource struct {
+	Source      string // defaults to the only source of the style
+	SourceLayer string // defaults to the name of the NamedLayer
+}
+
+// SldToMapbox converts the polygon, line, point (marks) and text symbolizers of an SLD 1.0 or SE 1.1 document to a
+// Mapbox style. Every symbolizer of a rule becomes a layer, in the order of the NamedLayers, FeatureTypeStyles and
+// rules of the SLD. Symbolizers and rules that cannot be converted are skipped with a warning.
+func SldToMapbox(root *sld.Node, options MapboxOptions) (*mapbox.Style, error) {
+	_, err := sld.Version(root)
+	if err != nil {
+		return nil, err
+	}
+	style := &mapbox.Style{
+		Version: 8,
+		Name:    options.Name,
+		Glyphs:  options.Glyphs,
+		Sources: options.Sources,
+		Layers:  []mapbox.Layer{},
+	}
+	if options.Sprite != "" {
+		style.Sprite = options.Sprite
+	}
+	if style.Sources == nil {
+		style.Sources = make(map[string]mapbox.Source)
+	}
+	ids := make(map[string]bool)
+	for _, namedLayer := range root.ChildrenNamed("NamedLayer") {
+		name := namedLayer.Child("Name").Text()
+		source, err := options.layerSource(name)
+		if err != nil {
+			return nil, err
+		}
+		userStyle := defaultUserStyle(namedLayer)
+		if userStyle == nil {
+			log.Printf("warning: skipping NamedLayer %s of the sld without a UserStyle", name)
+			continue
+		}
+		for _, featureTypeStyle := range userStyle.ChildrenNamed("FeatureTypeStyle") {
+			rules := featureTypeStyle.ChildrenNamed("Rule")
+			for i, rule := range rules {
+				layers, err := ruleToLayers(rule, rules, source)
+				if err != nil {
+					log.Printf("warning: skipping rule %d of NamedLayer %s in the mapbox style: %s", i+1, name, err)
+					continue
+				}
+				baseId := name + "-" + strconv.Itoa(i+1)
+				if ruleName := rule.Child("Name").Text(); ruleName != "" {
+					baseId = name + "-" + ruleName
+				}
+				for j := range layers {
+					id := baseId
+					if len(layers) > 1 {
+						id += "-" + strconv.Itoa(j+1)
+					}
+					layers[j].Id = uniqueId(ids, id)
+				}
+				style.Layers = append(style.Layers, layers...)
+			}
+		}
+	}
+	if len(style.Layers) == 0 {
+		return nil, fmt.Errorf("none of the rules of the sld can be converted to a mapbox style")
+	}
+	return style, nil
+}
+
+func (options MapboxOptions) layerSource(namedLayer string) (LayerSource, error) {
+	source := options.Layers[namedLayer]
+	if source.SourceLayer == "" {
+		source.SourceLayer = namedLayer
+	}
+	if source.Source == "" {
+		if len(options.Sources) != 1 {
+			return source, fmt.Errorf("no source configured for NamedLayer %s", namedLayer)
+		}
+		for name := range options.Sources {
+			source.Source = name
+		}
+	}
+	if _, ok := options.Sources[source.Source]; !ok {
+		return source, fmt.Errorf("source %s of NamedLayer %s not found in the sources", source.Source, namedLayer)
+	}
+	return source, nil
+}
+
+// defaultUserStyle the UserStyle marked as IsDefault, the first one otherwise
+func defaultUserStyle(namedLayer *sld.Node) *sld.Node {
+	userStyles := namedLayer.ChildrenNamed("UserStyle")
+	for _, userStyle := range userStyles {
+		isDefault := userStyle.Child("IsDefault").Text()
+		if isDefault == "1" || isDefault == "true" {
+			return userStyle
+		}
+	}
+	if len(userStyles) == 0 {
+		return nil
+	}
+	return userStyles[0]
+}
+
+func uniqueId(ids map[string]bool, id string) string {
+	unique := id
+	for i := 2; ids[unique]; i++ {
+		unique = id + "-" + strconv.Itoa(i)
+	}
+	ids[unique] = true
+	return unique
+}
+
+func ruleToLayers(rule *sld.Node, rules []*sld.Node, source LayerSource) ([]mapbox.Layer, error) {
+	filter, err := ruleFilter(rule, rules)
+	if err != nil {
+		return nil, err
+	}
+	template := mapbox.Layer{Source: source.Source, SourceLayer: source.SourceLayer, Filter: filter}
+	// a smaller scale denominator is a higher zoom level
+	if minScale := rule.Child("MinScaleDenominator"); minScale != nil {
+		scale, err := parseNumber(minScale.Text())
+		if err != nil || scale < 0 {
+			return nil, fmt.Errorf("invalid MinScaleDenominator: %s", minScale.Text())
+		}
+		if scale > 0 {
+			zoom := math.Min(roundNumber(scaleDenominatorToZoom(scale)), maxZoom)
+			template.MaxZoom = &zoom
+		}
+	}
+	if maxScale := rule.Child("MaxScaleDenominator"); maxScale != nil {
+		scale, err := parseNumber(maxScale.Text())
+		if err != nil || scale < 0 {
+			return nil, fmt.Errorf("invalid MaxScaleDenominator: %s", maxScale.Text())
+		}
+		if zoom := roundNumber(scaleDenominatorToZoom(scale)); zoom > 0 {
+			zoom = math.Min(zoom, maxZoom)
+			template.MinZoom = &zoom
+		}
+	}
+
+	var layers []mapbox.Layer
+	for _, symbolizer := range rule.Elements() {
+		var converted []mapbox.Layer
+		var err error
+		switch symbolizer.XMLName.Local {
+		case "PolygonSymbolizer":
+			converted, err = polygonLayers(symbolizer, template)
+		case "LineSymbolizer":
+			converted, err = lineLayers(symbolizer, template)
+		case "PointSymbolizer":
+			converted, err = pointLayers(symbolizer, template)
+		case "TextSymbolizer":
+			converted, err = textLayers(symbolizer, template)
+		case "RasterSymbolizer":
+			err = fmt.Errorf("RasterSymbolizer is not supported")
+		default:
+			continue
+		}
+		if err != nil {
+			log.Printf("warning: skipping %s in the mapbox style: %s", symbolizer.XMLName.Local, err)
+			continue
+		}
+		layers = append(layers, converted...)
+	}
+	if layers == nil {
+		return nil, fmt.Errorf("no symbolizer could be converted")
+	}
+	return layers, nil
+}
+
+// ruleFilter the mapbox filter of the rule, an ElseFilter matches what none of the other filters of the
+// FeatureTypeStyle match
+func ruleFilter(rule *sld.Node, rules []*sld.Node) (interface{}, error) {
+	if filter := rule.Child("Filter"); filter != nil {
+		return ogcFilterToMapbox(filter)
+	}
+	if rule.Child("ElseFilter") == nil {
+		return nil, nil
+	}
+	others := []interface{}{"any"}
+	for _, other := range rules {
+		if other == rule || other.Child("ElseFilter") != nil {
+			continue
+		}
+		filter := other.Child("Filter")
+		if filter == nil {
+			return nil, fmt.Errorf("ElseFilter never matches, since a rule without filter matches all features")
+		}
+		converted, err := ogcFilterToMapbox(filter)
+		if err != nil {
+			return nil, err
+		}
+		others = append(others, converted)
+	}
+	return []interface{}{"!", others}, nil
+}
+
+func roundNumber(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
+
+func polygonLayers(symbolizer *sld.Node, template mapbox.Layer) ([]mapbox.Layer, error) {
+	var layers []mapbox.Layer
+	if fillNode := symbolizer.Child("Fill"); fillNode != nil {
+		if fillNode.Child("GraphicFill") != nil {
+			return nil, fmt.Errorf("GraphicFill is not supported")
+		}
+		fillColor, opacity, err := parseFill(fillNode, "#808080")
+		if err != nil {
+			return nil, err
+		}
+		layer := template
+		layer.Type = mapbox.Fill
+		layer.Paint = map[string]interface{}{"fill-color": fillColor, "fill-opacity": opacity}
+		layers = append(layers, layer)
+	}
+	if strokeNode := symbolizer.Child("Stroke"); strokeNode != nil {
+		layer, err := strokeLayer(strokeNode, template)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, layer)
+	}
+	if layers == nil {
+		return nil, fmt.Errorf("PolygonSymbolizer without Fill and Stroke")
+	}
+	return layers, nil
+}
+
+func lineLayers(symbolizer *sld.Node, template mapbox.Layer) ([]mapbox.Layer, error) {
+	strokeNode := symbolizer.Child("Stroke")
+	if strokeNode == nil {
+		return nil, fmt.Errorf("LineSymbolizer without Stroke")
+	}
+	layer, err := strokeLayer(strokeNode, template)
+	if err != nil {
+		return nil, err
+	}
+	if offset := symbolizer.Child("PerpendicularOffset"); offset != nil {
+		value, err := parseNumber(offset.Text())
+		if err != nil {
+			return nil, fmt.Errorf("invalid PerpendicularOffset: %s", offset.Text())
+		}
+		// a positive perpendicular offset is to the left, a positive line-offset to the right
+		layer.Paint["line-offset"] = -value
+	}
+	return []mapbox.Layer{layer}, nil
+}
+
+func strokeLayer(strokeNode *sld.Node, template mapbox.Layer) (mapbox.Layer, error) {
+	if strokeNode.Child("GraphicStroke") != nil || strokeNode.Child("GraphicFill") != nil {
+		return mapbox.Layer{}, fmt.Errorf("graphic strokes are not supported")
+	}
+	parameters, err := svgParameters(strokeNode)
+	if err != nil {
+		return mapbox.Layer{}, err
+	}
+	strokeColor, err := cssColor(parameters, "stroke", "#000000")
+	if err != nil {
+		return mapbox.Layer{}, err
+	}
+	opacity, err := numberParameter(parameters, "stroke-opacity", 1)
+	if err != nil {
+		return mapbox.Layer{}, err
+	}
+	width, err := numberParameter(parameters, "stroke-width", 1)
+	if err != nil {
+		return mapbox.Layer{}, err
+	}
+	layer := template
+	layer.Type = mapbox.Line
+	layer.Paint = map[string]interface{}{"line-color": strokeColor, "line-opacity": opacity, "line-width": width}
+	if dashes, ok := parameters["stroke-dasharray"]; ok {
+		var dashArray []interface{}
+		for _, dash := range strings.Fields(strings.Replace(dashes, ",", " ", -1)) {
+			length, err := parseNumber(dash)
+			if err != nil {
+				return mapbox.Layer{}, fmt.Errorf("invalid stroke-dasharray: %s", dashes)
+			}
+			// mapbox dashes are in line widths
+			if width > 0 {
+				length = length / width
+			}
+			dashArray = append(dashArray, roundNumber(length))
+		}
+		layer.Paint["line-dasharray"] = dashArray
+	}
+	layout := make(map[string]interface{})
+	if join, ok := parameters["stroke-linejoin"]; ok {
+		if join == "mitre" {
+			join = "miter"
+		}
+		layout["line-join"] = join
+	}
+	if lineCap, ok := parameters["stroke-linecap"]; ok {
+		layout["line-cap"] = lineCap
+	}
+	if len(layout) > 0 {
+		layer.Layout = layout
+	}
+	return layer, nil
+}
+
+func pointLayers(symbolizer *sld.Node, template mapbox.Layer) ([]mapbox.Layer, error) {
+	graphic := symbolizer.Child("Graphic")
+	if graphic == nil {
+		return nil, fmt.Errorf("PointSymbolizer without Graphic")
+	}
+	mark := graphic.Child("Mark")
+	if mark == nil {
+		return nil, fmt.Errorf("only Marks are supported")
+	}
+	if wellKnownName := mark.Child("WellKnownName").Text(); wellKnownName != "" && wellKnownName != "circle" {
+		log.Printf("warning: mark %s is drawn as a circle in the mapbox style", wellKnownName)
+	}
+	size, err := numberElement(graphic, "Size", 6)
+	if err != nil {
+		return nil, err
+	}
+	graphicOpacity, err := numberElement(graphic, "Opacity", 1)
+	if err != nil {
+		return nil, err
+	}
+	layer := template
+	layer.Type = mapbox.Circle
+	layer.Paint = map[string]interface{}{"circle-radius": size / 2}
+	if fillNode := mark.Child("Fill"); fillNode != nil {
+		fillColor, opacity, err := parseFill(fillNode, "#808080")
+		if err != nil {
+			return nil, err
+		}
+		layer.Paint["circle-color"] = fillColor
+		layer.Paint["circle-opacity"] = roundNumber(opacity * graphicOpacity)
+	} else {
+		layer.Paint["circle-opacity"] = 0.0
+	}
+	if strokeNode := mark.Child("Stroke"); strokeNode != nil {
+		parameters, err := svgParameters(strokeNode)
+		if err != nil {
+			return nil, err
+		}
+		strokeColor, err := cssColor(parameters, "stroke", "#000000")
+		if err != nil {
+			return nil, err
+		}
+		opacity, err := numberParameter(parameters, "stroke-opacity", 1)
+		if err != nil {
+			return nil, err
+		}
+		width, err := numberParameter(parameters, "stroke-width", 1)
+		if err != nil {
+			return nil, err
+		}
+		layer.Paint["circle-stroke-color"] = strokeColor
+		layer.Paint["circle-stroke-opacity"] = roundNumber(opacity * graphicOpacity)
+		layer.Paint["circle-stroke-width"] = width
+	}
+	return []mapbox.Layer{layer}, nil
+}
+
+func textLayers(symbolizer *sld.Node, template mapbox.Layer) ([]mapbox.Layer, error) {
+	label := symbolizer.Child("Label")
+	if label == nil {
+		return nil, fmt.Errorf("TextSymbolizer without Label")
+	}
+	textField, err := labelTextField(label)
+	if err != nil {
+		return nil, err
+	}
+	layer := template
+	layer.Type = mapbox.Symbol
+	layer.Layout = map[string]interface{}{"text-field": textField}
+	layer.Paint = make(map[string]interface{})
+
+	size := 10.0
+	if font := symbolizer.Child("Font"); font != nil {
+		parameters, err := svgParameters(font)
+		if err != nil {
+			return nil, err
+		}
+		size, err = numberParameter(parameters, "font-size", size)
+		if err != nil {
+			return nil, err
+		}
+		if family, ok := parameters["font-family"]; ok {
+			layer.Layout["text-font"] = []interface{}{fontName(family, parameters["font-style"], parameters["font-weight"])}
+		}
+	}
+	layer.Layout["text-size"] = size
+
+	if placement := symbolizer.Child("LabelPlacement"); placement != nil {
+		if placement.Child("LinePlacement") != nil {
+			layer.Layout["symbol-placement"] = "line"
+		} else if pointPlacement := placement.Child("PointPlacement"); pointPlacement != nil {
+			if anchor := pointPlacement.Child("AnchorPoint"); anchor != nil {
+				x, err := numberElement(anchor, "AnchorPointX", 0.5)
+				if err != nil {
+					return nil, err
+				}
+				y, err := numberElement(anchor, "AnchorPointY", 0.5)
+				if err != nil {
+					return nil, err
+				}
+				layer.Layout["text-anchor"] = textAnchor(x, y)
+			}
+			if displacement := pointPlacement.Child("Displacement"); displacement != nil {
+				x, err := numberElement(displacement, "DisplacementX", 0)
+				if err != nil {
+					return nil, err
+				}
+				y, err := numberElement(displacement, "DisplacementY", 0)
+				if err != nil {
+					return nil, err
+				}
+				// sld displacements are in pixels pointing up, mapbox offsets in ems pointing down
+				if size > 0 && (x != 0 || y != 0) {
+					layer.Layout["text-offset"] = []interface{}{roundNumber(x / size), roundNumber(-y / size)}
+				}
+			}
+			if rotation := pointPlacement.Child("Rotation"); rotation != nil {
+				value, err := parseNumber(rotation.Text())
+				if err != nil {
+					return nil, fmt.Errorf("unsupported Rotation: %s", rotation.Text())
+				}
+				layer.Layout["text-rotate"] = value
+			}
+		}
+	}
+	if halo := symbolizer.Child("Halo"); halo != nil {
+		radius, err := numberElement(halo, "Radius", 1)
+		if err != nil {
+			return nil, err
+		}
+		haloColor := "#ffffff"
+		if fillNode := halo.Child("Fill"); fillNode != nil {
+			haloColor, _, err = parseFill(fillNode, haloColor)
+			if err != nil {
+				return nil, err
+			}
+		}
+		layer.Paint["text-halo-width"] = radius
+		layer.Paint["text-halo-color"] = haloColor
+	}
+	textColor, opacity := "#000000", 1.0
+	if fillNode := symbolizer.Child("Fill"); fillNode != nil {
+		textColor, opacity, err = parseFill(fillNode, textColor)
+		if err != nil {
+			return nil, err
+		}
+	}
+	layer.Paint["text-color"] = textColor
+	layer.Paint["text-opacity"] = opacity
+	return []mapbox.Layer{layer}, nil
+}
+
+// labelTextField converts the mixed content of a Label to a text-field: a string, a get or a concat expression
+func labelTextField(label *sld.Node) (interface{}, error) {
+	if len(label.Children) == 0 {
+		if label.Text() == "" {
+			return nil, fmt.Errorf("empty Label")
+		}
+		return label.Text(), nil
+	}
+	var parts []interface{}
+	for i, child := range label.Children {
+		switch child.XMLName.Local {
+		case "":
+			text := child.Content
+			if i == 0 {
+				text = strings.TrimLeft(text, " \t\r\n")
+			}
+			if i == len(label.Children)-1 {
+				text = strings.TrimRight(text, " \t\r\n")
+			}
+			if text != "" {
+				parts = append(parts, text)
+			}
+		case "PropertyName":
+			parts = append(parts, []interface{}{"get", child.Text()})
+		case "Literal":
+			parts = append(parts, child.Content)
+		default:
+			return nil, fmt.Errorf("unsupported Label expression: %s", child.XMLName.Local)
+		}
+	}
+	if len(parts) == 1 {
+		return parts[0], nil
+	}
+	return append([]interface{}{"concat"}, parts...), nil
+}
+
+// fontName combines a font family, style and weight to a font name like "Open Sans Bold Italic"
+func fontName(family string, style string, weight string) string {
+	name := strings.TrimSpace(strings.Split(family, ",")[0])
+	bold := weight == "bold"
+	italic := style == "italic" || style == "oblique"
+	switch {
+	case bold && italic:
+		return name + " Bold Italic"
+	case bold:
+		return name + " Bold"
+	case italic:
+		return name + " Italic"
+	default:
+		return name + " Regular"
+	}
+}
+
+func textAnchor(x float64, y float64) string {
+	var vertical, horizontal string
+	if y > 0.75 {
+		vertical = "top"
+	} else if y < 0.25 {
+		vertical = "bottom"
+	}
+	if x < 0.25 {
+		horizontal = "left"
+	} else if x > 0.75 {
+		horizontal = "right"
+	}
+	switch {
+	case vertical != "" && horizontal != "":
+		return vertical + "-" + horizontal
+	case vertical != "":
+		return vertical
+	case horizontal != "":
+		return horizontal
+	default:
+		return "center"
+	}
+}
+
+// svgParameters the CssParameters (SLD 1.0) or SvgParameters (SE 1.1) by name, only literal values are supported
+func svgParameters(node *sld.Node) (map[string]string, error) {
+	parameters := make(map[string]string)
+	for _, child := range node.Elements() {
+		if child.XMLName.Local != "CssParameter" && child.XMLName.Local != "SvgParameter" {
+			continue
+		}
+		name, _ := child.Attr("name")
+		var value string
+		switch elements := child.Elements(); {
+		case len(elements) == 0:
+			value = child.Text()
+		case len(elements) == 1 && elements[0].XMLName.Local == "Literal":
+			value = elements[0].Text()
+		default:
+			return nil, fmt.Errorf("parameter %s is data driven", name)
+		}
+		parameters[name] = value
+	}
+	return parameters, nil
+}
+
+func parseFill(fillNode *sld.Node, defaultColor string) (string, float64, error) {
+	parameters, err := svgParameters(fillNode)
+	if err != nil {
+		return "", 0, err
+	}
+	fillColor, err := cssColor(parameters, "fill", defaultColor)
+	if err != nil {
+		return "", 0, err
+	}
+	opacity, err := numberParameter(parameters, "fill-opacity", 1)
+	return fillColor, opacity, err
+}
+
+func cssColor(parameters map[string]string, name string, defaultColor string) (string, error) {
+	value, ok := parameters[name]
+	if !ok {
+		value = defaultColor
+	}
+	parsed, err := mapbox.ParseColor(value)
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", name, err)
+	}
+	return mapbox.ToCss(parsed), nil
+}
+
+// parseNumber parses a finite number, NaN and infinity are not numbers of a style
+func parseNumber(text string) (float64, error) {
+	number, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, fmt.Errorf("%s is not a finite number", text)
+	}
+	return number, nil
+}
+
+func numberParameter(parameters map[string]string, name string, defaultValue float64) (float64, error) {
+	value, ok := parameters[name]
+	if !ok {
+		return defaultValue, nil
+	}
+	number, err := parseNumber(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s should be a number, found: %s", name, value)
+	}
+	return number, nil
+}
+
+func numberElement(node *sld.Node, local string, defaultValue float64) (float64, error) {
+	child := node.Child(local)
+	if child == nil {
+		return defaultValue, nil
+	}
+	text := child.Text()
+	if literal := child.Child("Literal"); literal != nil {
+		text = literal.Text()
+	}
+	number, err := parseNumber(text)
+	if err != nil {
+		return 0, fmt.Errorf("%s should be a number, found: %s", local, text)
+	}
+	return number, nil
+}
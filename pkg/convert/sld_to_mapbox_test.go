@@ -0,0 +1,164 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/pdok/goas/pkg/mapbox"
+	"github.com/pdok/goas/pkg/sld"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOgcFilterToMapbox(t *testing.T) {
+	tests := []struct {
+		filter   string
+		expected interface{}
+	}{
+		{
+			filter:   `<ogc:PropertyIsEqualTo><ogc:PropertyName>class</ogc:PropertyName><ogc:Literal>river</ogc:Literal></ogc:PropertyIsEqualTo>`,
+			expected: []interface{}{"==", []interface{}{"get", "class"}, "river"},
+		},
+		{
+			filter:   `<ogc:PropertyIsLessThan><ogc:Literal>10</ogc:Literal><ogc:PropertyName>width</ogc:PropertyName></ogc:PropertyIsLessThan>`,
+			expected: []interface{}{">", []interface{}{"get", "width"}, 10.0},
+		},
+		{
+			filter:   `<ogc:PropertyIsEqualTo><ogc:PropertyName>code</ogc:PropertyName><ogc:Literal>0123</ogc:Literal></ogc:PropertyIsEqualTo>`,
+			expected: []interface{}{"==", []interface{}{"to-string", []interface{}{"get", "code"}}, "0123"},
+		},
+		{
+			filter:   `<ogc:PropertyIsNotEqualTo><ogc:Literal>2</ogc:Literal><ogc:PropertyName>lanes</ogc:PropertyName></ogc:PropertyIsNotEqualTo>`,
+			expected: []interface{}{"!=", []interface{}{"to-string", []interface{}{"get", "lanes"}}, "2"},
+		},
+		{
+			filter:   `<ogc:PropertyIsEqualTo><ogc:PropertyName>code</ogc:PropertyName><ogc:Literal>NaN</ogc:Literal></ogc:PropertyIsEqualTo>`,
+			expected: []interface{}{"==", []interface{}{"get", "code"}, "NaN"},
+		},
+		{
+			filter:   `<ogc:PropertyIsEqualTo><ogc:PropertyName>code</ogc:PropertyName><ogc:Literal>Inf</ogc:Literal></ogc:PropertyIsEqualTo>`,
+			expected: []interface{}{"==", []interface{}{"get", "code"}, "Inf"},
+		},
+		{
+			filter: `<ogc:And><ogc:Not><ogc:PropertyIsNull><ogc:PropertyName>name</ogc:PropertyName></ogc:PropertyIsNull></ogc:Not>` +
+				`<ogc:PropertyIsBetween><ogc:PropertyName>pop</ogc:PropertyName><ogc:LowerBoundary><ogc:Literal>1</ogc:Literal></ogc:LowerBoundary>` +
+				`<ogc:UpperBoundary><ogc:Literal>5</ogc:Literal></ogc:UpperBoundary></ogc:PropertyIsBetween></ogc:And>`,
+			expected: []interface{}{"all",
+				[]interface{}{"!", []interface{}{"!", []interface{}{"has", "name"}}},
+				[]interface{}{"all", []interface{}{">=", []interface{}{"get", "pop"}, 1.0}, []interface{}{"<=", []interface{}{"get", "pop"}, 5.0}}},
+		},
+	}
+	for _, test := range tests {
+		filter, err := sld.Parse([]byte(`<ogc:Filter xmlns:ogc="http://www.opengis.net/ogc">` + test.filter + `</ogc:Filter>`))
+		require.Nil(t, err)
+		converted, err := ogcFilterToMapbox(filter)
+		require.Nil(t, err)
+		require.Equal(t, test.expected, converted)
+	}
+
+	filter, _ := sld.Parse([]byte(`<ogc:Filter xmlns:ogc="http://www.opengis.net/ogc"><ogc:FeatureId fid="1"/></ogc:Filter>`))
+	_, err := ogcFilterToMapbox(filter)
+	require.NotNil(t, err)
+}
+
+func TestOgcLikeFilterToMapbox(t *testing.T) {
+	name := []interface{}{"to-string", []interface{}{"get", "name"}}
+	tests := []struct {
+		pattern  string
+		expected interface{}
+	}{
+		{pattern: `Main street`, expected: []interface{}{"==", name, "Main street"}},
+		{pattern: `Main*`, expected: []interface{}{"==", []interface{}{"index-of", "Main", name}, 0.0}},
+		{pattern: `*street*`, expected: []interface{}{">=", []interface{}{"index-of", "street", name}, 0.0}},
+		{pattern: `100!*`, expected: []interface{}{"==", name, "100*"}},
+		{pattern: `*`, expected: []interface{}{"has", "name"}},
+	}
+	for _, test := range tests {
+		filter, err := sld.Parse([]byte(`<ogc:Filter xmlns:ogc="http://www.opengis.net/ogc"><ogc:PropertyIsLike wildCard="*" singleChar="." escape="!">` +
+			`<ogc:PropertyName>name</ogc:PropertyName><ogc:Literal>` + test.pattern + `</ogc:Literal></ogc:PropertyIsLike></ogc:Filter>`))
+		require.Nil(t, err)
+		converted, err := ogcFilterToMapbox(filter)
+		require.Nil(t, err)
+		require.Equal(t, test.expected, converted, test.pattern)
+	}
+
+	filter, _ := sld.Parse([]byte(`<ogc:Filter xmlns:ogc="http://www.opengis.net/ogc"><ogc:PropertyIsLike wildCard="%" singleChar="_" escapeChar="\" matchCase="false">` +
+		`<ogc:PropertyName>name</ogc:PropertyName><ogc:Literal>Main%</ogc:Literal></ogc:PropertyIsLike></ogc:Filter>`))
+	converted, err := ogcFilterToMapbox(filter)
+	require.Nil(t, err)
+	require.Equal(t, []interface{}{"==", []interface{}{"index-of", "main", []interface{}{"downcase", name}}, 0.0}, converted)
+
+	for _, pattern := range []string{`M.in`, `*street`, `Main*street`} {
+		filter, _ = sld.Parse([]byte(`<ogc:Filter xmlns:ogc="http://www.opengis.net/ogc"><ogc:PropertyIsLike wildCard="*" singleChar="." escape="!">` +
+			`<ogc:PropertyName>name</ogc:PropertyName><ogc:Literal>` + pattern + `</ogc:Literal></ogc:PropertyIsLike></ogc:Filter>`))
+		_, err = ogcFilterToMapbox(filter)
+		require.ErrorContains(t, err, "PropertyIsLike pattern "+pattern+" is not supported", pattern)
+	}
+}
+
+func TestSldToMapbox(t *testing.T) {
+	root, err := sld.Parse([]byte( //language=xml
+		`<StyledLayerDescriptor version="1.1.0" xmlns="http://www.opengis.net/sld" xmlns:se="http://www.opengis.net/se" xmlns:ogc="http://www.opengis.net/ogc">
+		  <NamedLayer>
+			<se:Name>water</se:Name>
+			<UserStyle>
+			  <se:FeatureTypeStyle>
+				<se:Rule>
+				  <se:MinScaleDenominator>17061.837</se:MinScaleDenominator>
+				  <se:PolygonSymbolizer>
+					<se:Fill>
+					  <se:SvgParameter name="fill">#0000ff</se:SvgParameter>
+					  <se:SvgParameter name="fill-opacity">0.5</se:SvgParameter>
+					</se:Fill>
+				  </se:PolygonSymbolizer>
+				</se:Rule>
+				<se:Rule>
+				  <se:Name>labels</se:Name>
+				  <se:TextSymbolizer>
+					<se:Label>Lake <ogc:PropertyName>name</ogc:PropertyName></se:Label>
+					<se:Font>
+					  <se:SvgParameter name="font-family">Noto Sans</se:SvgParameter>
+					  <se:SvgParameter name="font-style">italic</se:SvgParameter>
+					</se:Font>
+					<se:LabelPlacement><se:LinePlacement/></se:LabelPlacement>
+				  </se:TextSymbolizer>
+				</se:Rule>
+			  </se:FeatureTypeStyle>
+			</UserStyle>
+		  </NamedLayer>
+		</StyledLayerDescriptor>`))
+	require.Nil(t, err)
+
+	sources := map[string]mapbox.Source{"test": {Type: "vector", Url: "https://example.org/tiles.json"}}
+	style, err := SldToMapbox(root, MapboxOptions{Name: "test", Sources: sources, Layers: map[string]LayerSource{"water": {SourceLayer: "lakes"}}})
+	require.Nil(t, err)
+	require.Equal(t, 8, style.Version)
+	require.Len(t, style.Layers, 2)
+
+	fill := style.Layers[0]
+	require.Equal(t, "water-1", fill.Id)
+	require.Equal(t, mapbox.Fill, fill.Type)
+	require.Equal(t, "test", fill.Source)
+	require.Equal(t, "lakes", fill.SourceLayer)
+	require.Equal(t, 14.0, *fill.MaxZoom)
+	require.Nil(t, fill.MinZoom)
+	require.Equal(t, map[string]interface{}{"fill-color": "#0000ff", "fill-opacity": 0.5}, fill.Paint)
+
+	text := style.Layers[1]
+	require.Equal(t, "water-labels", text.Id)
+	require.Equal(t, mapbox.Symbol, text.Type)
+	require.Equal(t, []interface{}{"concat", "Lake ", []interface{}{"get", "name"}}, text.Layout["text-field"])
+	require.Equal(t, []interface{}{"Noto Sans Italic"}, text.Layout["text-font"])
+	require.Equal(t, "line", text.Layout["symbol-placement"])
+
+	_, err = SldToMapbox(root, MapboxOptions{Name: "test"})
+	require.NotNil(t, err)
+}
+
+func TestNumberParameter(t *testing.T) {
+	width, err := numberParameter(map[string]string{"stroke-width": "2.5"}, "stroke-width", 1)
+	require.Nil(t, err)
+	require.Equal(t, 2.5, width)
+	for _, value := range []string{"NaN", "Inf", "-infinity", "1e400"} {
+		_, err = numberParameter(map[string]string{"stroke-width": value}, "stroke-width", 1)
+		require.EqualError(t, err, "stroke-width should be a number, found: "+value)
+	}
+}
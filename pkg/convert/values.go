@@ -0,0 +1,150 @@
+package convert
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/pdok/goas/pkg/mapbox"
+)
+
+// zoomZeroScaleDenominator the scale denominator at zoom level 0 of a Mapbox GL map (512 pixel tiles of 0.28 mm
+// in WebMercator), each next zoom level halves it
+const zoomZeroScaleDenominator = 279541132.0143589
+
+func zoomToScaleDenominator(zoom float64) float64 {
+	return zoomZeroScaleDenominator / math.Pow(2, zoom)
+}
+
+func scaleDenominatorToZoom(scaleDenominator float64) float64 {
+	return math.Log2(zoomZeroScaleDenominator / scaleDenominator)
+}
+
+// literalValue resolves a paint or layout property to a single value. Zoom dependent values (legacy stop functions,
+// interpolate and step expressions) are evaluated at the given zoom level, data driven values cannot be resolved.
+func literalValue(value interface{}, zoom float64) (interface{}, bool) {
+	switch v := value.(type) {
+	case nil:
+		return nil, false
+	case string, float64, bool:
+		return v, true
+	case map[string]interface{}:
+		// legacy function: {"stops": [[zoom, value], ...]}
+		if _, ok := v["property"]; ok {
+			return nil, false
+		}
+		stops, ok := v["stops"].([]interface{})
+		if !ok {
+			return nil, false
+		}
+		var zooms []float64
+		var values []interface{}
+		for _, stop := range stops {
+			pair, ok := stop.([]interface{})
+			if !ok || len(pair) != 2 {
+				return nil, false
+			}
+			stopZoom, ok := pair[0].(float64)
+			if !ok {
+				return nil, false
+			}
+			zooms = append(zooms, stopZoom)
+			values = append(values, pair[1])
+		}
+		return interpolate(zooms, values, zoom, true)
+	case []interface{}:
+		if len(v) == 0 {
+			return nil, false
+		}
+		operator, ok := v[0].(string)
+		if !ok {
+			// a literal array, like a dash array or a font stack
+			return v, true
+		}
+		switch operator {
+		case "literal":
+			if len(v) != 2 {
+				return nil, false
+			}
+			return v[1], true
+		case "interpolate":
+			if len(v) < 5 || !isZoomExpression(v[2]) {
+				return nil, false
+			}
+			zooms, values, ok := expressionStops(v[3:])
+			if !ok {
+				return nil, false
+			}
+			return interpolate(zooms, values, zoom, true)
+		case "step":
+			if len(v) < 3 || !isZoomExpression(v[1]) {
+				return nil, false
+			}
+			zooms, values, ok := expressionStops(v[3:])
+			if !ok {
+				return nil, false
+			}
+			zooms = append([]float64{math.Inf(-1)}, zooms...)
+			values = append([]interface{}{v[2]}, values...)
+			return interpolate(zooms, values, zoom, false)
+		default:
+			if mapbox.IsExpression(v) {
+				return nil, false
+			}
+			// a literal array, like a font stack ["Open Sans Regular"]
+			return v, true
+		}
+	default:
+		return nil, false
+	}
+}
+
+func isZoomExpression(value interface{}) bool {
+	expression, ok := value.([]interface{})
+	return ok && len(expression) == 1 && expression[0] == "zoom"
+}
+
+func expressionStops(stops []interface{}) (zooms []float64, values []interface{}, ok bool) {
+	if len(stops)%2 != 0 {
+		return nil, nil, false
+	}
+	for i := 0; i < len(stops); i += 2 {
+		stopZoom, ok := stops[i].(float64)
+		if !ok {
+			return nil, nil, false
+		}
+		value, ok := literalValue(stops[i+1], 0)
+		if !ok {
+			return nil, nil, false
+		}
+		zooms = append(zooms, stopZoom)
+		values = append(values, value)
+	}
+	return zooms, values, true
+}
+
+// interpolate the value at zoom between the stops, numbers are interpolated linearly when linear is set, all other
+// values step at the stop
+func interpolate(zooms []float64, values []interface{}, zoom float64, linear bool) (interface{}, bool) {
+	if len(zooms) == 0 {
+		return nil, false
+	}
+	if zoom <= zooms[0] {
+		return values[0], true
+	}
+	for i := 1; i < len(zooms); i++ {
+		if zoom < zooms[i] {
+			lower, lowerOk := values[i-1].(float64)
+			upper, upperOk := values[i].(float64)
+			if linear && lowerOk && upperOk {
+				fraction := (zoom - zooms[i-1]) / (zooms[i] - zooms[i-1])
+				return lower + (upper-lower)*fraction, true
+			}
+			return values[i-1], true
+		}
+	}
+	return values[len(values)-1], true
+}
+
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(math.Round(value*1000)/1000, 'f', -1, 64)
+}
@@ -2,6 +2,8 @@ package pkg
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -9,6 +11,7 @@ import (
 	"strings"
 	"text/template"
 
+	"github.com/pdok/goas/pkg/mapbox"
 	"github.com/pdok/goas/pkg/models"
 	"gopkg.in/yaml.v2"
 )
@@ -48,43 +51,220 @@ func GenerateDocuments(stylesConfig *models.StylesConfig, assetDir string, forma
 			documents = append(documents, *document)
 		}
 	}
-	styles := models.Styles{Default: stylesConfig.Default}
+	styleDocuments, err := generateStyles(stylesConfig, stylesConfig.StylesMetadata, stylesConfig.Default, "", assetDir, formats)
+	if err != nil {
+		return nil, err
+	}
+	documents = append(documents, styleDocuments...)
+	for _, collection := range stylesConfig.Collections {
+		collectionDocuments, err := generateCollectionStyles(stylesConfig, collection, assetDir, formats)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, collectionDocuments...)
+	}
+	coreDocuments, err := generateCoreDocuments(stylesConfig, formats)
+	if err != nil {
+		return nil, err
+	}
+	documents = append(documents, coreDocuments...)
+	if stylesConfig.ServiceDescription {
+		serviceDescDocuments, err := generateServiceDescription(stylesConfig, formats, documents)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, serviceDescDocuments...)
+	}
+	return documents, nil
+}
+
+// generateCoreDocuments renders the optional conformance declaration and landing page, for deployments without another
+// component implementing OGC API Common
+func generateCoreDocuments(stylesConfig *models.StylesConfig, formats []models.Format) ([]models.Document, error) {
+	var documents []models.Document
+	if stylesConfig.Conformance {
+		conformance := models.Conformance{ConformsTo: conformanceClasses(stylesConfig, formats)}
+		for _, format := range formats {
+			document, err := Render(conformance, models.ConformanceResource, format)
+			if err != nil {
+				return nil, err
+			}
+			documents = append(documents, *document)
+		}
+	}
+	if stylesConfig.LandingPage != nil {
+		landingPage := *stylesConfig.LandingPage
+		landingPage.Links = append(landingPageLinks(stylesConfig), landingPage.Links...)
+		for _, format := range formats {
+			document, err := Render(landingPage, models.LandingPageResource, format)
+			if err != nil {
+				return nil, err
+			}
+			documents = append(documents, *document)
+		}
+	}
+	return documents, nil
+}
+
+func landingPageLinks(stylesConfig *models.StylesConfig) []models.Link {
+	selfTitle := "This document"
+	stylesTitle := "Styles"
+	links := []models.Link{
+		{Href: &stylesConfig.BaseResource, Rel: models.SelfRelation, Title: &selfTitle},
+		{Href: models.StylesRelation.MustToUrl(stylesConfig.BaseResource, ""), Rel: models.StylesRelation, Title: &stylesTitle},
+	}
+	if stylesConfig.Conformance {
+		conformanceTitle := "Conformance declaration"
+		links = append(links, models.Link{
+			Href: models.ConformanceRelation.MustToUrl(stylesConfig.BaseResource, ""), Rel: models.ConformanceRelation, Title: &conformanceTitle,
+		})
+	}
+	if stylesConfig.ServiceDescription {
+		serviceDescTitle := "The API definition"
+		serviceDescType := models.OpenApiJsonMediaType
+		links = append(links, models.Link{
+			Href: models.ServiceDescRelation.MustToUrl(stylesConfig.BaseResource, ""), Rel: models.ServiceDescRelation,
+			Type: &serviceDescType, Title: &serviceDescTitle,
+		})
+	}
+	return links
+}
+
+// conformanceClasses returns the conformance classes satisfied by the rendered formats and the configured stylesheets
+func conformanceClasses(stylesConfig *models.StylesConfig, formats []models.Format) []models.ConformanceClass {
+	classes := []models.ConformanceClass{models.CoreConformance}
+	if stylesConfig.LandingPage != nil {
+		classes = append(classes, models.CommonCoreConformance, models.CommonLandingPageConformance)
+	}
+	if stylesConfig.ServiceDescription {
+		classes = append(classes, models.CommonOas30Conformance)
+	}
+	for _, format := range formats {
+		switch format.Name {
+		case models.JsonFormat.Name:
+			classes = append(classes, models.JsonConformance)
+		case models.HtmlFormat.Name:
+			classes = append(classes, models.HtmlConformance)
+		}
+	}
+	stylesheetClasses := make(map[models.ConformanceClass]bool)
 	for _, styleMetadata := range stylesConfig.StylesMetadata {
+		for _, stylesheet := range styleMetadata.Stylesheets {
+			if stylesheet.Link.Type == nil {
+				continue
+			}
+			root, params := stylesheet.Link.Type.SplitParams()
+			switch {
+			case root == models.MapboxMediaType:
+				stylesheetClasses[models.MapboxStylesConformance] = true
+			case root == models.SldMediaType && params["version"] == "1.1":
+				stylesheetClasses[models.Sld11Conformance] = true
+			case root == models.SldMediaType:
+				stylesheetClasses[models.Sld10Conformance] = true
+			}
+		}
+	}
+	for _, class := range []models.ConformanceClass{models.Sld10Conformance, models.Sld11Conformance, models.MapboxStylesConformance} {
+		if stylesheetClasses[class] {
+			classes = append(classes, class)
+		}
+	}
+	return classes
+}
+
+// generateStyles generates the stylesheets and metadata of the given styles plus the styles list containing them. When
+// pathPrefix is empty the styles are generated at the root of the API (/styles), otherwise they are generated under the
+// prefix (e.g. /collections/{collectionId}/styles) while the resources (thumbnails etc.) they refer to are shared.
+func generateStyles(stylesConfig *models.StylesConfig, stylesMetadata []models.StyleMetadata, defaultStyle string, pathPrefix string, assetDir string, formats []models.Format) ([]models.Document, error) {
+	var documents []models.Document
+	baseResource := stylesConfig.BaseResource
+	if pathPrefix != "" {
+		baseResource = fmt.Sprintf("%s/%s", stylesConfig.BaseResource, strings.TrimSuffix(pathPrefix, "/"))
+	}
+	styles := models.Styles{Default: defaultStyle}
+	for _, styleMetadata := range stylesMetadata {
+		styleMetadata = copyStyleMetadata(styleMetadata)
 		var stylesLinks []models.Link
 		var selfMetadataLink *models.Link
 		for i := range styleMetadata.Links {
-			document, link, isSelf, err := generateStyleMetadata(&styleMetadata.Links[i], styleMetadata.Id, assetDir, stylesConfig)
+			document, link, isSelf, err := generateStyleMetadata(&styleMetadata.Links[i], styleMetadata.Id, assetDir, stylesConfig, baseResource)
 			if err != nil {
 				return nil, err
 			}
-			documents = append(documents, *document)
-			stylesLinks = append(stylesLinks, *link)
+			if document != nil && pathPrefix == "" {
+				documents = append(documents, *document)
+			}
+			if link != nil {
+				stylesLinks = append(stylesLinks, *link)
+			}
 			if isSelf {
 				selfMetadataLink = link
 			}
 		}
 
+		if styleMetadata.Sprite != nil {
+			spriteDocuments, spriteLinks, err := generateSprites(stylesConfig, styleMetadata, assetDir)
+			if err != nil {
+				return nil, err
+			}
+			if pathPrefix == "" {
+				documents = append(documents, spriteDocuments...)
+			}
+			styleMetadata.Links = append(styleMetadata.Links, spriteLinks...)
+			stylesLinks = append(stylesLinks, spriteLinks...)
+		}
+
 		if selfMetadataLink == nil {
-			selfMetadataLink = generateMetadataLink(styleMetadata.Id, stylesConfig)
+			selfMetadataLink = generateMetadataLink(styleMetadata.Id, baseResource)
 			styleMetadata.Links = append(styleMetadata.Links, *selfMetadataLink)
 			// OGC API Styles Requirement 3F Each style SHALL have a link to the style metadata (link relation type: describedby) with the type attribute stating the media type of the metadata encoding.
 			stylesLinks = append(stylesLinks, *selfMetadataLink.WithOtherRelation(models.DescribedbyRelation))
 		}
+		var stylesheetDocuments []models.Document
 		for i := range styleMetadata.Stylesheets {
-			document, err := generateStylesheet(&styleMetadata.Stylesheets[i].Link, styleMetadata.Id, assetDir, stylesConfig)
+			document, err := generateStylesheet(&styleMetadata.Stylesheets[i], styleMetadata, assetDir, stylesConfig, baseResource)
 			if err != nil {
 				return nil, err
 			}
+			document.Path = pathPrefix + document.Path
 			documents = append(documents, *document)
+			stylesheetDocuments = append(stylesheetDocuments, *document)
 			// OGC API Styles Requirement 3C - The styles member SHALL include one item for each style currently on the server.
 			stylesLinks = append(stylesLinks, styleMetadata.Stylesheets[i].Link)
 		}
 
+		if styleMetadata.Glyphs != nil {
+			glyphsDocuments, glyphsLinks, err := generateGlyphs(stylesConfig, styleMetadata, stylesheetDocuments, assetDir)
+			if err != nil {
+				return nil, err
+			}
+			if pathPrefix == "" {
+				documents = append(documents, glyphsDocuments...)
+			}
+			styleMetadata.Links = append(styleMetadata.Links, glyphsLinks...)
+			stylesLinks = append(stylesLinks, glyphsLinks...)
+		}
+
+		sampleDocuments, err := generateSampleData(stylesConfig, &styleMetadata, assetDir)
+		if err != nil {
+			return nil, err
+		}
+		if pathPrefix == "" {
+			documents = append(documents, sampleDocuments...)
+		}
+
+		if styleMetadata.DeriveLayers {
+			err := deriveLayers(&styleMetadata, stylesheetDocuments)
+			if err != nil {
+				return nil, fmt.Errorf("could not derive the layers of style %s: %s", styleMetadata.Id, err)
+			}
+		}
+
 		styles.Styles = append(styles.Styles, models.Style{
 			Id: styleMetadata.Id, Title: *styleMetadata.Title, Links: stylesLinks,
 		})
 		for _, format := range formats {
-			document, err := Render(styleMetadata, models.DescribedbyRelation.MustToPath(styleMetadata.Id), format)
+			document, err := Render(styleMetadata, pathPrefix+models.DescribedbyRelation.MustToPath(styleMetadata.Id), format)
 			if err != nil {
 				return nil, err
 			}
@@ -92,7 +272,7 @@ func GenerateDocuments(stylesConfig *models.StylesConfig, assetDir string, forma
 		}
 	}
 	for _, format := range formats {
-		document, err := Render(styles, models.StylesResource, format)
+		document, err := Render(styles, pathPrefix+models.StylesResource, format)
 		if err != nil {
 			return nil, err
 		}
@@ -101,10 +281,36 @@ func GenerateDocuments(stylesConfig *models.StylesConfig, assetDir string, forma
 	return documents, nil
 }
 
-func generateStyleMetadata(styleMetadataLink *models.Link, metadataId string, assetDir string, styles *models.StylesConfig) (document *models.Document, link *models.Link, hasSelf bool, err error) {
-	err = styleMetadataLink.UpdateHref(styles.BaseResource, metadataId, styles.AdditionalFormats, false, true)
+// generateCollectionStyles generates the styles of a collection - OGC API Styles 8.2: /collections/{collectionId}/styles
+func generateCollectionStyles(stylesConfig *models.StylesConfig, collection models.Collection, assetDir string, formats []models.Format) ([]models.Document, error) {
+	var stylesMetadata []models.StyleMetadata
+	for _, styleId := range collection.Styles {
+		styleMetadata, ok := stylesConfig.GetStyleMetadata(styleId)
+		if !ok {
+			return nil, fmt.Errorf("style %s of collection %s not found in styles", styleId, collection.Id)
+		}
+		stylesMetadata = append(stylesMetadata, *styleMetadata)
+	}
+	pathPrefix := models.CollectionRelation.MustToPath(collection.Id) + "/"
+	return generateStyles(stylesConfig, stylesMetadata, collection.Default, pathPrefix, assetDir, formats)
+}
+
+// copyStyleMetadata copies the links and layers of the style metadata, so updating them does not change the config
+func copyStyleMetadata(styleMetadata models.StyleMetadata) models.StyleMetadata {
+	styleMetadata.Links = append([]models.Link(nil), styleMetadata.Links...)
+	styleMetadata.Stylesheets = append([]models.StyleSheet(nil), styleMetadata.Stylesheets...)
+	styleMetadata.Layers = append([]models.StyleLayer(nil), styleMetadata.Layers...)
+	return styleMetadata
+}
+
+func generateStyleMetadata(styleMetadataLink *models.Link, metadataId string, assetDir string, styles *models.StylesConfig, baseResource string) (document *models.Document, link *models.Link, hasSelf bool, err error) {
+	if styleMetadataLink.Rel != models.SelfRelation {
+		// resources are shared by all styles, regardless of the collection they are published in
+		baseResource = styles.BaseResource
+	}
+	err = styleMetadataLink.UpdateHref(baseResource, metadataId, styles.AdditionalFormats, false, true)
 	if err != nil {
-		return nil, nil, false, fmt.Errorf("error: %s could not update href with base url: %s and id: %s", err, styles.BaseResource, metadataId)
+		return nil, nil, false, fmt.Errorf("error: %s could not update href with base url: %s and id: %s", err, baseResource, metadataId)
 	}
 	if styleMetadataLink.Rel == models.StylesheetRelation {
 		log.Printf("warning: stylesheet link found in metadata links %s", *styleMetadataLink.Href)
@@ -114,6 +320,9 @@ func generateStyleMetadata(styleMetadataLink *models.Link, metadataId string, as
 		if err != nil {
 			return nil, nil, false, fmt.Errorf("error: %s could not update href with base url: %s and id: %s", err, styles.BaseResource, metadataId)
 		}
+		if document != nil {
+			describeContent(styleMetadataLink, document, styles.LinkChecksums)
+		}
 		// OGC API Styles Requirement 3I - If a thumbnail is available for a style in the style metadata (see recommendation /rec/core/style-md-preview), a link with the link relation type preview SHALL also be provided in the Styles resource.
 		link = styleMetadataLink
 	} else {
@@ -123,26 +332,80 @@ func generateStyleMetadata(styleMetadataLink *models.Link, metadataId string, as
 	return document, link, hasSelf, nil
 }
 
-func generateStylesheet(stylesheetLink *models.Link, metadataId string, assetDir string, styles *models.StylesConfig) (document *models.Document, err error) {
-	err = stylesheetLink.UpdateHref(styles.BaseResource, metadataId, styles.AdditionalFormats, true, false)
+func generateStylesheet(stylesheet *models.StyleSheet, styleMetadata models.StyleMetadata, assetDir string, styles *models.StylesConfig, baseResource string) (document *models.Document, err error) {
+	metadataId := styleMetadata.Id
+	err = stylesheet.Link.UpdateHref(baseResource, metadataId, styles.AdditionalFormats, true, false)
 	if err != nil {
-		return nil, fmt.Errorf("error: %s could not update href with base url: %s and id: %s", err, styles.BaseResource, metadataId)
+		return nil, fmt.Errorf("error: %s could not update href with base url: %s and id: %s", err, baseResource, metadataId)
 	}
-	document, err = generateAssetFromLinkRelation(*stylesheetLink, metadataId, assetDir, styles)
+	if stylesheet.GenerateFrom != nil {
+		document, err = generateConvertedStylesheet(*stylesheet, styleMetadata, assetDir, styles)
+		if err != nil {
+			return nil, err
+		}
+		return document, finishStylesheet(stylesheet, document, styleMetadata, styles)
+	}
+	document, err = generateAssetFromLinkRelation(stylesheet.Link, metadataId, assetDir, styles)
 	// OGC API Styles Requirement 3E - Each style SHALL have at least one link to a style encoding supported for the style (link relation type: stylesheet) with the type attribute stating the media type of the style encoding.
 	// OGC API Styles Requirement 3H - If a http://www.opengis.net/def/rel/ogc/1.0/schema link to a URI for the schema of the data is available for a style in the style metadata (see recommendation /rec/core/style-md-schema), a link with the same link relation type SHALL also be provided in the Styles resource.
 	if err != nil {
 		return nil, fmt.Errorf("error: %s could not update href with base url: %s and id: %s", err, styles.BaseResource, metadataId)
 	}
-	return document, nil
+	return document, finishStylesheet(stylesheet, document, styleMetadata, styles)
+}
+
+// finishStylesheet rewrites the urls of a Mapbox stylesheet for the environment, injects the generated sprite and glyphs
+// and describes the content in its link
+func finishStylesheet(stylesheet *models.StyleSheet, document *models.Document, styleMetadata models.StyleMetadata, styles *models.StylesConfig) error {
+	if root, _ := document.MediaType.SplitParams(); root == models.MapboxMediaType {
+		if environment, ok := styles.Environments[styles.Environment]; ok && styles.Environment != "" {
+			content, err := mapbox.RewriteUrls(document.Content.Bytes(), environment.RewriteUrl)
+			if err != nil {
+				return fmt.Errorf("could not rewrite the urls of stylesheet %s of style %s: %s", document.Path, styleMetadata.Id, err)
+			}
+			document.Content = content
+		}
+		var properties [][2]string
+		if styleMetadata.Sprite != nil {
+			properties = append(properties, [2]string{"sprite", spriteUrl(styles, styleMetadata.Id)})
+		}
+		if styleMetadata.Glyphs != nil {
+			properties = append(properties, [2]string{"glyphs", glyphsUrl(styles, styleMetadata.Id)})
+		}
+		for _, property := range properties {
+			content, err := mapbox.SetRootProperty(document.Content.Bytes(), property[0], property[1])
+			if err != nil {
+				return fmt.Errorf("could not set the %s of stylesheet %s of style %s: %s", property[0], document.Path, styleMetadata.Id, err)
+			}
+			document.Content = content
+		}
+	}
+	describeContent(&stylesheet.Link, document, styles.LinkChecksums)
+	return nil
+}
+
+// describeContent sets the length (and optionally the SHA-256 checksum) of the document the link refers to, so clients
+// can decide whether to download large documents and verify them
+func describeContent(link *models.Link, document *models.Document, withChecksum bool) {
+	length := document.Content.Len()
+	if link.Length != nil && *link.Length != length {
+		log.Printf("link length `%d` of %s does not match the document, overwriting with: `%d`", *link.Length, document.Path, length)
+	}
+	link.Length = &length
+	link.Sha256 = nil
+	if withChecksum {
+		checksum := sha256.Sum256(document.Content.Bytes())
+		hexChecksum := hex.EncodeToString(checksum[:])
+		link.Sha256 = &hexChecksum
+	}
 }
 
-func generateMetadataLink(metadataId string, styles *models.StylesConfig) *models.Link {
+func generateMetadataLink(metadataId string, baseResource string) *models.Link {
 	title := fmt.Sprintf("Style Metadata for %s", metadataId)
 	selfMetadataLink := models.Link{
 		Title: &title,
 		Rel:   models.SelfRelation,
-		Href:  models.DescribedbyRelation.MustToUrl(styles.BaseResource, metadataId),
+		Href:  models.DescribedbyRelation.MustToUrl(baseResource, metadataId),
 	}
 	return &selfMetadataLink
 }
@@ -185,5 +448,5 @@ func generateAssetFromSource(link models.Link, identifier string, assetDir strin
 	if err != nil {
 		return nil, err
 	}
-	return &models.Document{Path: path, MediaType: *link.Type, Content: &contentBuffer}, nil
+	return &models.Document{Path: path, MediaType: *link.Type, Content: &contentBuffer, Source: filename}, nil
 }
@@ -2,9 +2,12 @@ package pkg
 
 import (
 	"bytes"
+	"encoding/json"
+	"io/ioutil"
 	"strings"
 	"testing"
 
+	"github.com/pdok/goas/pkg/mapbox"
 	"github.com/pdok/goas/pkg/models"
 	"github.com/stretchr/testify/require"
 )
@@ -31,31 +34,109 @@ func TestGenerateDocuments(t *testing.T) {
 	config, _ := ParseConfig("../examples/config.yaml")
 	documents, err := GenerateDocuments(config, "../examples/assets", []models.Format{models.JsonFormat})
 	require.Nil(t, err)
+	thumbnail, err := ioutil.ReadFile("../examples/assets/thumbnail.png")
+	require.Nil(t, err)
 
 	expectedDocuments := []models.Document{
 		{
-			"resources/thumbnail.png",
-			"image/png",
-			bytes.NewBuffer([]byte(""))},
+			Path:      "resources/thumbnail.png",
+			MediaType: "image/png",
+			Content:   bytes.NewBuffer(thumbnail)},
 		{
-			"styles/night.mapbox.json",
-			"application/vnd.mapbox.style+json",
-			bytes.NewBuffer([]byte( //language=json
-				`{"MAPBOX_STYLE": "https://example.org/catalog/1.0"}`))},
+			Path:      "styles/night.mapbox.json",
+			MediaType: "application/vnd.mapbox.style+json",
+			Content: bytes.NewBuffer([]byte( //language=json
+				`{
+				  "version": 8,
+				  "name": "Topographic night style",
+				  "sources": {
+					"daraa": {
+					  "type": "vector",
+					  "tiles": [
+						"https://example.org/catalog/1.0/tiles/{z}/{x}/{y}.pbf"
+					  ]
+					}
+				  },
+				  "layers": [
+					{
+					  "id": "background",
+					  "type": "background",
+					  "paint": {
+						"background-color": "#1d1f20"
+					  }
+					},
+					{
+					  "id": "vegetationsrf",
+					  "type": "fill",
+					  "source": "daraa",
+					  "source-layer": "VegetationSrf",
+					  "paint": {
+						"fill-color": "#2e4a2c"
+					  }
+					},
+					{
+					  "id": "hydrographycrv",
+					  "type": "line",
+					  "source": "daraa",
+					  "source-layer": "hydrographycrv",
+					  "paint": {
+						"line-color": "#466ea0",
+						"line-width": 2
+					  }
+					}
+				  ]
+				}`))},
 		{
-			"styles/night.sld",
-			"application/vnd.ogc.sld+xml;version=1.0",
-			bytes.NewBuffer([]byte( //language=xml
-				`<root href="https://example.org/catalog/1.0">SLD</root>`))},
+			Path:      "styles/night.sld",
+			MediaType: "application/vnd.ogc.sld+xml;version=1.0",
+			Content: bytes.NewBuffer([]byte( //language=xml
+				`<?xml version="1.0" encoding="UTF-8"?>
+<StyledLayerDescriptor xmlns="http://www.opengis.net/sld" xmlns:ogc="http://www.opengis.net/ogc" version="1.0.0">
+  <Name>night</Name>
+  <NamedLayer>
+    <Name>VegetationSrf</Name>
+    <UserStyle>
+      <Title>Topographic night style</Title>
+      <FeatureTypeStyle>
+        <Rule>
+          <Name>vegetationsrf</Name>
+          <PolygonSymbolizer>
+            <Fill>
+              <CssParameter name="fill">#2e4a2c</CssParameter>
+            </Fill>
+          </PolygonSymbolizer>
+        </Rule>
+      </FeatureTypeStyle>
+    </UserStyle>
+  </NamedLayer>
+  <NamedLayer>
+    <Name>hydrographycrv</Name>
+    <UserStyle>
+      <Title>Topographic night style</Title>
+      <FeatureTypeStyle>
+        <Rule>
+          <Name>hydrographycrv</Name>
+          <LineSymbolizer>
+            <Stroke>
+              <CssParameter name="stroke">#466ea0</CssParameter>
+              <CssParameter name="stroke-width">2</CssParameter>
+            </Stroke>
+          </LineSymbolizer>
+        </Rule>
+      </FeatureTypeStyle>
+    </UserStyle>
+  </NamedLayer>
+</StyledLayerDescriptor>
+`))},
 		{
-			"styles/night.custom.json",
-			"application/vnd.custom.style+json",
-			bytes.NewBuffer([]byte( //language=text
-				`Custom Style = https://example.org/catalog/1.0`))},
+			Path:      "styles/night.custom.json",
+			MediaType: "application/vnd.custom.style+json",
+			Content: bytes.NewBuffer([]byte( //language=json
+				`{"customStyle": "https://example.org/catalog/1.0"}`))},
 		{
-			"styles/night/metadata.json",
-			"application/json",
-			bytes.NewBuffer([]byte( //language=json
+			Path:      "styles/night/metadata.json",
+			MediaType: "application/json",
+			Content: bytes.NewBuffer([]byte( //language=json
 				`{
 				  "id": "night",
 				  "title": "Topographic night style",
@@ -81,7 +162,8 @@ func TestGenerateDocuments(t *testing.T) {
 					  "link": {
 						"href": "https://example.org/catalog/1.0/styles/night?f=mapbox",
 						"rel": "stylesheet",
-						"type": "application/vnd.mapbox.style+json"
+						"type": "application/vnd.mapbox.style+json",
+						"length": 755
 					  }
 					},
 					{
@@ -91,7 +173,8 @@ func TestGenerateDocuments(t *testing.T) {
 					  "link": {
 						"href": "https://example.org/catalog/1.0/styles/night?f=sld10",
 						"rel": "stylesheet",
-						"type": "application/vnd.ogc.sld+xml;version=1.0"
+						"type": "application/vnd.ogc.sld+xml;version=1.0",
+						"length": 1110
 					  }
 					},
 					{
@@ -100,7 +183,8 @@ func TestGenerateDocuments(t *testing.T) {
 					  "link": {
 						"href": "https://example.org/catalog/1.0/styles/night?f=custom",
 						"rel": "stylesheet",
-						"type": "application/vnd.custom.style+json"
+						"type": "application/vnd.custom.style+json",
+						"length": 55
 					  }
 					}
 				  ],
@@ -121,6 +205,28 @@ func TestGenerateDocuments(t *testing.T) {
 						"href": "https://services.interactive-instruments.de/vtp/daraa/collections/hydrographycrv/items?f=json&limit=100",
 						"rel": "start",
 						"type": "application/geo+json"
+					  },
+					  "propertiesSchema": {
+						"type": "object",
+						"properties": {
+						  "F_CODE": {
+							"description": "feature type code",
+							"type": "string",
+							"enum": ["BH140", "BH020"]
+						  },
+						  "WID": {
+							"description": "width in meters",
+							"type": "number",
+							"format": "double",
+							"minimum": 0
+						  },
+						  "ZI005_FNA": {
+							"description": "name",
+							"type": "string",
+							"nullable": true
+						  }
+						},
+						"required": ["F_CODE"]
 					  }
 					}
 				  ],
@@ -129,7 +235,8 @@ func TestGenerateDocuments(t *testing.T) {
 					  "href": "https://example.org/catalog/1.0/resources/night.png",
 					  "rel": "preview",
 					  "type": "image/png",
-					  "title": "thumbnail of the night style applied to OSM data from Daraa, Syria"
+					  "title": "thumbnail of the night style applied to OSM data from Daraa, Syria",
+					  "length": 288
 					},
 					{
 					  "href": "https://example.org/catalog/1.0/styles/night/metadata",
@@ -140,9 +247,9 @@ func TestGenerateDocuments(t *testing.T) {
 				}`),
 			)},
 		{
-			"styles.json",
-			"application/json",
-			bytes.NewBuffer([]byte( //language=json
+			Path:      "styles.json",
+			MediaType: "application/json",
+			Content: bytes.NewBuffer([]byte( //language=json
 				`{
 				  "default": "night",
 				  "styles": [
@@ -154,7 +261,8 @@ func TestGenerateDocuments(t *testing.T) {
 						  "href": "https://example.org/catalog/1.0/resources/night.png",
 						  "rel": "preview",
 						  "type": "image/png",
-						  "title": "thumbnail of the night style applied to OSM data from Daraa, Syria"
+						  "title": "thumbnail of the night style applied to OSM data from Daraa, Syria",
+						  "length": 288
 						},
 						{
 						  "href": "https://example.org/catalog/1.0/styles/night/metadata",
@@ -164,17 +272,20 @@ func TestGenerateDocuments(t *testing.T) {
 						{
 						  "href": "https://example.org/catalog/1.0/styles/night?f=mapbox",
 						  "rel": "stylesheet",
-						  "type": "application/vnd.mapbox.style+json"
+						  "type": "application/vnd.mapbox.style+json",
+						  "length": 755
 						},
 						{
 						  "href": "https://example.org/catalog/1.0/styles/night?f=sld10",
 						  "rel": "stylesheet",
-						  "type": "application/vnd.ogc.sld+xml;version=1.0"
+						  "type": "application/vnd.ogc.sld+xml;version=1.0",
+						  "length": 1110
 						},
 						{
 						  "href": "https://example.org/catalog/1.0/styles/night?f=custom",
 						  "rel": "stylesheet",
-						  "type": "application/vnd.custom.style+json"
+						  "type": "application/vnd.custom.style+json",
+						  "length": 55
 						}
 					  ]
 					}
@@ -195,9 +306,9 @@ func TestGenerateDocumentsMinimalConfig(t *testing.T) {
 
 	expectedDocuments := []models.Document{
 		{
-			"styles/night/metadata.json",
-			"application/json",
-			bytes.NewBuffer([]byte( //language=json
+			Path:      "styles/night/metadata.json",
+			MediaType: "application/json",
+			Content: bytes.NewBuffer([]byte( //language=json
 				`{
 					"id": "night",
 					"title": "Topographic night style",
@@ -210,9 +321,9 @@ func TestGenerateDocumentsMinimalConfig(t *testing.T) {
 					]
 				}`))},
 		{
-			"styles.json",
-			"application/json",
-			bytes.NewBuffer([]byte( //language=json
+			Path:      "styles.json",
+			MediaType: "application/json",
+			Content: bytes.NewBuffer([]byte( //language=json
 				`{
  				  "styles": [
   					{
@@ -235,3 +346,213 @@ func TestGenerateDocumentsMinimalConfig(t *testing.T) {
 		require.Equal(t, bytesToComparableString(expectedDocument.Content), bytesToComparableString(documents[i].Content))
 	}
 }
+
+func TestGenerateCoreDocuments(t *testing.T) {
+	config, _ := ParseConfig("../examples/config.yaml")
+	documents, err := generateCoreDocuments(config, []models.Format{models.JsonFormat})
+	require.Nil(t, err)
+
+	expectedDocuments := []models.Document{
+		{
+			Path:      "conformance.json",
+			MediaType: "application/json",
+			Content: bytes.NewBuffer([]byte( //language=json
+				`{
+				  "conformsTo": [
+					"http://www.opengis.net/spec/ogcapi-styles-1/1.0/conf/core",
+					"http://www.opengis.net/spec/ogcapi-common-1/1.0/conf/core",
+					"http://www.opengis.net/spec/ogcapi-common-1/1.0/conf/landing-page",
+					"http://www.opengis.net/spec/ogcapi-common-1/1.0/conf/oas30",
+					"http://www.opengis.net/spec/ogcapi-styles-1/1.0/conf/json",
+					"http://www.opengis.net/spec/ogcapi-styles-1/1.0/conf/sld-10",
+					"http://www.opengis.net/spec/ogcapi-styles-1/1.0/conf/mapbox-styles"
+				  ]
+				}`))},
+		{
+			Path:      "index.json",
+			MediaType: "application/json",
+			Content: bytes.NewBuffer([]byte( //language=json
+				`{
+				  "title": "Example styles",
+				  "description": "Styles for the Daraa, Syria OSM dataset",
+				  "links": [
+					{
+					  "href": "https://example.org/catalog/1.0",
+					  "rel": "self",
+					  "title": "This document"
+					},
+					{
+					  "href": "https://example.org/catalog/1.0/styles",
+					  "rel": "http://www.opengis.net/def/rel/ogc/1.0/styles",
+					  "title": "Styles"
+					},
+					{
+					  "href": "https://example.org/catalog/1.0/conformance",
+					  "rel": "http://www.opengis.net/def/rel/ogc/1.0/conformance",
+					  "title": "Conformance declaration"
+					},
+					{
+					  "href": "https://example.org/catalog/1.0/api",
+					  "rel": "service-desc",
+					  "type": "application/vnd.oai.openapi+json;version=3.0",
+					  "title": "The API definition"
+					},
+					{
+					  "href": "https://example.org/catalog/1.0/docs",
+					  "rel": "service-doc",
+					  "type": "text/html",
+					  "title": "Documentation of the styles"
+					}
+				  ]
+				}`))},
+	}
+	require.Len(t, documents, len(expectedDocuments))
+	for i, expectedDocument := range expectedDocuments {
+		require.Equal(t, expectedDocument.Path, documents[i].Path)
+		require.Equal(t, expectedDocument.MediaType, documents[i].MediaType)
+		require.Equal(t, bytesToComparableString(expectedDocument.Content), bytesToComparableString(documents[i].Content))
+	}
+}
+
+func TestGenerateCoreDocumentsMinimalConfig(t *testing.T) {
+	config, _ := ParseConfig("../examples/minimal_config.yaml")
+	documents, err := generateCoreDocuments(config, []models.Format{models.JsonFormat})
+	require.Nil(t, err)
+	require.Empty(t, documents)
+}
+
+func TestLandingPageLinksWithoutServiceDescription(t *testing.T) {
+	config, _ := ParseConfig("../examples/config.yaml")
+	config.ServiceDescription = false
+	var rels []models.LinkRelation
+	for _, link := range landingPageLinks(config) {
+		rels = append(rels, link.Rel)
+	}
+	require.Equal(t, []models.LinkRelation{models.SelfRelation, models.StylesRelation, models.ConformanceRelation}, rels,
+		"the service-desc link is only added when the service description is generated")
+}
+
+func TestGenerateCollectionStyles(t *testing.T) {
+	config, _ := ParseConfig("../examples/config.yaml")
+	documents, err := generateCollectionStyles(config, config.Collections[0], "../examples/assets", []models.Format{models.JsonFormat})
+	require.Nil(t, err)
+
+	var paths []string
+	for _, document := range documents {
+		paths = append(paths, document.Path)
+	}
+	require.Equal(t, []string{
+		"collections/daraa/styles/night.mapbox.json",
+		"collections/daraa/styles/night.sld",
+		"collections/daraa/styles/night.custom.json",
+		"collections/daraa/styles/night/metadata.json",
+		"collections/daraa/styles.json",
+	}, paths)
+	require.Equal(t, bytesToComparableString(bytes.NewBuffer([]byte( //language=json
+		`{
+		  "default": "night",
+		  "styles": [
+			{
+			  "id": "night",
+			  "title": "Topographic night style",
+			  "links": [
+				{
+				  "href": "https://example.org/catalog/1.0/resources/night.png",
+				  "rel": "preview",
+				  "type": "image/png",
+				  "title": "thumbnail of the night style applied to OSM data from Daraa, Syria",
+				  "length": 288
+				},
+				{
+				  "href": "https://example.org/catalog/1.0/collections/daraa/styles/night/metadata",
+				  "rel": "describedby",
+				  "title": "Style Metadata for night"
+				},
+				{
+				  "href": "https://example.org/catalog/1.0/collections/daraa/styles/night?f=mapbox",
+				  "rel": "stylesheet",
+				  "type": "application/vnd.mapbox.style+json",
+				  "length": 755
+				},
+				{
+				  "href": "https://example.org/catalog/1.0/collections/daraa/styles/night?f=sld10",
+				  "rel": "stylesheet",
+				  "type": "application/vnd.ogc.sld+xml;version=1.0",
+				  "length": 1110
+				},
+				{
+				  "href": "https://example.org/catalog/1.0/collections/daraa/styles/night?f=custom",
+				  "rel": "stylesheet",
+				  "type": "application/vnd.custom.style+json",
+				  "length": 55
+				}
+			  ]
+			}
+		  ]
+		}`))), bytesToComparableString(documents[4].Content))
+	// the global style metadata is not changed by publishing it in a collection
+	require.Equal(t, "https://example.org/catalog/1.0/styles/night?f=sld10", *config.StylesMetadata[0].Stylesheets[1].Link.Href)
+}
+
+func TestGenerateDocumentsGeneratedStylesheet(t *testing.T) {
+	config, _ := ParseConfig("../examples/generate_config.yaml")
+	documents, err := GenerateDocuments(config, "../examples/assets", []models.Format{models.JsonFormat})
+	require.Nil(t, err)
+	require.Len(t, documents, 12)
+	require.Equal(t, "styles/daraa.sld", documents[5].Path)
+	require.Equal(t, models.MediaType("application/vnd.ogc.sld+xml;version=1.1"), documents[5].MediaType)
+	require.Equal(t, "styles/daraa-legacy.mapbox.json", documents[8].Path)
+	require.Equal(t, models.MediaType("application/vnd.mapbox.style+json"), documents[8].MediaType)
+	require.Equal(t, "resources/daraa-legacy/sample/daraa.gpkg", documents[9].Path, "the sample data of both layers")
+
+	content := documents[5].Content.String()
+	require.Contains(t, content, `version="1.1.0"`)
+	require.Contains(t, content, "<se:Name>VegetationSrf</se:Name>")
+	require.Contains(t, content, `<se:SvgParameter name="fill">#2e4a2c</se:SvgParameter>`)
+	require.Contains(t, content, "<se:MaxScaleDenominator>1091957.547</se:MaxScaleDenominator>")
+	require.Contains(t, content, "<ogc:PropertyName>F_CODE</ogc:PropertyName>")
+	require.NotContains(t, content, "background")
+
+	var style map[string]interface{}
+	require.Nil(t, json.Unmarshal(documents[8].Content.Bytes(), &style))
+	require.Equal(t, "Daraa legacy style", style["name"])
+	layers := style["layers"].([]interface{})
+	require.Len(t, layers, 6)
+	require.Equal(t, "settlementpnt", layers[5].(map[string]interface{})["source-layer"])
+}
+
+func TestGenerateDocumentsEnvironment(t *testing.T) {
+	config, _ := ParseConfig("../examples/generate_config.yaml")
+	config.Environment = "acc"
+	documents, err := GenerateDocuments(config, "../examples/assets", []models.Format{models.JsonFormat})
+	require.Nil(t, err)
+
+	var style, legacyStyle mapbox.Style
+	require.Nil(t, json.Unmarshal(documents[4].Content.Bytes(), &style))
+	require.Nil(t, json.Unmarshal(documents[8].Content.Bytes(), &legacyStyle))
+	require.Equal(t, []string{"https://tiles.acc.example.org/{z}/{x}/{y}.pbf"}, style.Sources["daraa"].Tiles)
+	require.Equal(t, "https://fonts.acc.example.org/{fontstack}/{range}.pbf", style.Glyphs)
+	require.Equal(t, "https://example.org/catalog/1.0/resources/daraa/sprite", style.Sprite, "the generated sprite is not rewritten")
+	require.Equal(t, []string{"https://tiles.acc.example.org/{z}/{x}/{y}.pbf"}, legacyStyle.Sources["daraa"].Tiles)
+	require.Equal(t, "https://fonts.acc.example.org/{fontstack}/{range}.pbf", legacyStyle.Glyphs)
+}
+
+func TestGenerateDocumentsLinkChecksums(t *testing.T) {
+	config, _ := ParseConfig("../examples/config.yaml")
+	config.LinkChecksums = true
+	documents, err := GenerateDocuments(config, "../examples/assets", []models.Format{models.JsonFormat})
+	require.Nil(t, err)
+
+	var styles models.Styles
+	for _, document := range documents {
+		if document.Path == "styles.json" {
+			require.Nil(t, json.Unmarshal(document.Content.Bytes(), &styles))
+		}
+	}
+	preview := styles.Styles[0].Links[0]
+	require.Equal(t, models.PreviewRelation, preview.Rel)
+	require.Equal(t, 288, *preview.Length)
+	require.Equal(t, "5325b2550e345332a23664fda03eb9eb68be528f0bbba76e4d3a992c584979d0", *preview.Sha256)
+	require.Nil(t, styles.Styles[0].Links[1].Sha256, "the metadata link refers to a rendered document")
+	require.NotNil(t, styles.Styles[0].Links[2].Sha256)
+}
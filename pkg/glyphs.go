@@ -0,0 +1,383 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"log"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/pdok/goas/pkg/mapbox"
+	"github.com/pdok/goas/pkg/models"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// The glyphs are signed distance fields as Mapbox GL expects them (like node-fontnik generates them): rendered at 24
+// pixels with a 3 pixel buffer, where 192 is the edge of the glyph and the distance spans 8 pixels
+const (
+	glyphSize      = 24
+	glyphBuffer    = 3
+	glyphRadius    = 8
+	glyphCutoff    = 0.25
+	glyphRangeSize = 256
+	glyphRanges    = 256 // up to 65280-65535
+
+	glyphInfinity = 1e20
+)
+
+// glyph a glyph of a font range - https://github.com/mapbox/glyph-pbf-composite/blob/master/proto/glyphs.proto
+type glyph struct {
+	id      rune
+	bitmap  []byte
+	width   int
+	height  int
+	left    int
+	top     int
+	advance int
+}
+
+type glyphFont struct {
+	name          string
+	assetFilename string
+	font          *sfnt.Font
+	ascent        int
+}
+
+// glyphsUrl the url template of the glyphs of a style, as the Mapbox glyphs property expects
+func glyphsUrl(stylesConfig *models.StylesConfig, styleId string) string {
+	return *models.PreloadRelation.MustToUrl(stylesConfig.BaseResource, styleId+"/fonts/{fontstack}/{range}.pbf")
+}
+
+// generateGlyphs renders the glyph ranges of every font of the style, and of every font stack of multiple fonts the
+// text-font of the layers of the Mapbox stylesheets refers to, which uses the first font that has a glyph. All 256
+// ranges are published under resources/{styleId}/fonts/{fontstack}/, the ranges with glyphs are linked as preload
+// resources.
+func generateGlyphs(stylesConfig *models.StylesConfig, styleMetadata models.StyleMetadata, stylesheets []models.Document, assetDir string) ([]models.Document, []models.Link, error) {
+	fonts := make(map[string]glyphFont)
+	var fontstacks [][]string
+	for _, fontOptions := range styleMetadata.Glyphs.Fonts {
+		loaded, err := readGlyphFont(filepath.Join(assetDir, fontOptions.AssetFilename), fontOptions.Name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not generate the glyphs of style %s: %s", styleMetadata.Id, err)
+		}
+		loaded.assetFilename = fontOptions.AssetFilename
+		if _, ok := fonts[loaded.name]; ok {
+			return nil, nil, fmt.Errorf("could not generate the glyphs of style %s: font %s is configured twice", styleMetadata.Id, loaded.name)
+		}
+		fonts[loaded.name] = loaded
+		fontstacks = append(fontstacks, []string{loaded.name})
+	}
+	for _, fontstack := range textFonts(stylesheets) {
+		missing := false
+		for _, name := range fontstack {
+			if _, ok := fonts[name]; !ok {
+				log.Printf("warning: font %s of text-font %s of style %s is not in its glyphs", name, strings.Join(fontstack, ","), styleMetadata.Id)
+				missing = true
+			}
+		}
+		if len(fontstack) > 1 && !missing {
+			fontstacks = append(fontstacks, fontstack)
+		}
+	}
+
+	var documents []models.Document
+	var links []models.Link
+	for _, fontstack := range fontstacks {
+		var stack []glyphFont
+		for _, name := range fontstack {
+			stack = append(stack, fonts[name])
+		}
+		fontstackName := strings.Join(fontstack, ",")
+		for i := 0; i < glyphRanges; i++ {
+			start := rune(i * glyphRangeSize)
+			glyphs, err := renderGlyphRange(stack, start)
+			if err != nil {
+				return nil, nil, fmt.Errorf("could not generate the glyphs of font %s of style %s: %s", fontstackName, styleMetadata.Id, err)
+			}
+			rangeName := fmt.Sprintf("%d-%d", start, start+glyphRangeSize-1)
+			identifier := fmt.Sprintf("%s/fonts/%s/%s.pbf", styleMetadata.Id, fontstackName, rangeName)
+			document := models.Document{
+				Path:      models.PreloadRelation.MustToPath(identifier),
+				MediaType: models.ProtobufMediaType,
+				Content:   bytes.NewBuffer(encodeGlyphRange(fontstackName, rangeName, glyphs)),
+				Source:    stack[0].assetFilename,
+			}
+			documents = append(documents, document)
+			if len(glyphs) == 0 {
+				continue
+			}
+			mediaType := models.ProtobufMediaType
+			title := fmt.Sprintf("glyphs %s of font %s", rangeName, fontstackName)
+			link := models.Link{
+				Href:  models.PreloadRelation.MustToUrl(stylesConfig.BaseResource, identifier),
+				Rel:   models.PreloadRelation,
+				Type:  &mediaType,
+				Title: &title,
+			}
+			describeContent(&link, &document, stylesConfig.LinkChecksums)
+			links = append(links, link)
+		}
+	}
+	return documents, links, nil
+}
+
+func readGlyphFont(fontPath string, name *string) (glyphFont, error) {
+	content, err := ioutil.ReadFile(fontPath)
+	if err != nil {
+		return glyphFont{}, fmt.Errorf("could not find font %s", fontPath)
+	}
+	parsed, err := sfnt.Parse(content)
+	if err != nil {
+		return glyphFont{}, fmt.Errorf("font %s is not a TTF or OTF font: %s", fontPath, err)
+	}
+	var buffer sfnt.Buffer
+	result := glyphFont{font: parsed}
+	if name != nil {
+		result.name = *name
+	} else {
+		result.name, err = parsed.Name(&buffer, sfnt.NameIDFull)
+		if err != nil {
+			return result, fmt.Errorf("font %s has no full name, configure a name: %s", fontPath, err)
+		}
+	}
+	metrics, err := parsed.Metrics(&buffer, fixed.I(glyphSize), font.HintingNone)
+	if err != nil {
+		return result, fmt.Errorf("font %s has no metrics: %s", fontPath, err)
+	}
+	result.ascent = metrics.Ascent.Round()
+	return result, nil
+}
+
+// textFonts the distinct literal text-font values of the layers of the Mapbox stylesheets
+func textFonts(stylesheets []models.Document) [][]string {
+	var fontstacks [][]string
+	seen := make(map[string]bool)
+	for _, stylesheet := range stylesheets {
+		style, err := mapbox.Parse(stylesheet.Content.Bytes())
+		if err != nil {
+			continue
+		}
+		for _, layer := range style.Layers {
+			values, ok := layer.Layout["text-font"].([]interface{})
+			if !ok {
+				continue
+			}
+			var fontstack []string
+			for _, value := range values {
+				if name, ok := value.(string); ok {
+					fontstack = append(fontstack, name)
+				}
+			}
+			key := strings.Join(fontstack, ",")
+			if len(fontstack) == len(values) && !seen[key] {
+				seen[key] = true
+				fontstacks = append(fontstacks, fontstack)
+			}
+		}
+	}
+	return fontstacks
+}
+
+func renderGlyphRange(stack []glyphFont, start rune) ([]glyph, error) {
+	var buffer sfnt.Buffer
+	var glyphs []glyph
+	for id := start; id < start+glyphRangeSize; id++ {
+		for _, stackFont := range stack {
+			index, err := stackFont.font.GlyphIndex(&buffer, id)
+			if err != nil {
+				return nil, err
+			}
+			if index == 0 {
+				continue
+			}
+			rendered, err := renderGlyph(stackFont, &buffer, index)
+			if err != nil {
+				return nil, fmt.Errorf("glyph %d: %s", id, err)
+			}
+			rendered.id = id
+			glyphs = append(glyphs, rendered)
+			break
+		}
+	}
+	return glyphs, nil
+}
+
+func renderGlyph(source glyphFont, buffer *sfnt.Buffer, index sfnt.GlyphIndex) (glyph, error) {
+	ppem := fixed.I(glyphSize)
+	advance, err := source.font.GlyphAdvance(buffer, index, ppem, font.HintingNone)
+	if err != nil {
+		return glyph{}, err
+	}
+	segments, err := source.font.LoadGlyph(buffer, index, ppem, nil)
+	if err != nil {
+		return glyph{}, err
+	}
+	rendered := glyph{advance: advance.Round()}
+	bounds := segments.Bounds()
+	if len(segments) == 0 || bounds.Empty() {
+		return rendered, nil
+	}
+	// the segments are in pixels with the y-axis pointing down, from the origin of the glyph on the baseline
+	minX, minY := bounds.Min.X.Floor(), bounds.Min.Y.Floor()
+	rendered.width = bounds.Max.X.Ceil() - minX
+	rendered.height = bounds.Max.Y.Ceil() - minY
+	rendered.left = minX
+	rendered.top = -minY - source.ascent
+
+	width, height := rendered.width+2*glyphBuffer, rendered.height+2*glyphBuffer
+	point := func(p fixed.Point26_6) (float32, float32) {
+		return float32(p.X)/64 - float32(minX-glyphBuffer), float32(p.Y)/64 - float32(minY-glyphBuffer)
+	}
+	rasterizer := vector.NewRasterizer(width, height)
+	for i, segment := range segments {
+		switch segment.Op {
+		case sfnt.SegmentOpMoveTo:
+			if i > 0 {
+				rasterizer.ClosePath()
+			}
+			rasterizer.MoveTo(point(segment.Args[0]))
+		case sfnt.SegmentOpLineTo:
+			rasterizer.LineTo(point(segment.Args[0]))
+		case sfnt.SegmentOpQuadTo:
+			bx, by := point(segment.Args[0])
+			cx, cy := point(segment.Args[1])
+			rasterizer.QuadTo(bx, by, cx, cy)
+		case sfnt.SegmentOpCubeTo:
+			bx, by := point(segment.Args[0])
+			cx, cy := point(segment.Args[1])
+			dx, dy := point(segment.Args[2])
+			rasterizer.CubeTo(bx, by, cx, cy, dx, dy)
+		}
+	}
+	rasterizer.ClosePath()
+	alpha := image.NewAlpha(image.Rect(0, 0, width, height))
+	rasterizer.Draw(alpha, alpha.Bounds(), image.Opaque, image.Point{})
+	rendered.bitmap = signedDistanceField(alpha.Pix, width, height)
+	return rendered, nil
+}
+
+// signedDistanceField converts the coverage of the pixels into their distance to the edge of the glyph, with the
+// Euclidean distance transform of TinySDF (https://github.com/mapbox/tiny-sdf), which uses the coverage of the pixels
+// on the edge for sub pixel precision
+func signedDistanceField(coverage []byte, width int, height int) []byte {
+	size := width * height
+	outer := make([]float64, size)
+	inner := make([]float64, size)
+	for i, value := range coverage {
+		a := float64(value) / 255
+		switch {
+		case a == 0:
+			outer[i], inner[i] = glyphInfinity, 0
+		case a == 1:
+			outer[i], inner[i] = 0, glyphInfinity
+		default:
+			d := 0.5 - a
+			outer[i], inner[i] = math.Max(d, 0)*math.Max(d, 0), math.Min(d, 0)*math.Min(d, 0)
+		}
+	}
+	distanceTransform(outer, width, height)
+	distanceTransform(inner, width, height)
+
+	field := make([]byte, size)
+	for i := range field {
+		d := math.Sqrt(outer[i]) - math.Sqrt(inner[i])
+		field[i] = byte(math.Max(0, math.Min(255, math.Round(255-255*(d/glyphRadius+glyphCutoff)))))
+	}
+	return field
+}
+
+// distanceTransform the squared Euclidean distance transform of Felzenszwalb and Huttenlocher, in place
+func distanceTransform(grid []float64, width int, height int) {
+	length := width
+	if height > length {
+		length = height
+	}
+	f := make([]float64, length)
+	v := make([]int, length)
+	z := make([]float64, length+1)
+	for x := 0; x < width; x++ {
+		distanceTransform1d(grid, x, width, height, f, v, z)
+	}
+	for y := 0; y < height; y++ {
+		distanceTransform1d(grid, y*width, 1, width, f, v, z)
+	}
+}
+
+func distanceTransform1d(grid []float64, offset int, stride int, length int, f []float64, v []int, z []float64) {
+	v[0] = 0
+	z[0] = -glyphInfinity
+	z[1] = glyphInfinity
+	f[0] = grid[offset]
+	k := 0
+	for q := 1; q < length; q++ {
+		f[q] = grid[offset+q*stride]
+		var s float64
+		for {
+			r := v[k]
+			s = (f[q] - f[r] + float64(q*q-r*r)) / float64(q-r) / 2
+			if s > z[k] {
+				break
+			}
+			k--
+			if k < 0 {
+				break
+			}
+		}
+		k++
+		v[k] = q
+		z[k] = s
+		z[k+1] = glyphInfinity
+	}
+	k = 0
+	for q := 0; q < length; q++ {
+		for z[k+1] < float64(q) {
+			k++
+		}
+		r := v[k]
+		grid[offset+q*stride] = f[r] + float64((q-r)*(q-r))
+	}
+}
+
+// encodeGlyphRange encodes the glyphs as a glyphs protocol buffer message with a single fontstack
+func encodeGlyphRange(fontstack string, rangeName string, glyphs []glyph) []byte {
+	var stack []byte
+	stack = appendBytesField(stack, 1, []byte(fontstack))
+	stack = appendBytesField(stack, 2, []byte(rangeName))
+	for _, glyph := range glyphs {
+		var message []byte
+		message = appendVarintField(message, 1, uint64(glyph.id))
+		if len(glyph.bitmap) > 0 {
+			message = appendBytesField(message, 2, glyph.bitmap)
+		}
+		message = appendVarintField(message, 3, uint64(glyph.width))
+		message = appendVarintField(message, 4, uint64(glyph.height))
+		message = appendVarintField(message, 5, zigzag(glyph.left))
+		message = appendVarintField(message, 6, zigzag(glyph.top))
+		message = appendVarintField(message, 7, uint64(glyph.advance))
+		stack = appendBytesField(stack, 3, message)
+	}
+	return appendBytesField(nil, 1, stack)
+}
+
+func appendVarintField(message []byte, field int, value uint64) []byte {
+	message = binary.AppendUvarint(message, uint64(field<<3))
+	return binary.AppendUvarint(message, value)
+}
+
+func appendBytesField(message []byte, field int, value []byte) []byte {
+	message = binary.AppendUvarint(message, uint64(field<<3|2))
+	message = binary.AppendUvarint(message, uint64(len(value)))
+	return append(message, value...)
+}
+
+// zigzag encodes a sint32
+func zigzag(value int) uint64 {
+	return uint64(uint32((int32(value) << 1) ^ (int32(value) >> 31)))
+}
@@ -0,0 +1,154 @@
+package pkg
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pdok/goas/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+const glyphsConfig = `base-resource: https://example.org/catalog/1.0/
+styles:
+  - id: "night"
+    title: "Night"
+    glyphs:
+      fonts:
+        - asset-filename: "fonts/Go-Regular.ttf"
+        - asset-filename: "fonts/Go-Bold.ttf"
+          name: "Go Heavy"
+    stylesheets:
+    - link:
+        asset-filename: "night.json"
+        href: "https://example.org/catalog/1.0/styles/night?f=mapbox"
+        rel: "stylesheet"
+        type: "application/vnd.mapbox.style+json"
+`
+
+const glyphsStylesheet = `{
+  "version": 8,
+  "sources": {},
+  "sprite": [{"id": "default", "url": "https://example.org/sprites/night"}],
+  "glyphs": "https://fonts.openmaptiles.org/{fontstack}/{range}.pbf",
+  "layers": [
+    {"id": "labels", "type": "symbol", "layout": {"text-field": "{name}", "text-font": ["Go Heavy", "Go Regular"]}},
+    {"id": "other", "type": "symbol", "layout": {"text-field": "{name}", "text-font": ["Open Sans Bold"]}}
+  ]
+}`
+
+func TestGenerateGlyphs(t *testing.T) {
+	assetDir := t.TempDir()
+	require.Nil(t, os.MkdirAll(filepath.Join(assetDir, "fonts"), os.ModePerm))
+	require.Nil(t, os.WriteFile(filepath.Join(assetDir, "fonts", "Go-Regular.ttf"), goregular.TTF, 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(assetDir, "fonts", "Go-Bold.ttf"), gobold.TTF, 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(assetDir, "night.json"), []byte(glyphsStylesheet), 0644))
+	configPath := filepath.Join(assetDir, "config.yaml")
+	require.Nil(t, os.WriteFile(configPath, []byte(glyphsConfig), 0644))
+	config, err := ParseConfig(configPath)
+	require.Nil(t, err)
+
+	documents, err := GenerateDocuments(config, assetDir, []models.Format{models.JsonFormat})
+	require.Nil(t, err)
+	glyphs := map[string]models.Document{}
+	for _, document := range documents {
+		glyphs[document.Path] = document
+	}
+	assert.Len(t, documents, 3*glyphRanges+3, "the ranges of both fonts and the stack of both, the stylesheet, metadata and styles")
+
+	var style map[string]interface{}
+	require.Nil(t, json.Unmarshal(glyphs["styles/night.mapbox.json"].Content.Bytes(), &style))
+	assert.Equal(t, "https://example.org/catalog/1.0/resources/night/fonts/{fontstack}/{range}.pbf", style["glyphs"])
+
+	name, rangeName, decoded := decodeGlyphRange(t, glyphs["resources/night/fonts/Go Regular/0-255.pbf"].Content.Bytes())
+	assert.Equal(t, "Go Regular", name)
+	assert.Equal(t, "0-255", rangeName)
+	a, ok := decoded[65]
+	require.True(t, ok)
+	assert.Len(t, a.bitmap, (a.width+2*glyphBuffer)*(a.height+2*glyphBuffer))
+	assert.True(t, a.height > 12 && a.height < 24, "an A is about the cap height of 24 pixels")
+	assert.Equal(t, byte(0), a.bitmap[0], "the corner is far outside the glyph")
+	maximum := byte(0)
+	for _, value := range a.bitmap {
+		if value > maximum {
+			maximum = value
+		}
+	}
+	assert.True(t, maximum > 192, "the glyph has pixels inside the edge")
+	space := decoded[32]
+	assert.Equal(t, 0, space.width)
+	assert.True(t, space.advance > 0)
+
+	_, _, empty := decodeGlyphRange(t, glyphs["resources/night/fonts/Go Regular/55296-55551.pbf"].Content.Bytes())
+	assert.Len(t, empty, 0)
+	name, _, stacked := decodeGlyphRange(t, glyphs["resources/night/fonts/Go Heavy,Go Regular/0-255.pbf"].Content.Bytes())
+	assert.Equal(t, "Go Heavy,Go Regular", name, "the text-font of a stylesheet with a MapLibre array sprite")
+	assert.Len(t, stacked, len(decoded))
+
+	var metadata models.StyleMetadata
+	require.Nil(t, json.Unmarshal(glyphs["styles/night/metadata.json"].Content.Bytes(), &metadata))
+	assert.Equal(t, "https://example.org/catalog/1.0/resources/night/fonts/Go Regular/0-255.pbf", *metadata.Links[1].Href)
+	assert.Equal(t, models.ProtobufMediaType, *metadata.Links[1].Type)
+}
+
+func TestReadGlyphFontInvalid(t *testing.T) {
+	fontPath := filepath.Join(t.TempDir(), "font.ttf")
+	require.Nil(t, os.WriteFile(fontPath, []byte("not a font"), 0644))
+	_, err := readGlyphFont(fontPath, nil)
+	assert.ErrorContains(t, err, "is not a TTF or OTF font")
+}
+
+// decodeGlyphRange decodes the single fontstack of a glyphs protocol buffer message
+func decodeGlyphRange(t *testing.T, content []byte) (string, string, map[rune]glyph) {
+	fields := decodeFields(t, content)
+	require.Len(t, fields[1], 1)
+	stack := decodeFields(t, fields[1][0].([]byte))
+	glyphs := make(map[rune]glyph)
+	for _, message := range stack[3] {
+		glyphFields := decodeFields(t, message.([]byte))
+		decoded := glyph{
+			id:      rune(glyphFields[1][0].(uint64)),
+			width:   int(glyphFields[3][0].(uint64)),
+			height:  int(glyphFields[4][0].(uint64)),
+			left:    unzigzag(glyphFields[5][0].(uint64)),
+			top:     unzigzag(glyphFields[6][0].(uint64)),
+			advance: int(glyphFields[7][0].(uint64)),
+		}
+		if len(glyphFields[2]) > 0 {
+			decoded.bitmap = glyphFields[2][0].([]byte)
+		}
+		glyphs[decoded.id] = decoded
+	}
+	return string(stack[1][0].([]byte)), string(stack[2][0].([]byte)), glyphs
+}
+
+func decodeFields(t *testing.T, message []byte) map[int][]interface{} {
+	fields := make(map[int][]interface{})
+	for len(message) > 0 {
+		key, n := binary.Uvarint(message)
+		require.True(t, n > 0)
+		message = message[n:]
+		value, n := binary.Uvarint(message)
+		require.True(t, n > 0)
+		message = message[n:]
+		switch key & 7 {
+		case 0:
+			fields[int(key>>3)] = append(fields[int(key>>3)], value)
+		case 2:
+			fields[int(key>>3)] = append(fields[int(key>>3)], message[:value])
+			message = message[value:]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+	}
+	return fields
+}
+
+func unzigzag(value uint64) int {
+	return int(int32(uint32(value>>1)) ^ -int32(value&1))
+}
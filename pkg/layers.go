@@ -0,0 +1,117 @@
+package pkg
+
+import (
+	"log"
+
+	"github.com/pdok/goas/pkg/convert"
+	"github.com/pdok/goas/pkg/mapbox"
+	"github.com/pdok/goas/pkg/models"
+	"github.com/pdok/goas/pkg/sld"
+)
+
+// deriveLayers sets the layers of the style to the data layers the native stylesheet renders, with their geometry type
+// and the properties its filters and expressions refer to, typed by the literal values they are compared with. The id,
+// type, sample-data and properties-schema of a layer in the config (or inferred from its sample data) take precedence
+// over the derived ones, layers in the config that are not in the stylesheet are kept with a warning.
+func deriveLayers(styleMetadata *models.StyleMetadata, stylesheets []models.Document) error {
+	stylesheet, ok := nativeStylesheet(*styleMetadata, stylesheets)
+	if !ok {
+		log.Printf("warning: style %s has no Mapbox or SLD stylesheet to derive its layers from", styleMetadata.Id)
+		return nil
+	}
+	var dataLayers []convert.DataLayer
+	if root, _ := stylesheet.MediaType.SplitParams(); root == models.MapboxMediaType {
+		style, err := mapbox.Parse(stylesheet.Content.Bytes())
+		if err != nil {
+			return err
+		}
+		dataLayers = convert.MapboxDataLayers(style)
+	} else {
+		root, err := sld.Parse(stylesheet.Content.Bytes())
+		if err != nil {
+			return err
+		}
+		dataLayers = convert.SldDataLayers(root)
+	}
+
+	configured := make(map[string]models.StyleLayer)
+	for _, layer := range styleMetadata.Layers {
+		configured[layer.Id] = layer
+	}
+	var layers []models.StyleLayer
+	derived := make(map[string]bool)
+	for _, dataLayer := range dataLayers {
+		derived[dataLayer.Id] = true
+		layer := models.StyleLayer{Id: dataLayer.Id, GeometryType: dataLayer.GeometryType}
+		if len(dataLayer.Attributes) > 0 {
+			layer.PropertiesSchema = &models.PropertiesSchema{Type: "object", Properties: make(map[string]*models.PropertiesSchema)}
+			for _, attribute := range dataLayer.Attributes {
+				layer.PropertiesSchema.Properties[attribute.Name] = &models.PropertiesSchema{Type: valuesType(attribute.Values)}
+			}
+		}
+		if explicit, ok := configured[dataLayer.Id]; ok {
+			if explicit.GeometryType != nil {
+				layer.GeometryType = explicit.GeometryType
+			}
+			if explicit.PropertiesSchema != nil {
+				layer.PropertiesSchema = explicit.PropertiesSchema
+			}
+			layer.SampleData = explicit.SampleData
+		}
+		layers = append(layers, layer)
+	}
+	for _, layer := range styleMetadata.Layers {
+		if !derived[layer.Id] {
+			log.Printf("warning: layer %s of style %s is not in its stylesheet %s", layer.Id, styleMetadata.Id, stylesheet.Path)
+			layers = append(layers, layer)
+		}
+	}
+	styleMetadata.Layers = layers
+	return nil
+}
+
+// valuesType the schema type all values have in common, empty when there are no values or they are of different types
+func valuesType(values []interface{}) string {
+	schemaType := ""
+	for _, value := range values {
+		var valueType string
+		switch value.(type) {
+		case string:
+			valueType = "string"
+		case float64:
+			valueType = "number"
+		case bool:
+			valueType = "boolean"
+		}
+		if schemaType != "" && schemaType != valueType {
+			return ""
+		}
+		schemaType = valueType
+	}
+	return schemaType
+}
+
+// nativeStylesheet the generated Mapbox or SLD stylesheet of the style marked as native, or else the first one read from
+// an asset
+func nativeStylesheet(styleMetadata models.StyleMetadata, stylesheets []models.Document) (models.Document, bool) {
+	candidate := -1
+	for i, stylesheet := range styleMetadata.Stylesheets {
+		if i >= len(stylesheets) {
+			break
+		}
+		root, _ := stylesheets[i].MediaType.SplitParams()
+		if root != models.MapboxMediaType && root != models.SldMediaType {
+			continue
+		}
+		if stylesheet.Native != nil && *stylesheet.Native {
+			return stylesheets[i], true
+		}
+		if candidate < 0 && stylesheet.GenerateFrom == nil {
+			candidate = i
+		}
+	}
+	if candidate < 0 {
+		return models.Document{}, false
+	}
+	return stylesheets[candidate], true
+}
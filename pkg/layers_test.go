@@ -0,0 +1,62 @@
+package pkg
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/pdok/goas/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeriveLayers(t *testing.T) {
+	config, _ := ParseConfig("../examples/generate_config.yaml")
+	config.StylesMetadata[0].Layers = append(config.StylesMetadata[0].Layers, models.StyleLayer{Id: "roads"})
+	documents, err := GenerateDocuments(config, "../examples/assets", []models.Format{models.JsonFormat})
+	require.Nil(t, err)
+	metadata := map[string]models.StyleMetadata{}
+	for _, document := range documents {
+		if document.Path == "styles/daraa/metadata.json" || document.Path == "styles/daraa-legacy/metadata.json" {
+			var styleMetadata models.StyleMetadata
+			require.Nil(t, json.Unmarshal(document.Content.Bytes(), &styleMetadata))
+			metadata[styleMetadata.Id] = styleMetadata
+		}
+	}
+
+	layers := metadata["daraa"].Layers
+	require.Len(t, layers, 4)
+	assert.Equal(t, []string{"VegetationSrf", "hydrographycrv", "SettlementPnt", "roads"}, layerIds(layers))
+	assert.Equal(t, models.Polygons, *layers[0].GeometryType)
+	assert.Equal(t, "https://demo.ldproxy.net/daraa/collections/VegetationSrf/items?f=json&limit=100", *layers[0].SampleData.Href, "the configured sample data is kept")
+	assert.Nil(t, layers[0].PropertiesSchema)
+	assert.Equal(t, models.Lines, *layers[1].GeometryType)
+	assert.Equal(t, []string{"F_CODE"}, propertyNames(layers[1].PropertiesSchema))
+	assert.Equal(t, "string", layers[1].PropertiesSchema.Properties["F_CODE"].Type, "compared with a string")
+	assert.Equal(t, models.Points, *layers[2].GeometryType, "a circle with labels")
+	assert.Equal(t, []string{"POP", "ZI005_FNA"}, propertyNames(layers[2].PropertiesSchema))
+	assert.Nil(t, layers[3].GeometryType, "a configured layer that is not in the stylesheet is kept")
+
+	legacyLayers := metadata["daraa-legacy"].Layers
+	assert.Equal(t, []string{"VegetationSrf", "hydrographycrv", "SettlementPnt"}, layerIds(legacyLayers))
+	assert.Equal(t, models.Polygons, *legacyLayers[0].GeometryType)
+	assert.Equal(t, models.Lines, *legacyLayers[1].GeometryType)
+	assert.Equal(t, models.Points, *legacyLayers[2].GeometryType)
+	assert.Equal(t, []string{"POP", "UPDATED", "ZI005_FNA"}, propertyNames(legacyLayers[2].PropertiesSchema), "inferred from the sample data")
+	assert.Nil(t, config.StylesMetadata[0].Layers[0].GeometryType, "the config is not changed")
+}
+
+func layerIds(layers []models.StyleLayer) (ids []string) {
+	for _, layer := range layers {
+		ids = append(ids, layer.Id)
+	}
+	return ids
+}
+
+func propertyNames(schema *models.PropertiesSchema) (names []string) {
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
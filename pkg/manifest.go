@@ -0,0 +1,27 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	"github.com/pdok/goas/pkg/models"
+)
+
+// GenerateManifest generates the manifest (at _goas/manifest.json) of the documents, with the path, media type, length,
+// SHA-256 checksum and source asset of every document, sorted by path
+func GenerateManifest(documents []models.Document) (*models.Document, error) {
+	manifest := models.Manifest{Files: []models.ManifestFile{}}
+	for _, document := range documents {
+		file := models.NewManifestFile(document.Path, document.MediaType, document.Content.Bytes())
+		file.ContentEncoding = document.ContentEncoding
+		file.Source = document.Source
+		manifest.Files = append(manifest.Files, file)
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool { return manifest.Files[i].Path < manifest.Files[j].Path })
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &models.Document{Path: models.ManifestPath, MediaType: models.JsonMediaType, Content: bytes.NewBuffer(content)}, nil
+}
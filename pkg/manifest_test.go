@@ -0,0 +1,53 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/pdok/goas/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateManifest(t *testing.T) {
+	config, _ := ParseConfig("../examples/config.yaml")
+	documents, err := GenerateDocuments(config, "../examples/assets", []models.Format{models.JsonFormat})
+	require.Nil(t, err)
+
+	manifest, err := GenerateManifest(documents)
+	require.Nil(t, err)
+	assert.Equal(t, "_goas/manifest.json", manifest.Path)
+	assert.Equal(t, models.JsonMediaType, manifest.MediaType)
+	var actual models.Manifest
+	require.Nil(t, json.Unmarshal(manifest.Content.Bytes(), &actual))
+	require.Len(t, actual.Files, len(documents))
+	for i := 1; i < len(actual.Files); i++ {
+		assert.Less(t, actual.Files[i-1].Path, actual.Files[i].Path, "files are sorted by path")
+	}
+	for _, file := range actual.Files {
+		if file.Path == "resources/thumbnail.png" {
+			assert.Equal(t, models.MediaType("image/png"), file.MediaType)
+			assert.Equal(t, "thumbnail.png", file.Source)
+			assert.Len(t, file.Sha256, 64)
+		}
+		if file.Path == "styles.json" {
+			assert.Equal(t, "", file.Source, "rendered documents have no source asset")
+		}
+	}
+
+	again, _ := GenerateManifest(documents)
+	assert.Equal(t, manifest.Content.String(), again.Content.String())
+}
+
+func TestGenerateManifestFile(t *testing.T) {
+	documents := []models.Document{{Path: "styles.json.gz", MediaType: models.JsonMediaType, Content: bytes.NewBufferString("abc"), ContentEncoding: models.GzipEncoding}}
+	manifest, _ := GenerateManifest(documents)
+	assert.JSONEq(t, `{"files": [{
+		"path": "styles.json.gz",
+		"mediaType": "application/json",
+		"contentEncoding": "gzip",
+		"length": 3,
+		"sha256": "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	}]}`, manifest.Content.String())
+}
@@ -0,0 +1,34 @@
+package mapbox
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected color.NRGBA
+	}{
+		{value: "#f00", expected: color.NRGBA{R: 255, A: 255}},
+		{value: "#00ff0080", expected: color.NRGBA{G: 255, A: 128}},
+		{value: "rgb(70, 110, 160)", expected: color.NRGBA{R: 70, G: 110, B: 160, A: 255}},
+		{value: "rgba(0, 0, 255, 0.5)", expected: color.NRGBA{B: 255, A: 128}},
+		{value: "hsl(0, 100%, 50%)", expected: color.NRGBA{R: 255, A: 255}},
+		{value: "Orange", expected: color.NRGBA{R: 255, G: 165, A: 255}},
+		{value: "transparent", expected: color.NRGBA{}},
+	}
+	for _, test := range tests {
+		parsed, err := ParseColor(test.value)
+		require.Nil(t, err, test.value)
+		require.Equal(t, test.expected, parsed, test.value)
+	}
+
+	for _, value := range []string{"", "#ff", "rgb(1, 2)", "not-a-color"} {
+		_, err := ParseColor(value)
+		require.NotNil(t, err, value)
+	}
+	require.Equal(t, "#466ea0", ToHex(color.NRGBA{R: 70, G: 110, B: 160, A: 255}))
+}
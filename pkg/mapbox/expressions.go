@@ -0,0 +1,42 @@
+package mapbox
+
+// IsExpression whether the value is an expression: an array starting with a known operator
+func IsExpression(value interface{}) bool {
+	array, ok := value.([]interface{})
+	if !ok || len(array) == 0 {
+		return false
+	}
+	operator, ok := array[0].(string)
+	return ok && expressionOperators[operator]
+}
+
+// expressionOperators the operators of the expression language - https://docs.mapbox.com/mapbox-gl-js/style-spec/expressions/
+var expressionOperators = map[string]bool{
+	// types
+	"array": true, "boolean": true, "collator": true, "format": true, "image": true, "literal": true, "number": true,
+	"number-format": true, "object": true, "string": true, "to-boolean": true, "to-color": true, "to-number": true,
+	"to-string": true, "typeof": true,
+	// feature data
+	"accumulated": true, "feature-state": true, "geometry-type": true, "id": true, "line-progress": true,
+	"properties": true,
+	// lookup
+	"at": true, "config": true, "get": true, "has": true, "in": true, "index-of": true, "length": true, "slice": true,
+	// decision
+	"!": true, "!=": true, "<": true, "<=": true, "==": true, ">": true, ">=": true, "all": true, "any": true,
+	"case": true, "coalesce": true, "match": true, "within": true,
+	// ramps, scales, curves
+	"interpolate": true, "interpolate-hcl": true, "interpolate-lab": true, "step": true,
+	// variable binding
+	"let": true, "var": true,
+	// string
+	"concat": true, "downcase": true, "is-supported-script": true, "resolved-locale": true, "upcase": true,
+	// color
+	"rgb": true, "rgba": true, "to-rgba": true,
+	// math
+	"-": true, "*": true, "/": true, "%": true, "^": true, "+": true, "abs": true, "acos": true, "asin": true,
+	"atan": true, "ceil": true, "cos": true, "distance": true, "e": true, "floor": true, "ln": true, "ln2": true,
+	"log10": true, "log2": true, "max": true, "min": true, "pi": true, "random": true, "round": true, "sin": true,
+	"sqrt": true, "tan": true,
+	// camera and heatmap
+	"zoom": true, "pitch": true, "distance-from-center": true, "heatmap-density": true,
+}
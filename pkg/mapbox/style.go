@@ -0,0 +1,279 @@
+package mapbox
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// LayerType the type of a layer - https://docs.mapbox.com/mapbox-gl-js/style-spec/layers/#type
+type LayerType string
+
+const (
+	Background    LayerType = "background"
+	Fill          LayerType = "fill"
+	Line          LayerType = "line"
+	Symbol        LayerType = "symbol"
+	Circle        LayerType = "circle"
+	Heatmap       LayerType = "heatmap"
+	FillExtrusion LayerType = "fill-extrusion"
+	Raster        LayerType = "raster"
+	Hillshade     LayerType = "hillshade"
+	Sky           LayerType = "sky"
+)
+
+var LayerTypes = []LayerType{Background, Fill, Line, Symbol, Circle, Heatmap, FillExtrusion, Raster, Hillshade, Sky}
+
+// Style a Mapbox GL (or MapLibre) style - https://docs.mapbox.com/mapbox-gl-js/style-spec/root/
+type Style struct {
+	Version    int                    `json:"version"`
+	Name       string                 `json:"name,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	Center     []float64              `json:"center,omitempty"`
+	Zoom       *float64               `json:"zoom,omitempty"`
+	Bearing    *float64               `json:"bearing,omitempty"`
+	Pitch      *float64               `json:"pitch,omitempty"`
+	Light      map[string]interface{} `json:"light,omitempty"`
+	Sources    map[string]Source      `json:"sources"`
+	Sprite     interface{}            `json:"sprite,omitempty"` // a url, or (MapLibre) an array of {"id", "url"} objects
+	Glyphs     string                 `json:"glyphs,omitempty"`
+	Transition map[string]interface{} `json:"transition,omitempty"`
+	Layers     []Layer                `json:"layers"`
+}
+
+// Source a source of the style - https://docs.mapbox.com/mapbox-gl-js/style-spec/sources/
+type Source struct {
+	Type        string      `yaml:"type" json:"type"`
+	Url         string      `yaml:"url" json:"url,omitempty"`
+	Tiles       []string    `yaml:"tiles" json:"tiles,omitempty"`
+	MinZoom     *float64    `yaml:"minzoom" json:"minzoom,omitempty"`
+	MaxZoom     *float64    `yaml:"maxzoom" json:"maxzoom,omitempty"`
+	Attribution string      `yaml:"attribution" json:"attribution,omitempty"`
+	Data        interface{} `yaml:"data" json:"data,omitempty"`
+}
+
+// Layer a layer of the style - https://docs.mapbox.com/mapbox-gl-js/style-spec/layers/
+type Layer struct {
+	Id          string                 `json:"id"`
+	Type        LayerType              `json:"type"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	Source      string                 `json:"source,omitempty"`
+	SourceLayer string                 `json:"source-layer,omitempty"`
+	MinZoom     *float64               `json:"minzoom,omitempty"`
+	MaxZoom     *float64               `json:"maxzoom,omitempty"`
+	Filter      interface{}            `json:"filter,omitempty"`
+	Layout      map[string]interface{} `json:"layout,omitempty"`
+	Paint       map[string]interface{} `json:"paint,omitempty"`
+}
+
+func Parse(content []byte) (*Style, error) {
+	var style Style
+	err := json.Unmarshal(content, &style)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse mapbox style: %s", err)
+	}
+	return &style, nil
+}
+
+func (style *Style) Encode() (*bytes.Buffer, error) {
+	content := new(bytes.Buffer)
+	enc := json.NewEncoder(content)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(style)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode mapbox style: %s", err)
+	}
+	return content, nil
+}
+
+// SetRootProperty sets a root property (e.g. sprite) of the style, keeping the order and values of the other properties.
+// A new property is added before the layers.
+func SetRootProperty(content []byte, key string, value interface{}) (*bytes.Buffer, error) {
+	properties, err := decodeObject(content)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse mapbox style: %s", err)
+	}
+	encodedValue, err := encodeValue(value)
+	if err != nil {
+		return nil, err
+	}
+	if !properties.set(key, encodedValue) {
+		index := len(properties)
+		for i := range properties {
+			if properties[i].key == "layers" {
+				index = i
+			}
+		}
+		properties = append(properties[:index], append(object{{key, encodedValue}}, properties[index:]...)...)
+	}
+	return properties.indent()
+}
+
+// RewriteUrls rewrites the urls of the sprite, glyphs and sources (url and tiles) of the style, keeping the order and
+// values of the other properties
+func RewriteUrls(content []byte, rewrite func(url string) string) (*bytes.Buffer, error) {
+	properties, err := decodeObject(content)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse mapbox style: %s", err)
+	}
+	for i, property := range properties {
+		var rewritten json.RawMessage
+		switch property.key {
+		case "sprite":
+			var sprites []map[string]interface{}
+			if json.Unmarshal(property.value, &sprites) == nil {
+				// multiple sprites - https://maplibre.org/maplibre-style-spec/sprite/#multiple-sprite-sources
+				for _, sprite := range sprites {
+					if url, ok := sprite["url"].(string); ok {
+						sprite["url"] = rewrite(url)
+					}
+				}
+				rewritten, err = encodeValue(sprites)
+				break
+			}
+			rewritten, err = rewriteString(property.value, rewrite)
+		case "glyphs":
+			rewritten, err = rewriteString(property.value, rewrite)
+		case "sources":
+			rewritten, err = rewriteSources(property.value, rewrite)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not rewrite the %s of mapbox style: %s", property.key, err)
+		}
+		properties[i].value = rewritten
+	}
+	return properties.indent()
+}
+
+func rewriteSources(value json.RawMessage, rewrite func(url string) string) (json.RawMessage, error) {
+	sources, err := decodeObject(value)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sources {
+		source, err := decodeObject(sources[i].value)
+		if err != nil {
+			return nil, fmt.Errorf("source %s: %s", sources[i].key, err)
+		}
+		for j, property := range source {
+			switch property.key {
+			case "url":
+				source[j].value, err = rewriteString(property.value, rewrite)
+			case "tiles":
+				var tiles []string
+				err = json.Unmarshal(property.value, &tiles)
+				if err == nil {
+					for k := range tiles {
+						tiles[k] = rewrite(tiles[k])
+					}
+					source[j].value, err = encodeValue(tiles)
+				}
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s of source %s: %s", property.key, sources[i].key, err)
+			}
+		}
+		sources[i].value = source.compact()
+	}
+	return sources.compact(), nil
+}
+
+func rewriteString(value json.RawMessage, rewrite func(url string) string) (json.RawMessage, error) {
+	var url string
+	err := json.Unmarshal(value, &url)
+	if err != nil {
+		return nil, err
+	}
+	return encodeValue(rewrite(url))
+}
+
+// object the properties of a JSON object in their original order, with their values as is
+type object []objectProperty
+
+type objectProperty struct {
+	key   string
+	value json.RawMessage
+}
+
+func decodeObject(content []byte) (object, error) {
+	var properties object
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	token, err := decoder.Token()
+	if err != nil || token != json.Delim('{') {
+		return nil, fmt.Errorf("expected an object")
+	}
+	for decoder.More() {
+		token, err = decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		var raw json.RawMessage
+		err = decoder.Decode(&raw)
+		if err != nil {
+			return nil, err
+		}
+		properties = append(properties, objectProperty{token.(string), raw})
+	}
+	return properties, nil
+}
+
+func encodeValue(value interface{}) (json.RawMessage, error) {
+	var encoded bytes.Buffer
+	encoder := json.NewEncoder(&encoded)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(value)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(encoded.Bytes()), nil
+}
+
+// set replaces the value of the property, returns false when the object does not have the property
+func (properties object) set(key string, value json.RawMessage) bool {
+	found := false
+	for i := range properties {
+		if properties[i].key == key {
+			properties[i].value = value
+			found = true
+		}
+	}
+	return found
+}
+
+func (properties object) compact() json.RawMessage {
+	var compact bytes.Buffer
+	compact.WriteString("{")
+	for i, property := range properties {
+		if i > 0 {
+			compact.WriteString(",")
+		}
+		encodedKey, _ := encodeValue(property.key)
+		compact.Write(encodedKey)
+		compact.WriteString(":")
+		compact.Write(property.value)
+	}
+	compact.WriteString("}")
+	return compact.Bytes()
+}
+
+func (properties object) indent() (*bytes.Buffer, error) {
+	indented := new(bytes.Buffer)
+	err := json.Indent(indented, properties.compact(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("could not encode mapbox style: %s", err)
+	}
+	indented.WriteString("\n")
+	return indented, nil
+}
+
+// SourceLayerName the name of the data layer the layer is rendered from, the source itself for non vector sources
+func (layer Layer) SourceLayerName() string {
+	if layer.SourceLayer != "" {
+		return layer.SourceLayer
+	}
+	return layer.Source
+}
@@ -0,0 +1,98 @@
+package mapbox
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSprite(t *testing.T) {
+	style, err := Parse([]byte(`{"version": 8, "sprite": "https://example.org/sprite", "sources": {}, "layers": []}`))
+	require.Nil(t, err)
+	assert.Equal(t, "https://example.org/sprite", style.Sprite)
+
+	style, err = Parse([]byte(`{"version": 8, "sources": {}, "layers": [{"id": "water", "type": "fill"}],
+		"sprite": [{"id": "roads", "url": "https://example.org/roads"}, {"id": "pois", "url": "https://example.org/pois"}]}`))
+	require.Nil(t, err, "a MapLibre array of sprites")
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"id": "roads", "url": "https://example.org/roads"},
+		map[string]interface{}{"id": "pois", "url": "https://example.org/pois"},
+	}, style.Sprite)
+	assert.Len(t, style.Layers, 1)
+}
+
+func TestSetRootProperty(t *testing.T) {
+	content := []byte(`{"version": 8, "name": "night", "sources": {}, "layers": []}`)
+
+	added, err := SetRootProperty(content, "sprite", "https://example.org/sprite?a=1&b=2")
+	require.Nil(t, err)
+	assert.Equal(t, `{
+  "version": 8,
+  "name": "night",
+  "sources": {},
+  "sprite": "https://example.org/sprite?a=1&b=2",
+  "layers": []
+}
+`, added.String())
+
+	replaced, err := SetRootProperty(added.Bytes(), "name", "day")
+	require.Nil(t, err)
+	assert.Contains(t, replaced.String(), `"name": "day",`)
+
+	_, err = SetRootProperty([]byte(`[]`), "sprite", "x")
+	assert.EqualError(t, err, "could not parse mapbox style: expected an object")
+}
+
+func TestRewriteUrls(t *testing.T) {
+	content := []byte(`{
+  "version": 8,
+  "sprite": "https://styles.example.org/sprite",
+  "glyphs": "https://fonts.example.org/{fontstack}/{range}.pbf",
+  "sources": {
+    "tiles": {"type": "vector", "tiles": ["https://tiles.example.org/{z}/{x}/{y}.pbf"], "maxzoom": 16},
+    "terrain": {"type": "raster-dem", "url": "https://tiles.example.org/terrain.json"}
+  },
+  "layers": [{"id": "background", "type": "background", "metadata": {"url": "https://tiles.example.org/"}}]
+}`)
+
+	rewritten, err := RewriteUrls(content, func(url string) string {
+		return strings.Replace(url, "example.org", "acc.example.org", 1)
+	})
+	require.Nil(t, err)
+	assert.Equal(t, `{
+  "version": 8,
+  "sprite": "https://styles.acc.example.org/sprite",
+  "glyphs": "https://fonts.acc.example.org/{fontstack}/{range}.pbf",
+  "sources": {
+    "tiles": {
+      "type": "vector",
+      "tiles": [
+        "https://tiles.acc.example.org/{z}/{x}/{y}.pbf"
+      ],
+      "maxzoom": 16
+    },
+    "terrain": {
+      "type": "raster-dem",
+      "url": "https://tiles.acc.example.org/terrain.json"
+    }
+  },
+  "layers": [
+    {
+      "id": "background",
+      "type": "background",
+      "metadata": {
+        "url": "https://tiles.example.org/"
+      }
+    }
+  ]
+}
+`, rewritten.String())
+
+	sprites, err := RewriteUrls([]byte(`{"sprite": [{"id": "roads", "url": "https://example.org/roads"}]}`), func(url string) string {
+		return url + "-acc"
+	})
+	require.Nil(t, err)
+	assert.Contains(t, sprites.String(), `"url": "https://example.org/roads-acc"`)
+}
@@ -0,0 +1,517 @@
+package mapbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Problem a violation of the style specification at a JSON pointer (RFC 6901) in the style
+type Problem struct {
+	Pointer string
+	Message string
+}
+
+func (problem Problem) String() string {
+	return fmt.Sprintf("%s: %s", problem.Pointer, problem.Message)
+}
+
+var rootProperties = map[string]bool{
+	"version": true, "name": true, "metadata": true, "center": true, "zoom": true, "bearing": true, "pitch": true,
+	"light": true, "lights": true, "terrain": true, "fog": true, "projection": true, "sources": true, "sprite": true,
+	"glyphs": true, "transition": true, "layers": true, "imports": true, "schema": true, "camera": true,
+	"models": true, "sky": true, "state": true, "color-theme": true, "iconsets": true, "featuresets": true,
+}
+
+var sourceTypes = map[string]bool{
+	"vector": true, "raster": true, "raster-dem": true, "raster-array": true, "geojson": true, "image": true,
+	"video": true, "model": true, "batched-model": true,
+}
+
+var layerProperties = map[string]bool{
+	"id": true, "type": true, "metadata": true, "source": true, "source-layer": true, "slot": true, "minzoom": true,
+	"maxzoom": true, "filter": true, "layout": true, "paint": true, "ref": true,
+}
+
+// layoutProperties and paintProperties the properties per layer type - https://docs.mapbox.com/mapbox-gl-js/style-spec/layers/
+var layoutProperties = map[LayerType][]string{
+	Background: {},
+	Fill:       {"fill-sort-key"},
+	Line:       {"line-cap", "line-join", "line-miter-limit", "line-round-limit", "line-sort-key", "line-z-offset", "line-elevation-reference", "line-cross-slope", "line-width-unit"},
+	Symbol: {"symbol-placement", "symbol-spacing", "symbol-avoid-edges", "symbol-sort-key", "symbol-z-order",
+		"symbol-z-elevate", "symbol-elevation-reference", "icon-allow-overlap", "icon-overlap", "icon-ignore-placement",
+		"icon-optional", "icon-rotation-alignment", "icon-size", "icon-size-scale-range", "icon-text-fit",
+		"icon-text-fit-padding", "icon-image", "icon-rotate", "icon-padding", "icon-keep-upright", "icon-offset",
+		"icon-anchor", "icon-pitch-alignment", "text-pitch-alignment", "text-rotation-alignment", "text-field",
+		"text-font", "text-size", "text-size-scale-range", "text-max-width", "text-line-height", "text-letter-spacing",
+		"text-justify", "text-radial-offset", "text-variable-anchor", "text-variable-anchor-offset", "text-anchor",
+		"text-max-angle", "text-writing-mode", "text-rotate", "text-padding", "text-keep-upright", "text-transform",
+		"text-offset", "text-allow-overlap", "text-overlap", "text-ignore-placement", "text-optional"},
+	Circle:        {"circle-sort-key", "circle-elevation-reference"},
+	Heatmap:       {},
+	FillExtrusion: {"fill-extrusion-edge-radius"},
+	Raster:        {},
+	Hillshade:     {},
+	Sky:           {},
+}
+
+var paintProperties = map[LayerType][]string{
+	Background: {"background-color", "background-pattern", "background-opacity", "background-emissive-strength", "background-pitch-alignment"},
+	Fill: {"fill-antialias", "fill-opacity", "fill-color", "fill-outline-color", "fill-translate",
+		"fill-translate-anchor", "fill-pattern", "fill-emissive-strength", "fill-z-offset"},
+	Line: {"line-opacity", "line-color", "line-translate", "line-translate-anchor", "line-width", "line-gap-width",
+		"line-offset", "line-blur", "line-dasharray", "line-pattern", "line-gradient", "line-trim-offset",
+		"line-trim-fade-range", "line-trim-color", "line-emissive-strength", "line-border-width", "line-border-color",
+		"line-occlusion-opacity"},
+	Symbol: {"icon-opacity", "icon-occlusion-opacity", "icon-emissive-strength", "icon-color", "icon-halo-color",
+		"icon-halo-width", "icon-halo-blur", "icon-translate", "icon-translate-anchor", "icon-image-cross-fade",
+		"icon-color-saturation", "icon-color-contrast", "icon-color-brightness-min", "icon-color-brightness-max",
+		"symbol-z-offset", "text-opacity", "text-occlusion-opacity", "text-emissive-strength", "text-color",
+		"text-halo-color", "text-halo-width", "text-halo-blur", "text-translate", "text-translate-anchor"},
+	Circle: {"circle-radius", "circle-color", "circle-blur", "circle-opacity", "circle-translate",
+		"circle-translate-anchor", "circle-pitch-scale", "circle-pitch-alignment", "circle-stroke-width",
+		"circle-stroke-color", "circle-stroke-opacity", "circle-emissive-strength"},
+	Heatmap: {"heatmap-radius", "heatmap-weight", "heatmap-intensity", "heatmap-color", "heatmap-opacity"},
+	FillExtrusion: {"fill-extrusion-opacity", "fill-extrusion-color", "fill-extrusion-translate",
+		"fill-extrusion-translate-anchor", "fill-extrusion-pattern", "fill-extrusion-height", "fill-extrusion-base",
+		"fill-extrusion-vertical-gradient", "fill-extrusion-ambient-occlusion-intensity",
+		"fill-extrusion-ambient-occlusion-radius", "fill-extrusion-emissive-strength"},
+	Raster: {"raster-opacity", "raster-hue-rotate", "raster-brightness-min", "raster-brightness-max",
+		"raster-saturation", "raster-contrast", "raster-resampling", "raster-fade-duration", "raster-color",
+		"raster-color-mix", "raster-color-range", "raster-emissive-strength"},
+	Hillshade: {"hillshade-illumination-direction", "hillshade-illumination-anchor", "hillshade-exaggeration",
+		"hillshade-shadow-color", "hillshade-highlight-color", "hillshade-accent-color", "hillshade-emissive-strength"},
+	Sky: {"sky-type", "sky-atmosphere-sun", "sky-atmosphere-sun-intensity", "sky-gradient-center",
+		"sky-gradient-radius", "sky-gradient", "sky-atmosphere-halo-color", "sky-atmosphere-color", "sky-opacity"},
+}
+
+// legacyFilterOperators the operators of the (deprecated) filter syntax that differ from expressions
+var legacyFilterOperators = map[string]bool{
+	"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true, "in": true, "!in": true, "has": true,
+	"!has": true, "all": true, "any": true, "none": true,
+}
+
+// Validate checks a style against the Mapbox (and MapLibre) style specification: version 8, the required root
+// properties, the sources and layers, the paint and layout properties per layer type and the expression syntax
+func Validate(content []byte) []Problem {
+	var root interface{}
+	err := json.Unmarshal(content, &root)
+	if err != nil {
+		return []Problem{{Pointer: "", Message: fmt.Sprintf("invalid json: %s", err)}}
+	}
+	style, ok := root.(map[string]interface{})
+	if !ok {
+		return []Problem{{Pointer: "", Message: "style should be an object"}}
+	}
+	v := &validator{}
+	for _, key := range []string{"version", "sources", "layers"} {
+		if _, ok := style[key]; !ok {
+			v.add("", "missing required property %s", key)
+		}
+	}
+	for _, key := range sortedKeys(style) {
+		if !rootProperties[key] {
+			v.add(pointer("", key), "unknown root property")
+		}
+	}
+	if version, ok := style["version"]; ok && version != 8.0 {
+		v.add("/version", "should be 8, found: %v", version)
+	}
+	for _, key := range []string{"name", "glyphs"} {
+		if value, ok := style[key]; ok {
+			if _, ok := value.(string); !ok {
+				v.add(pointer("", key), "should be a string")
+			}
+		}
+	}
+	sources := v.sources(style["sources"])
+	if layers, ok := style["layers"]; ok {
+		v.layers(layers, sources)
+	}
+	return v.problems
+}
+
+type validator struct {
+	problems []Problem
+}
+
+func (v *validator) add(path string, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{Pointer: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// sources validates the sources, returning the type per source id
+func (v *validator) sources(value interface{}) map[string]string {
+	types := make(map[string]string)
+	sources, ok := value.(map[string]interface{})
+	if !ok {
+		if value != nil {
+			v.add("/sources", "should be an object")
+		}
+		return types
+	}
+	for _, id := range sortedKeys(sources) {
+		path := pointer("/sources", id)
+		source, ok := sources[id].(map[string]interface{})
+		if !ok {
+			v.add(path, "should be an object")
+			continue
+		}
+		sourceType, _ := source["type"].(string)
+		if !sourceTypes[sourceType] {
+			v.add(pointer(path, "type"), "unknown source type: %v", source["type"])
+		}
+		types[id] = sourceType
+	}
+	return types
+}
+
+func (v *validator) layers(value interface{}, sources map[string]string) {
+	layers, ok := value.([]interface{})
+	if !ok {
+		v.add("/layers", "should be an array")
+		return
+	}
+	ids := make(map[string]bool)
+	for i, value := range layers {
+		path := pointer("/layers", strconv.Itoa(i))
+		layer, ok := value.(map[string]interface{})
+		if !ok {
+			v.add(path, "should be an object")
+			continue
+		}
+		for _, key := range sortedKeys(layer) {
+			if !layerProperties[key] {
+				v.add(pointer(path, key), "unknown layer property")
+			}
+		}
+		id, ok := layer["id"].(string)
+		if !ok || id == "" {
+			v.add(pointer(path, "id"), "missing required layer id")
+		} else if ids[id] {
+			v.add(pointer(path, "id"), "duplicate layer id: %s", id)
+		}
+		ids[id] = true
+
+		layerType := LayerType(fmt.Sprint(layer["type"]))
+		if _, ok := paintProperties[layerType]; !ok {
+			v.add(pointer(path, "type"), "unknown layer type: %v", layer["type"])
+			continue
+		}
+		v.layerSource(path, layerType, layer, sources)
+		v.zoomRange(path, layer)
+		if filter, ok := layer["filter"]; ok {
+			v.filter(pointer(path, "filter"), filter)
+		}
+		v.properties(pointer(path, "layout"), layer["layout"], layoutProperties[layerType], false)
+		v.properties(pointer(path, "paint"), layer["paint"], paintProperties[layerType], true)
+	}
+}
+
+func (v *validator) layerSource(path string, layerType LayerType, layer map[string]interface{}, sources map[string]string) {
+	if layerType == Background || layerType == Sky {
+		return
+	}
+	source, ok := layer["source"].(string)
+	if !ok {
+		v.add(pointer(path, "source"), "missing required source for a %s layer", layerType)
+		return
+	}
+	sourceType, ok := sources[source]
+	if !ok {
+		v.add(pointer(path, "source"), "source %s not found in sources", source)
+		return
+	}
+	_, hasSourceLayer := layer["source-layer"]
+	if !sourceTypes[sourceType] {
+		return
+	}
+	if sourceType == "vector" && !hasSourceLayer {
+		v.add(pointer(path, "source-layer"), "missing required source-layer for vector source %s", source)
+	} else if sourceType != "vector" && hasSourceLayer {
+		v.add(pointer(path, "source-layer"), "source-layer is only allowed for vector sources")
+	}
+}
+
+func (v *validator) zoomRange(path string, layer map[string]interface{}) {
+	minZoom, maxZoom := 0.0, 24.0
+	for _, key := range []string{"minzoom", "maxzoom"} {
+		value, ok := layer[key]
+		if !ok {
+			continue
+		}
+		zoom, ok := value.(float64)
+		if !ok || zoom < 0 || zoom > 24 {
+			v.add(pointer(path, key), "should be a number between 0 and 24, found: %v", value)
+			continue
+		}
+		if key == "minzoom" {
+			minZoom = zoom
+		} else {
+			maxZoom = zoom
+		}
+	}
+	if minZoom > maxZoom {
+		v.add(pointer(path, "minzoom"), "minzoom %v is larger than maxzoom %v", minZoom, maxZoom)
+	}
+}
+
+func (v *validator) properties(path string, value interface{}, known []string, paint bool) {
+	if value == nil {
+		return
+	}
+	properties, ok := value.(map[string]interface{})
+	if !ok {
+		v.add(path, "should be an object")
+		return
+	}
+	knownSet := map[string]bool{"visibility": !paint}
+	for _, name := range known {
+		knownSet[name] = true
+	}
+	for _, name := range sortedKeys(properties) {
+		propertyPath := pointer(path, name)
+		if paint && strings.HasSuffix(name, "-transition") && knownSet[strings.TrimSuffix(name, "-transition")] {
+			continue
+		}
+		if !knownSet[name] {
+			v.add(propertyPath, "unknown property for this layer type")
+			continue
+		}
+		if name == "visibility" && properties[name] != "visible" && properties[name] != "none" {
+			v.add(propertyPath, "should be visible or none, found: %v", properties[name])
+			continue
+		}
+		v.propertyValue(propertyPath, name, properties[name])
+	}
+}
+
+func (v *validator) propertyValue(path string, name string, value interface{}) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		v.legacyFunction(path, typed)
+	case []interface{}:
+		if IsExpression(typed) {
+			v.expression(path, typed)
+		}
+	case string:
+		if strings.HasSuffix(name, "-color") {
+			if _, err := ParseColor(typed); err != nil {
+				v.add(path, "%s", err)
+			}
+		}
+	}
+}
+
+// legacyFunction checks the stops of a (deprecated) function - https://docs.mapbox.com/mapbox-gl-js/style-spec/other/#function
+func (v *validator) legacyFunction(path string, function map[string]interface{}) {
+	stops, ok := function["stops"]
+	if !ok {
+		if function["type"] != "identity" {
+			v.add(path, "function without stops")
+		}
+		return
+	}
+	array, ok := stops.([]interface{})
+	if !ok || len(array) == 0 {
+		v.add(pointer(path, "stops"), "should be a non empty array")
+		return
+	}
+	for i, stop := range array {
+		pair, ok := stop.([]interface{})
+		if !ok || len(pair) != 2 {
+			v.add(pointer(pointer(path, "stops"), strconv.Itoa(i)), "stop should be an array of an input and an output")
+		}
+	}
+}
+
+// filter checks a filter, in the legacy filter syntax or as an expression
+func (v *validator) filter(path string, filter interface{}) {
+	if _, ok := filter.(bool); ok {
+		return
+	}
+	array, ok := filter.([]interface{})
+	if !ok || len(array) == 0 {
+		v.add(path, "should be an expression, found: %v", filter)
+		return
+	}
+	operator, _ := array[0].(string)
+	if !legacyFilterOperators[operator] || len(array) > 1 && !isLegacyFilter(array) {
+		v.expression(path, array)
+		return
+	}
+	args := array[1:]
+	switch operator {
+	case "all", "any", "none":
+		for i, arg := range args {
+			v.filter(pointer(path, strconv.Itoa(i+1)), arg)
+		}
+	case "has", "!has":
+		if len(args) != 1 {
+			v.add(path, "%s filter should have a single key", operator)
+		}
+	case "in", "!in":
+		if len(args) < 1 {
+			v.add(path, "%s filter should have a key", operator)
+		}
+	default:
+		if len(args) != 2 {
+			v.add(path, "%s filter should have a key and a value", operator)
+		}
+	}
+}
+
+// isLegacyFilter whether a filter uses the legacy syntax, in which the key is a string instead of an expression
+func isLegacyFilter(filter []interface{}) bool {
+	switch filter[0] {
+	case "all", "any":
+		for _, arg := range filter[1:] {
+			if sub, ok := arg.([]interface{}); ok && len(sub) > 0 && isLegacyFilter(sub) {
+				return true
+			}
+		}
+		return false
+	case "none":
+		return true
+	case "has":
+		_, isKey := filter[1].(string)
+		return len(filter) == 2 && isKey
+	case "!has", "!in":
+		return true
+	case "in":
+		if len(filter) < 2 {
+			return true
+		}
+		_, isKey := filter[1].(string)
+		return isKey && len(filter) != 3 || isKey && !isArray(filter[2])
+	default:
+		if len(filter) < 2 {
+			return true
+		}
+		_, isKey := filter[1].(string)
+		return isKey
+	}
+}
+
+func isArray(value interface{}) bool {
+	_, ok := value.([]interface{})
+	return ok
+}
+
+// expressionArity the minimal and maximal (-1 for any) number of arguments of operators with a fixed arity
+var expressionArity = map[string][2]int{
+	"get": {1, 2}, "has": {1, 2}, "at": {2, 2}, "in": {2, 2}, "index-of": {2, 3}, "length": {1, 1},
+	"slice": {2, 3}, "!": {1, 1}, "==": {2, 3}, "!=": {2, 3}, "<": {2, 3}, "<=": {2, 3}, ">": {2, 3},
+	">=": {2, 3}, "literal": {1, 1}, "zoom": {0, 0}, "geometry-type": {0, 0}, "id": {0, 0},
+	"properties": {0, 0}, "to-string": {1, 1}, "to-boolean": {1, 1}, "typeof": {1, 1}, "downcase": {1, 1},
+	"upcase": {1, 1}, "rgb": {3, 3}, "rgba": {4, 4}, "to-rgba": {1, 1}, "abs": {1, 1}, "ceil": {1, 1},
+	"floor": {1, 1}, "round": {1, 1}, "sqrt": {1, 1}, "-": {1, 2}, "/": {2, 2}, "%": {2, 2}, "^": {2, 2},
+	"coalesce": {1, -1}, "concat": {1, -1}, "all": {0, -1}, "any": {0, -1}, "+": {2, -1}, "*": {2, -1},
+	"min": {1, -1}, "max": {1, -1}, "pi": {0, 0}, "e": {0, 0}, "ln2": {0, 0},
+}
+
+// expression checks the syntax of an expression: known operators, the number of arguments and the structure of
+// interpolate, step, match and case - https://docs.mapbox.com/mapbox-gl-js/style-spec/expressions/
+func (v *validator) expression(path string, expression []interface{}) {
+	operator, ok := expression[0].(string)
+	if !ok || !expressionOperators[operator] {
+		v.add(path, "unknown expression operator: %v", expression[0])
+		return
+	}
+	args := expression[1:]
+	if arity, ok := expressionArity[operator]; ok && (len(args) < arity[0] || arity[1] >= 0 && len(args) > arity[1]) {
+		v.add(path, "wrong number of arguments for %s: %d", operator, len(args))
+		return
+	}
+	argsFrom := 1
+	switch operator {
+	case "literal":
+		return
+	case "interpolate", "interpolate-hcl", "interpolate-lab":
+		if len(args) < 4 || len(args)%2 != 0 {
+			v.add(path, "%s should have an interpolation type, an input and pairs of stops and outputs", operator)
+			return
+		}
+		interpolation, ok := args[0].([]interface{})
+		if !ok || len(interpolation) == 0 || (interpolation[0] != "linear" && interpolation[0] != "exponential" && interpolation[0] != "cubic-bezier") {
+			v.add(pointer(path, "1"), "unknown interpolation type: %v", args[0])
+		}
+		v.stops(path, args, 2)
+		argsFrom = 2
+	case "step":
+		if len(args) < 2 || len(args)%2 != 0 {
+			v.add(path, "step should have an input, a default output and pairs of stops and outputs")
+			return
+		}
+		v.stops(path, args, 2)
+	case "match":
+		if len(args) < 4 || len(args)%2 != 0 {
+			v.add(path, "match should have an input, pairs of labels and outputs and a fallback")
+			return
+		}
+		// only the input and outputs are expressions, labels are literals
+		v.argument(pointer(path, "1"), args[0])
+		for i := 2; i < len(args); i += 2 {
+			v.argument(pointer(path, strconv.Itoa(i+1)), args[i])
+		}
+		v.argument(pointer(path, strconv.Itoa(len(args))), args[len(args)-1])
+		return
+	case "case":
+		if len(args) < 3 || len(args)%2 != 1 {
+			v.add(path, "case should have pairs of conditions and outputs and a fallback")
+			return
+		}
+	case "let":
+		if len(args) < 3 || len(args)%2 != 1 {
+			v.add(path, "let should have pairs of names and values and an expression")
+			return
+		}
+	}
+	for i := argsFrom - 1; i < len(args); i++ {
+		v.argument(pointer(path, strconv.Itoa(i+1)), args[i])
+	}
+}
+
+// stops checks the stop inputs of interpolate and step are numbers in ascending order
+func (v *validator) stops(path string, args []interface{}, from int) {
+	previous := 0.0
+	for i := from; i < len(args); i += 2 {
+		stop, ok := args[i].(float64)
+		if !ok {
+			v.add(pointer(path, strconv.Itoa(i+1)), "stop input should be a number, found: %v", args[i])
+			return
+		}
+		if i > from && stop <= previous {
+			v.add(pointer(path, strconv.Itoa(i+1)), "stop inputs should be in strictly ascending order")
+		}
+		previous = stop
+	}
+}
+
+// argument checks an argument of an expression, nested arrays are expressions unless wrapped in a literal
+func (v *validator) argument(path string, arg interface{}) {
+	array, ok := arg.([]interface{})
+	if !ok || len(array) == 0 {
+		return
+	}
+	if _, isOperator := array[0].(string); !isOperator {
+		// e.g. the [r, g, b, a] of an interpolated color or the numbers of an offset
+		return
+	}
+	v.expression(path, array)
+}
+
+// pointer appends an escaped reference token to a JSON pointer
+func pointer(path string, token string) string {
+	return path + "/" + strings.Replace(strings.Replace(token, "~", "~0", -1), "/", "~1", -1)
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
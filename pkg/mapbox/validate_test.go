@@ -0,0 +1,70 @@
+package mapbox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	valid := `{
+	  "version": 8,
+	  "sources": {"osm": {"type": "vector", "url": "https://example.org/tiles.json"}},
+	  "glyphs": "https://example.org/{fontstack}/{range}.pbf",
+	  "layers": [
+		{"id": "background", "type": "background", "paint": {"background-color": "white"}},
+		{
+		  "id": "roads",
+		  "type": "line",
+		  "source": "osm",
+		  "source-layer": "roads",
+		  "filter": ["all", ["==", "class", "primary"], ["!has", "tunnel"]],
+		  "layout": {"line-cap": "round", "visibility": "visible"},
+		  "paint": {
+			"line-color": ["match", ["get", "class"], ["primary", "trunk"], "#f00", "#000"],
+			"line-width": ["interpolate", ["exponential", 1.5], ["zoom"], 5, 0.5, 18, 12],
+			"line-color-transition": {"duration": 300}
+		  }
+		},
+		{
+		  "id": "labels",
+		  "type": "symbol",
+		  "source": "osm",
+		  "source-layer": "places",
+		  "filter": [">=", ["get", "population"], 1000],
+		  "layout": {"text-field": ["get", "name"], "text-font": ["Open Sans Regular"], "text-size": {"stops": [[10, 12], [14, 16]]}}
+		}
+	  ]
+	}`
+	require.Empty(t, Validate([]byte(valid)))
+
+	invalid := `{
+	  "version": 7,
+	  "sources": {"osm": {"type": "vectors"}},
+	  "layers": [
+		{"id": "roads", "type": "line", "source": "unknown", "paint": {"fill-color": "#f00", "line-color": "#ggg"}},
+		{"id": "roads", "type": "lines", "source": "osm"},
+		{"id": "water", "type": "fill", "source": "osm", "source-layer": "water", "minzoom": 12, "maxzoom": 10,
+		 "filter": ["==", ["gett", "class"], "lake"],
+		 "paint": {"fill-opacity": ["step", ["zoom"], 0, 10, 0.5, 8, 1]}}
+	  ]
+	}`
+	var problems []string
+	for _, problem := range Validate([]byte(invalid)) {
+		problems = append(problems, problem.String())
+	}
+	require.Equal(t, []string{
+		"/version: should be 8, found: 7",
+		"/sources/osm/type: unknown source type: vectors",
+		"/layers/0/source: source unknown not found in sources",
+		"/layers/0/paint/fill-color: unknown property for this layer type",
+		"/layers/0/paint/line-color: unknown color: #ggg",
+		"/layers/1/id: duplicate layer id: roads",
+		"/layers/1/type: unknown layer type: lines",
+		"/layers/2/minzoom: minzoom 12 is larger than maxzoom 10",
+		"/layers/2/filter/1: unknown expression operator: gett",
+		"/layers/2/paint/fill-opacity/5: stop inputs should be in strictly ascending order",
+	}, problems)
+
+	require.Equal(t, "/layers/~1a~0b", pointer("/layers", "/a~b"))
+}
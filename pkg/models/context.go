@@ -2,14 +2,60 @@ package models
 
 import (
 	"bytes"
+	"strings"
 )
 
 type StylesConfig struct {
-	BaseResource      string            `yaml:"base-resource"`
-	Default           string            `yaml:"default,omitempty"`
-	AdditionalFormats []Format          `yaml:"additional-formats,omitempty"`
-	AdditionalAssets  []AdditionalAsset `yaml:"additional-assets,omitempty"`
-	StylesMetadata    []StyleMetadata   `yaml:"styles"`
+	BaseResource       string                 `yaml:"base-resource"`
+	Default            string                 `yaml:"default,omitempty"`
+	AdditionalFormats  []Format               `yaml:"additional-formats,omitempty"`
+	AdditionalAssets   []AdditionalAsset      `yaml:"additional-assets,omitempty"`
+	StylesMetadata     []StyleMetadata        `yaml:"styles"`
+	Conformance        bool                   `yaml:"conformance,omitempty"`
+	LandingPage        *LandingPage           `yaml:"landing-page,omitempty"`
+	ServiceDescription bool                   `yaml:"service-description,omitempty"`
+	Collections        []Collection           `yaml:"collections,omitempty"`
+	Publish            PublishRules           `yaml:"publish,omitempty"`
+	LinkChecksums      bool                   `yaml:"link-checksums,omitempty"` // add the SHA-256 checksum of the document to links
+	Environments       map[string]Environment `yaml:"environments,omitempty"`
+	Environment        string                 `yaml:"-"` // the environment the documents are generated for (--environment)
+}
+
+// Environment the url rewrites of the Mapbox stylesheets for an environment (e.g. acc or prod)
+type Environment struct {
+	Rewrites []UrlRewrite `yaml:"rewrites"`
+}
+
+// UrlRewrite replaces the prefix from of the sprite, glyphs and source urls of Mapbox stylesheets with to
+type UrlRewrite struct {
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+}
+
+// RewriteUrl rewrites the url with the first rewrite whose prefix matches, otherwise the url is returned as is
+func (environment Environment) RewriteUrl(url string) string {
+	for _, rewrite := range environment.Rewrites {
+		if strings.HasPrefix(url, rewrite.From) {
+			return rewrite.To + strings.TrimPrefix(url, rewrite.From)
+		}
+	}
+	return url
+}
+
+// Collection publishes a selection of the styles for a dataset collection - OGC API Styles 8.2: /collections/{collectionId}/styles
+type Collection struct {
+	Id      string   `yaml:"id"`
+	Default string   `yaml:"default,omitempty"`
+	Styles  []string `yaml:"styles"`
+}
+
+func (stylesConfig *StylesConfig) GetStyleMetadata(styleId string) (*StyleMetadata, bool) {
+	for i := range stylesConfig.StylesMetadata {
+		if stylesConfig.StylesMetadata[i].Id == styleId {
+			return &stylesConfig.StylesMetadata[i], true
+		}
+	}
+	return nil, false
 }
 
 type AdditionalAsset struct {
@@ -21,4 +67,7 @@ type Document struct {
 	Path      string
 	MediaType MediaType
 	Content   *bytes.Buffer
+	Source    string // the asset the document is read or converted from, empty for rendered documents
+	// ContentEncoding the encoding of a pre-compressed variant of a document, the media type is that of the original
+	ContentEncoding ContentEncoding
 }
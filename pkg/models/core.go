@@ -0,0 +1,13 @@
+package models
+
+// LandingPage based on OGC API Common - Requirement /req/landing-page/root-success
+type LandingPage struct {
+	Title       string  `yaml:"title" json:"title,omitempty"`
+	Description *string `yaml:"description" json:"description,omitempty"`
+	Links       []Link  `yaml:"links" json:"links"` // minimally: self, conformance (when generated) and styles ("rel": "http://www.opengis.net/def/rel/ogc/1.0/styles")
+}
+
+// Conformance based on OGC API Common - Requirement /req/landing-page/conformance-success
+type Conformance struct {
+	ConformsTo []ConformanceClass `json:"conformsTo"`
+}
@@ -8,10 +8,14 @@ import (
 )
 
 const (
+	LandingPageResource   = "index"
+	ConformanceResource   = "conformance"
+	ServiceDescResource   = "api"
 	StylesResource        = "styles"
 	StyleResource         = "styles/%s"
 	StyleMetadataResource = "styles/%s/metadata"
 	ResourceResource      = "resources/%s" // this is not clearly specified in the OGC API Styles spec, taken from the examples
+	CollectionResource    = "collections/%s"
 )
 
 type LinkRelation string
@@ -55,11 +59,17 @@ func (linkRelation LinkRelation) ToPath(identifier string) (string, error) {
 	switch linkRelation {
 	case StylesRelation:
 		return StylesResource, nil
+	case CollectionRelation:
+		return fmt.Sprintf(CollectionResource, identifier), nil
+	case ConformanceRelation:
+		return ConformanceResource, nil
+	case ServiceDescRelation:
+		return ServiceDescResource, nil
 	case StylesheetRelation:
 		return fmt.Sprintf(StyleResource, identifier), nil
 	case DescribedbyRelation:
 		return fmt.Sprintf(StyleMetadataResource, identifier), nil
-	case PreviewRelation, PreloadRelation:
+	case PreviewRelation, PreloadRelation, EnclosureRelation:
 		return fmt.Sprintf(ResourceResource, identifier), nil
 	default:
 		return "", fmt.Errorf("no path known for link relation: %s", linkRelation)
@@ -104,11 +114,17 @@ func (linkRelation *LinkRelation) UnmarshalYAML(unmarshal func(interface{}) erro
 type MediaType string
 
 const (
-	JsonMediaType   MediaType = "application/json"
-	HtmlMediaType   MediaType = "text/html"
-	SldMediaType    MediaType = "application/vnd.ogc.sld+xml"
-	MapboxMediaType MediaType = "application/vnd.mapbox.style+json"
-	PngMediaType    MediaType = "image/png"
+	JsonMediaType       MediaType = "application/json"
+	HtmlMediaType       MediaType = "text/html"
+	SldMediaType        MediaType = "application/vnd.ogc.sld+xml"
+	MapboxMediaType     MediaType = "application/vnd.mapbox.style+json"
+	PngMediaType        MediaType = "image/png"
+	ProtobufMediaType   MediaType = "application/x-protobuf"
+	GeoJsonMediaType    MediaType = "application/geo+json"
+	GeoPackageMediaType MediaType = "application/geopackage+sqlite3"
+
+	OpenApiJsonMediaType MediaType = "application/vnd.oai.openapi+json;version=3.0"
+	OpenApiYamlMediaType MediaType = "application/vnd.oai.openapi;version=3.0"
 
 	mediaTypeSeperator     = ";"
 	mediaTypePartSeperator = "="
@@ -125,15 +141,6 @@ var (
 	knownBaseFormats = []Format{JsonFormat, HtmlFormat, SldFormat, MapboxFormat, PngFormat}
 )
 
-func GetFormat(format string) (Format, bool) {
-	for _, knownFormat := range knownBaseFormats {
-		if knownFormat.Name == format {
-			return knownFormat, true
-		}
-	}
-	return Format{}, false
-}
-
 var versionRegex = regexp.MustCompile(`\d+`)
 
 func (m MediaType) SplitParams() (MediaType, map[string]string) {
@@ -182,6 +189,21 @@ func (f Format) ToQuery() string {
 	return fmt.Sprintf(formatQuery, f.Name)
 }
 
+type ConformanceClass string
+
+// ConformanceClass The conformance classes goas can declare, taken from: OGC API Styles - Annex A and OGC API Common - Annex A
+const (
+	CoreConformance              ConformanceClass = "http://www.opengis.net/spec/ogcapi-styles-1/1.0/conf/core"
+	JsonConformance              ConformanceClass = "http://www.opengis.net/spec/ogcapi-styles-1/1.0/conf/json"
+	HtmlConformance              ConformanceClass = "http://www.opengis.net/spec/ogcapi-styles-1/1.0/conf/html"
+	Sld10Conformance             ConformanceClass = "http://www.opengis.net/spec/ogcapi-styles-1/1.0/conf/sld-10"
+	Sld11Conformance             ConformanceClass = "http://www.opengis.net/spec/ogcapi-styles-1/1.0/conf/sld-11"
+	MapboxStylesConformance      ConformanceClass = "http://www.opengis.net/spec/ogcapi-styles-1/1.0/conf/mapbox-styles"
+	CommonCoreConformance        ConformanceClass = "http://www.opengis.net/spec/ogcapi-common-1/1.0/conf/core"
+	CommonLandingPageConformance ConformanceClass = "http://www.opengis.net/spec/ogcapi-common-1/1.0/conf/landing-page"
+	CommonOas30Conformance       ConformanceClass = "http://www.opengis.net/spec/ogcapi-common-1/1.0/conf/oas30"
+)
+
 type GeometryType string
 type GeometryTypes []GeometryType
 
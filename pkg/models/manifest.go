@@ -0,0 +1,30 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// ManifestPath the path of the manifest of the generated documents
+const ManifestPath = "_goas/manifest.json"
+
+// Manifest the generated documents, for tooling that needs to know what was published without listing the destination
+type Manifest struct {
+	Files []ManifestFile `json:"files"`
+}
+
+// ManifestFile a generated document
+type ManifestFile struct {
+	Path            string          `json:"path"`
+	MediaType       MediaType       `json:"mediaType,omitempty"`
+	ContentEncoding ContentEncoding `json:"contentEncoding,omitempty"`
+	Length          int             `json:"length"`
+	Sha256          string          `json:"sha256"`
+	Source          string          `json:"source,omitempty"` // the asset the document is read or converted from
+}
+
+// NewManifestFile the manifest entry of a document with the given content
+func NewManifestFile(path string, mediaType MediaType, content []byte) ManifestFile {
+	checksum := sha256.Sum256(content)
+	return ManifestFile{Path: path, MediaType: mediaType, Length: len(content), Sha256: hex.EncodeToString(checksum[:])}
+}
@@ -0,0 +1,55 @@
+package models
+
+// OpenApi a subset of the OpenAPI 3.0 document - https://spec.openapis.org/oas/v3.0.3 - as needed to describe the
+// static documents generated by goas
+type OpenApi struct {
+	OpenApi string                     `json:"openapi" yaml:"openapi"`
+	Info    OpenApiInfo                `json:"info" yaml:"info"`
+	Servers []OpenApiServer            `json:"servers,omitempty" yaml:"servers,omitempty"`
+	Paths   map[string]OpenApiPathItem `json:"paths" yaml:"paths"`
+}
+
+type OpenApiInfo struct {
+	Title       string  `json:"title" yaml:"title"`
+	Description *string `json:"description,omitempty" yaml:"description,omitempty"`
+	Version     string  `json:"version" yaml:"version"`
+}
+
+type OpenApiServer struct {
+	Url string `json:"url" yaml:"url"`
+}
+
+type OpenApiPathItem struct {
+	Get *OpenApiOperation `json:"get,omitempty" yaml:"get,omitempty"`
+}
+
+type OpenApiOperation struct {
+	Summary     string                     `json:"summary,omitempty" yaml:"summary,omitempty"`
+	OperationId string                     `json:"operationId,omitempty" yaml:"operationId,omitempty"`
+	Parameters  []OpenApiParameter         `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	Responses   map[string]OpenApiResponse `json:"responses" yaml:"responses"`
+}
+
+type OpenApiParameter struct {
+	Name        string `json:"name" yaml:"name"`
+	In          string `json:"in" yaml:"in"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Required    bool   `json:"required,omitempty" yaml:"required,omitempty"`
+	Schema      Schema `json:"schema" yaml:"schema"`
+}
+
+type OpenApiResponse struct {
+	Description string                       `json:"description" yaml:"description"`
+	Content     map[MediaType]OpenApiContent `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+type OpenApiContent struct {
+	Schema Schema `json:"schema" yaml:"schema"`
+}
+
+// Schema based on the OpenAPI 3.0 Schema Object - https://spec.openapis.org/oas/v3.0.3#schema-object
+type Schema struct {
+	Type   string   `json:"type,omitempty" yaml:"type,omitempty"`
+	Format string   `json:"format,omitempty" yaml:"format,omitempty"`
+	Enum   []string `json:"enum,omitempty" yaml:"enum,omitempty"`
+}
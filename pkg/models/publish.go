@@ -0,0 +1,130 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ContentEncoding the encoding of a pre-compressed variant of a document
+type ContentEncoding string
+
+const (
+	GzipEncoding   ContentEncoding = "gzip"
+	BrotliEncoding ContentEncoding = "br"
+)
+
+// Extension the extension appended to the path of a document for its pre-compressed variant
+func (encoding ContentEncoding) Extension() string {
+	switch encoding {
+	case GzipEncoding:
+		return ".gz"
+	case BrotliEncoding:
+		return ".br"
+	}
+	return ""
+}
+
+// PublishRule the headers and metadata of the published documents with a path matching the pattern. The documents are
+// published with the settings of all matching rules, in order, so later rules override (or add to) earlier rules.
+type PublishRule struct {
+	Path         string            `yaml:"path"` // glob pattern, * matches within a path segment and ** any number of segments
+	CacheControl string            `yaml:"cache-control,omitempty"`
+	Compress     []ContentEncoding `yaml:"compress,omitempty"` // pre-compressed variants written next to the document
+	Metadata     map[string]string `yaml:"metadata,omitempty"`
+	Tags         map[string]string `yaml:"tags,omitempty"`
+}
+
+// PublishRules the publish rules of the config
+type PublishRules []PublishRule
+
+// ObjectHeaders the headers and metadata a document is published with on object storage
+type ObjectHeaders struct {
+	CacheControl    string
+	ContentEncoding ContentEncoding
+	Compress        []ContentEncoding
+	Metadata        map[string]string
+	Tags            map[string]string
+}
+
+// Headers the headers of the document (or pre-compressed variant of a document) at the path
+func (rules PublishRules) Headers(path string) ObjectHeaders {
+	headers := rules.match(path)
+	for _, encoding := range []ContentEncoding{GzipEncoding, BrotliEncoding} {
+		if !strings.HasSuffix(path, encoding.Extension()) {
+			continue
+		}
+		original := rules.match(strings.TrimSuffix(path, encoding.Extension()))
+		if original.compresses(encoding) {
+			original.ContentEncoding = encoding
+			original.Compress = nil
+			return original
+		}
+	}
+	return headers
+}
+
+func (rules PublishRules) match(path string) ObjectHeaders {
+	var headers ObjectHeaders
+	for _, rule := range rules {
+		if !MatchPath(rule.Path, path) {
+			continue
+		}
+		if rule.CacheControl != "" {
+			headers.CacheControl = rule.CacheControl
+		}
+		if rule.Compress != nil {
+			headers.Compress = rule.Compress
+		}
+		headers.Metadata = merge(headers.Metadata, rule.Metadata)
+		headers.Tags = merge(headers.Tags, rule.Tags)
+	}
+	return headers
+}
+
+func (headers ObjectHeaders) compresses(encoding ContentEncoding) bool {
+	for _, compress := range headers.Compress {
+		if compress == encoding {
+			return true
+		}
+	}
+	return false
+}
+
+func merge(values map[string]string, overrides map[string]string) map[string]string {
+	if len(overrides) == 0 {
+		return values
+	}
+	merged := make(map[string]string)
+	for key, value := range values {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged
+}
+
+// MatchPath whether the path matches the glob pattern, * matches within a path segment and ** any number of segments
+func MatchPath(pattern string, path string) bool {
+	var expression strings.Builder
+	expression.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expression.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expression.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expression.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expression.WriteString("[^/]")
+		default:
+			expression.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	expression.WriteString("$")
+	matched, err := regexp.MatchString(expression.String(), strings.TrimPrefix(path, "/"))
+	return err == nil && matched
+}
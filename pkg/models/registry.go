@@ -0,0 +1,80 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Renderer renders an object (e.g. Styles or StyleMetadata) to a Document at the given path
+type Renderer func(obj interface{}, path string) (*Document, error)
+
+type registeredRenderer struct {
+	format   Format
+	renderer Renderer
+}
+
+var (
+	renderersLock sync.RWMutex
+	renderers     = make(map[string]registeredRenderer)
+)
+
+// RegisterRenderer makes a Renderer available for a Format, so it can be selected by its name (e.g. with --formats).
+// Registering a format name twice is an error.
+func RegisterRenderer(format Format, renderer Renderer) error {
+	if format.Name == "" {
+		return fmt.Errorf("cannot register a renderer for a format without a name")
+	}
+	if renderer == nil {
+		return fmt.Errorf("cannot register an empty renderer for format: %s", format.Name)
+	}
+	renderersLock.Lock()
+	defer renderersLock.Unlock()
+	if _, ok := renderers[format.Name]; ok {
+		return fmt.Errorf("a renderer for format: %s is already registered", format.Name)
+	}
+	renderers[format.Name] = registeredRenderer{format, renderer}
+	return nil
+}
+
+// unregisterRenderer removes the Renderer registered for the name of a format, so tests leave the registry as it was
+func unregisterRenderer(name string) {
+	renderersLock.Lock()
+	defer renderersLock.Unlock()
+	delete(renderers, name)
+}
+
+// GetRenderer returns the Renderer registered for the name of the format
+func GetRenderer(format Format) (Renderer, bool) {
+	renderersLock.RLock()
+	defer renderersLock.RUnlock()
+	registered, ok := renderers[format.Name]
+	return registered.renderer, ok
+}
+
+// GetFormat returns the registered render Format with the given name
+func GetFormat(format string) (Format, bool) {
+	renderersLock.RLock()
+	defer renderersLock.RUnlock()
+	registered, ok := renderers[format]
+	return registered.format, ok
+}
+
+// RenderFormats returns all formats a renderer is registered for, sorted by name
+func RenderFormats() (formats []Format) {
+	renderersLock.RLock()
+	defer renderersLock.RUnlock()
+	for _, registered := range renderers {
+		formats = append(formats, registered.format)
+	}
+	sort.Slice(formats, func(i, j int) bool { return formats[i].Name < formats[j].Name })
+	return formats
+}
+
+// RenderFormatNames returns the names of all formats a renderer is registered for, sorted
+func RenderFormatNames() (names []string) {
+	for _, format := range RenderFormats() {
+		names = append(names, format.Name)
+	}
+	return names
+}
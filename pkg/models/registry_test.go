@@ -0,0 +1,30 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterRenderer(t *testing.T) {
+	format := Format{MediaType: "text/plain", Name: "test-text", Extension: "txt"}
+	err := RegisterRenderer(format, func(obj interface{}, path string) (*Document, error) {
+		return &Document{Path: path, MediaType: format.MediaType, Content: bytes.NewBufferString(fmt.Sprint(obj))}, nil
+	})
+	require.Nil(t, err)
+	t.Cleanup(func() { unregisterRenderer(format.Name) })
+
+	registered, ok := GetFormat("test-text")
+	require.True(t, ok)
+	require.Equal(t, format, registered)
+	require.Contains(t, RenderFormatNames(), "test-text")
+
+	renderer, ok := GetRenderer(format)
+	require.True(t, ok)
+	result, err := renderer(struct{ Test string }{"test"}, "test.txt")
+	require.Nil(t, err)
+	require.Equal(t, "test.txt", result.Path)
+	require.Equal(t, "{test}", result.Content.String())
+}
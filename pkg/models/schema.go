@@ -0,0 +1,20 @@
+package models
+
+// SchemaTypes the types of an OpenAPI 3.0 Schema
+var SchemaTypes = []string{"string", "number", "integer", "boolean", "array", "object"}
+
+// PropertiesSchema the schema of the properties of the features of a layer, an OpenAPI 3.0 Schema object (a subset of
+// JSON Schema) - https://spec.openapis.org/oas/v3.0.3#schema-object
+type PropertiesSchema struct {
+	Title       string                       `yaml:"title" json:"title,omitempty"`
+	Description string                       `yaml:"description" json:"description,omitempty"`
+	Type        string                       `yaml:"type" json:"type,omitempty"`
+	Format      string                       `yaml:"format" json:"format,omitempty"` // e.g. int32, double or date-time
+	Nullable    bool                         `yaml:"nullable" json:"nullable,omitempty"`
+	Enum        []interface{}                `yaml:"enum" json:"enum,omitempty"`
+	Minimum     *float64                     `yaml:"minimum" json:"minimum,omitempty"`
+	Maximum     *float64                     `yaml:"maximum" json:"maximum,omitempty"`
+	Properties  map[string]*PropertiesSchema `yaml:"properties" json:"properties,omitempty"` // the properties of an object
+	Required    []string                     `yaml:"required" json:"required,omitempty"`
+	Items       *PropertiesSchema            `yaml:"items" json:"items,omitempty"` // the items of an array
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"strings"
+
+	"github.com/pdok/goas/pkg/mapbox"
 )
 
 // Styles based on OGC API Styles Requirement 3B -  http://www.opengis.net/def/rel/ogc/1.0/styles: Refers to a collection of styles.
@@ -21,34 +23,73 @@ type Style struct {
 
 // StyleMetadata based on OGC API Styles Requirement 7B
 type StyleMetadata struct {
-	Id             string       `yaml:"id" json:"id"`
-	Title          *string      `yaml:"title" json:"title,omitempty"`
-	Description    *string      `yaml:"description" json:"description,omitempty"`
-	Keywords       []string     `yaml:"keywords" json:"keywords,omitempty"`
-	PointOfContact *string      `yaml:"point-of-contact" json:"pointOfContact,omitempty"`
-	License        *string      `yaml:"license" json:"license,omitempty"`
-	Created        *string      `yaml:"created" json:"created,omitempty"`
-	Updated        *string      `yaml:"updated" json:"updated,omitempty"`
-	Scope          *string      `yaml:"scope" json:"scope,omitempty"`
-	Version        *string      `yaml:"version" json:"version,omitempty"`
-	Stylesheets    []StyleSheet `yaml:"stylesheets" json:"stylesheets,omitempty"`
-	Layers         []struct {
-		Id           string        `yaml:"id" json:"id"`
-		GeometryType *GeometryType `yaml:"type" json:"geometryType,omitempty"`
-		SampleData   Link          `yaml:"sample-data" json:"sampleData,omitempty"`
-		// TODO: the Properties schema is a stub and can be an implementation of: https://raw.githubusercontent.com/OAI/OpenAPI-Specification/master/schemas/v3.0/schema.json#/definitions/Schema
-		PropertiesSchema *PropertiesSchema `yaml:"properties-schema" json:"propertiesSchema,omitempty"`
-	} `yaml:"layers" json:"layers,omitempty"`
-	Links []Link `yaml:"links" json:"links,omitempty"`
+	Id             string         `yaml:"id" json:"id"`
+	Title          *string        `yaml:"title" json:"title,omitempty"`
+	Description    *string        `yaml:"description" json:"description,omitempty"`
+	Keywords       []string       `yaml:"keywords" json:"keywords,omitempty"`
+	PointOfContact *string        `yaml:"point-of-contact" json:"pointOfContact,omitempty"`
+	License        *string        `yaml:"license" json:"license,omitempty"`
+	Created        *string        `yaml:"created" json:"created,omitempty"`
+	Updated        *string        `yaml:"updated" json:"updated,omitempty"`
+	Scope          *string        `yaml:"scope" json:"scope,omitempty"`
+	Version        *string        `yaml:"version" json:"version,omitempty"`
+	Stylesheets    []StyleSheet   `yaml:"stylesheets" json:"stylesheets,omitempty"`
+	Layers         []StyleLayer   `yaml:"layers" json:"layers,omitempty"`
+	DeriveLayers   bool           `yaml:"derive-layers" json:"-"` // derive the layers from the native stylesheet
+	Links          []Link         `yaml:"links" json:"links,omitempty"`
+	Sprite         *SpriteOptions `yaml:"sprite" json:"-"` // generate the sprite of the Mapbox stylesheets from a directory of icons
+	Glyphs         *GlyphsOptions `yaml:"glyphs" json:"-"` // generate the glyphs of the Mapbox stylesheets from TTF or OTF fonts
+}
+
+// SpriteOptions the icons the sprite sheets (1x and 2x) of the Mapbox stylesheets of a style are generated from
+type SpriteOptions struct {
+	Icons string `yaml:"icons"` // directory (in the asset dir) with the PNG icons, name.png and optionally name@2x.png
+}
+
+// StyleLayer a layer of the data the style renders, based on OGC API Styles Requirement 7B
+type StyleLayer struct {
+	Id               string            `yaml:"id" json:"id"`
+	GeometryType     *GeometryType     `yaml:"type" json:"geometryType,omitempty"`
+	SampleData       *Link             `yaml:"sample-data" json:"sampleData,omitempty"`
+	PropertiesSchema *PropertiesSchema `yaml:"properties-schema" json:"propertiesSchema,omitempty"`
+}
+
+// GlyphsOptions the fonts the glyph ranges ({fontstack}/{range}.pbf) of the Mapbox stylesheets of a style are generated from
+type GlyphsOptions struct {
+	Fonts []GlyphsFont `yaml:"fonts"`
+}
+
+// GlyphsFont a TTF or OTF font and the name the text-font of layers refers to it with
+type GlyphsFont struct {
+	AssetFilename string  `yaml:"asset-filename"`
+	Name          *string `yaml:"name"` // defaults to the full name of the font, e.g. "Noto Sans Regular"
 }
 
 // StyleSheet based on OGC API Styles Requirement 7B
 type StyleSheet struct {
-	Title         *string `yaml:"title" json:"title,omitempty"`
-	Version       *string `yaml:"version" json:"version,omitempty"`
-	Specification *string `yaml:"specification" json:"specification,omitempty"`
-	Native        *bool   `yaml:"native" json:"native,omitempty"`
-	Link          Link    `yaml:"link" json:"link"`
+	Title         *string        `yaml:"title" json:"title,omitempty"`
+	Version       *string        `yaml:"version" json:"version,omitempty"`
+	Specification *string        `yaml:"specification" json:"specification,omitempty"`
+	Native        *bool          `yaml:"native" json:"native,omitempty"`
+	Link          Link           `yaml:"link" json:"link"`
+	GenerateFrom  *string        `yaml:"generate-from" json:"-"` // the format name (e.g. mapbox) of another stylesheet of the style to convert into this stylesheet, instead of an asset-filename
+	Mapbox        *MapboxOptions `yaml:"mapbox" json:"-"`        // the sources of a Mapbox stylesheet generated from an SLD
+}
+
+// MapboxOptions the sources (and sprite and glyphs) of a Mapbox stylesheet generated from an SLD, and which source
+// (layer) the layers generated for each NamedLayer of the SLD are rendered from
+type MapboxOptions struct {
+	Sprite  *string                  `yaml:"sprite"`
+	Glyphs  *string                  `yaml:"glyphs"`
+	Sources map[string]mapbox.Source `yaml:"sources"`
+	Layers  []NamedLayerSource       `yaml:"layers"`
+}
+
+// NamedLayerSource the source and source-layer of the Mapbox layers generated for a NamedLayer of an SLD
+type NamedLayerSource struct {
+	NamedLayer  string `yaml:"named-layer"`
+	Source      string `yaml:"source"`       // defaults to the only source
+	SourceLayer string `yaml:"source-layer"` // defaults to the name of the NamedLayer
 }
 
 // Link based on OGC API Features - http://schemas.opengis.net/ogcapi/features/part1/1.0/openapi/schemas/link.yaml - as referenced by OGC API Styles Requirements 3B and 7B
@@ -59,7 +100,8 @@ type Link struct {
 	Type          *MediaType   `yaml:"type" json:"type,omitempty"`
 	Title         *string      `yaml:"title" json:"title,omitempty"`
 	Hreflang      *string      `yaml:"hreflang" json:"hreflang,omitempty"`
-	Length        *int         `yaml:"length" json:"length,omitempty"`
+	Length        *int         `yaml:"length" json:"length,omitempty"` // set to the length of the generated document
+	Sha256        *string      `yaml:"-" json:"sha256,omitempty"`      // the checksum of the generated document, with link-checksums
 }
 
 type Format struct {
@@ -114,5 +156,3 @@ func (link *Link) UpdateHref(baseResource string, styleId string, additionalForm
 	link.Href = url
 	return nil
 }
-
-type PropertiesSchema struct{} // TODO implement later
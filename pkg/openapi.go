@@ -0,0 +1,195 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pdok/goas/pkg/models"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	openApiVersion  = "3.0.3"
+	apiVersion      = "1.0.0"
+	defaultApiTitle = "OGC API Styles"
+)
+
+// generateServiceDescription renders an OpenAPI 3.0 document (as json and yaml) describing the paths of the given documents
+func generateServiceDescription(stylesConfig *models.StylesConfig, formats []models.Format, documents []models.Document) ([]models.Document, error) {
+	openApi := buildOpenApi(stylesConfig, formats, documents)
+
+	jsonContent := new(bytes.Buffer)
+	enc := json.NewEncoder(jsonContent)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(openApi)
+	if err != nil {
+		return nil, fmt.Errorf("error: %v, could not render service description to json", err)
+	}
+	yamlContent, err := yaml.Marshal(openApi)
+	if err != nil {
+		return nil, fmt.Errorf("error: %v, could not render service description to yaml", err)
+	}
+	return []models.Document{
+		{Path: models.ServiceDescResource + ".json", MediaType: models.OpenApiJsonMediaType, Content: jsonContent},
+		{Path: models.ServiceDescResource + ".yaml", MediaType: models.OpenApiYamlMediaType, Content: bytes.NewBuffer(yamlContent)},
+	}, nil
+}
+
+func buildOpenApi(stylesConfig *models.StylesConfig, formats []models.Format, documents []models.Document) models.OpenApi {
+	info := models.OpenApiInfo{Title: defaultApiTitle, Version: apiVersion}
+	if stylesConfig.LandingPage != nil {
+		info.Title = stylesConfig.LandingPage.Title
+		info.Description = stylesConfig.LandingPage.Description
+	}
+	paths := make(map[string]models.OpenApiPathItem)
+	if stylesConfig.LandingPage != nil {
+		paths["/"] = getOperation("getLandingPage", "Landing page", renderedResponse(formats), formatParameter(formatNames(formats)))
+	}
+	if stylesConfig.Conformance {
+		paths["/"+models.ConformanceResource] = getOperation("getConformanceDeclaration", "Conformance declaration",
+			renderedResponse(formats), formatParameter(formatNames(formats)))
+	}
+	paths["/"+models.ServiceDescResource] = getOperation("getServiceDescription", "This API definition",
+		response(models.OpenApiJsonMediaType, models.OpenApiYamlMediaType), formatParameter([]string{"json", "yaml"}))
+
+	var styleIds []string
+	for _, styleMetadata := range stylesConfig.StylesMetadata {
+		styleIds = append(styleIds, styleMetadata.Id)
+	}
+	styleIdParameter := pathParameter("styleId", "Local identifier of a style", styleIds)
+	paths["/"+models.StylesResource] = getOperation("getStyles", "Lists the available styles",
+		renderedResponse(formats), formatParameter(formatNames(formats)))
+	stylesheetFormats, stylesheetMediaTypes := stylesheetFormats(stylesConfig)
+	paths["/"+fmt.Sprintf(models.StyleResource, "{styleId}")] = getOperation("getStyle", "Fetch a style",
+		response(stylesheetMediaTypes...), styleIdParameter, formatParameter(stylesheetFormats))
+	paths["/"+fmt.Sprintf(models.StyleMetadataResource, "{styleId}")] = getOperation("getStyleMetadata", "Fetch the metadata about a style",
+		renderedResponse(formats), styleIdParameter, formatParameter(formatNames(formats)))
+
+	if stylesConfig.Collections != nil {
+		var collectionIds []string
+		for _, collection := range stylesConfig.Collections {
+			collectionIds = append(collectionIds, collection.Id)
+		}
+		collectionIdParameter := pathParameter("collectionId", "Local identifier of a collection", collectionIds)
+		collectionPath := "/" + fmt.Sprintf(models.CollectionResource, "{collectionId}") + "/"
+		paths[collectionPath+models.StylesResource] = getOperation("getCollectionStyles", "Lists the available styles of a collection",
+			renderedResponse(formats), collectionIdParameter, formatParameter(formatNames(formats)))
+		paths[collectionPath+fmt.Sprintf(models.StyleResource, "{styleId}")] = getOperation("getCollectionStyle", "Fetch a style of a collection",
+			response(stylesheetMediaTypes...), collectionIdParameter, styleIdParameter, formatParameter(stylesheetFormats))
+		paths[collectionPath+fmt.Sprintf(models.StyleMetadataResource, "{styleId}")] = getOperation("getCollectionStyleMetadata", "Fetch the metadata about a style of a collection",
+			renderedResponse(formats), collectionIdParameter, styleIdParameter, formatParameter(formatNames(formats)))
+	}
+
+	resourceIds, resourceMediaTypes := resources(documents)
+	if resourceIds != nil {
+		paths["/"+fmt.Sprintf(models.ResourceResource, "{resourceId}")] = getOperation("getResource", "Fetch a symbol resource",
+			response(resourceMediaTypes...), pathParameter("resourceId", "Local identifier of a symbol resource", resourceIds))
+	}
+
+	return models.OpenApi{
+		OpenApi: openApiVersion,
+		Info:    info,
+		Servers: []models.OpenApiServer{{Url: stylesConfig.BaseResource}},
+		Paths:   paths,
+	}
+}
+
+func getOperation(operationId string, summary string, responses map[string]models.OpenApiResponse, parameters ...models.OpenApiParameter) models.OpenApiPathItem {
+	return models.OpenApiPathItem{Get: &models.OpenApiOperation{
+		Summary: summary, OperationId: operationId, Parameters: parameters, Responses: responses,
+	}}
+}
+
+func pathParameter(name string, description string, values []string) models.OpenApiParameter {
+	return models.OpenApiParameter{
+		Name: name, In: "path", Description: description, Required: true,
+		Schema: models.Schema{Type: "string", Enum: values},
+	}
+}
+
+func formatParameter(formats []string) models.OpenApiParameter {
+	return models.OpenApiParameter{
+		Name: "f", In: "query", Description: "The format of the response",
+		Schema: models.Schema{Type: "string", Enum: formats},
+	}
+}
+
+func renderedResponse(formats []models.Format) map[string]models.OpenApiResponse {
+	var mediaTypes []models.MediaType
+	for _, format := range formats {
+		mediaTypes = append(mediaTypes, format.MediaType)
+	}
+	return response(mediaTypes...)
+}
+
+func response(mediaTypes ...models.MediaType) map[string]models.OpenApiResponse {
+	content := make(map[models.MediaType]models.OpenApiContent)
+	for _, mediaType := range mediaTypes {
+		content[mediaType] = models.OpenApiContent{Schema: mediaTypeSchema(mediaType)}
+	}
+	return map[string]models.OpenApiResponse{
+		"200": {Description: "successful operation", Content: content},
+		"404": {Description: "the requested resource does not exist"},
+	}
+}
+
+func mediaTypeSchema(mediaType models.MediaType) models.Schema {
+	root, _ := mediaType.SplitParams()
+	switch {
+	case root == models.JsonMediaType || strings.HasSuffix(string(root), "+json"):
+		return models.Schema{Type: "object"}
+	case strings.HasPrefix(string(root), "image/"):
+		return models.Schema{Type: "string", Format: "binary"}
+	default:
+		return models.Schema{Type: "string"}
+	}
+}
+
+func formatNames(formats []models.Format) (names []string) {
+	for _, format := range formats {
+		names = append(names, format.Name)
+	}
+	return names
+}
+
+// stylesheetFormats returns the (versioned) format names and media types of all configured stylesheets
+func stylesheetFormats(stylesConfig *models.StylesConfig) (names []string, mediaTypes []models.MediaType) {
+	seen := make(map[string]bool)
+	for _, styleMetadata := range stylesConfig.StylesMetadata {
+		for _, stylesheet := range styleMetadata.Stylesheets {
+			if stylesheet.Link.Type == nil {
+				continue
+			}
+			format := stylesheet.Link.Type.ToFormat(stylesConfig.AdditionalFormats, true)
+			if format.Name == "" || seen[format.Name] {
+				continue
+			}
+			seen[format.Name] = true
+			names = append(names, format.Name)
+			mediaTypes = append(mediaTypes, *stylesheet.Link.Type)
+		}
+	}
+	return names, mediaTypes
+}
+
+// resources returns the sorted ids and the media types of all documents written as resource
+func resources(documents []models.Document) (ids []string, mediaTypes []models.MediaType) {
+	resourcePrefix := fmt.Sprintf(models.ResourceResource, "")
+	seen := make(map[models.MediaType]bool)
+	for _, document := range documents {
+		if !strings.HasPrefix(document.Path, resourcePrefix) {
+			continue
+		}
+		ids = append(ids, strings.TrimPrefix(document.Path, resourcePrefix))
+		if !seen[document.MediaType] {
+			seen[document.MediaType] = true
+			mediaTypes = append(mediaTypes, document.MediaType)
+		}
+	}
+	sort.Strings(ids)
+	return ids, mediaTypes
+}
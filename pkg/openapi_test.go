@@ -0,0 +1,69 @@
+package pkg
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pdok/goas/pkg/models"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestGenerateServiceDescription(t *testing.T) {
+	config, _ := ParseConfig("../examples/config.yaml")
+	documents, err := GenerateDocuments(config, "../examples/assets", []models.Format{models.JsonFormat, models.HtmlFormat})
+	require.Nil(t, err)
+
+	var jsonDocument, yamlDocument *models.Document
+	for i := range documents {
+		switch documents[i].Path {
+		case "api.json":
+			jsonDocument = &documents[i]
+		case "api.yaml":
+			yamlDocument = &documents[i]
+		}
+	}
+	require.NotNil(t, jsonDocument)
+	require.NotNil(t, yamlDocument)
+	require.Equal(t, models.OpenApiJsonMediaType, jsonDocument.MediaType)
+	require.Equal(t, models.OpenApiYamlMediaType, yamlDocument.MediaType)
+
+	var fromJson, fromYaml models.OpenApi
+	require.Nil(t, json.Unmarshal(jsonDocument.Content.Bytes(), &fromJson))
+	require.Nil(t, yaml.Unmarshal(yamlDocument.Content.Bytes(), &fromYaml))
+	require.Equal(t, fromJson, fromYaml)
+
+	require.Equal(t, "3.0.3", fromJson.OpenApi)
+	require.Equal(t, "Example styles", fromJson.Info.Title)
+	require.Equal(t, "https://example.org/catalog/1.0", fromJson.Servers[0].Url)
+	var paths []string
+	for path := range fromJson.Paths {
+		paths = append(paths, path)
+	}
+	require.ElementsMatch(t, []string{"/", "/api", "/conformance", "/styles", "/styles/{styleId}",
+		"/styles/{styleId}/metadata", "/resources/{resourceId}", "/collections/{collectionId}/styles",
+		"/collections/{collectionId}/styles/{styleId}", "/collections/{collectionId}/styles/{styleId}/metadata"}, paths)
+
+	style := fromJson.Paths["/styles/{styleId}"].Get
+	require.Equal(t, []string{"night"}, style.Parameters[0].Schema.Enum)
+	require.Equal(t, []string{"mapbox", "sld10", "custom"}, style.Parameters[1].Schema.Enum)
+	require.Contains(t, style.Responses["200"].Content, models.MediaType("application/vnd.ogc.sld+xml;version=1.0"))
+
+	resource := fromJson.Paths["/resources/{resourceId}"].Get
+	require.Equal(t, []string{"thumbnail.png"}, resource.Parameters[0].Schema.Enum)
+}
+
+func TestGenerateServiceDescriptionMinimalConfig(t *testing.T) {
+	config, _ := ParseConfig("../examples/minimal_config.yaml")
+	documents, err := generateServiceDescription(config, []models.Format{models.JsonFormat}, nil)
+	require.Nil(t, err)
+
+	var openApi models.OpenApi
+	require.Nil(t, json.Unmarshal(documents[0].Content.Bytes(), &openApi))
+	require.Equal(t, "OGC API Styles", openApi.Info.Title)
+	require.NotContains(t, openApi.Paths, "/")
+	require.NotContains(t, openApi.Paths, "/conformance")
+	require.NotContains(t, openApi.Paths, "/resources/{resourceId}")
+	require.NotContains(t, openApi.Paths, "/collections/{collectionId}/styles")
+	require.Contains(t, openApi.Paths, "/styles")
+}
@@ -0,0 +1,56 @@
+package pkg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/andybalholm/brotli"
+	"github.com/pdok/goas/pkg/models"
+)
+
+// CompressDocuments adds the pre-compressed variants (e.g. styles.json.gz) of the documents the publish rules ask for.
+// A variant has the media type of the document and its path is the path of the document with the extension of the
+// encoding. The compression is deterministic, so unchanged documents result in unchanged variants.
+func CompressDocuments(rules models.PublishRules, documents []models.Document) ([]models.Document, error) {
+	compressed := append([]models.Document(nil), documents...)
+	for _, document := range documents {
+		for _, encoding := range rules.Headers(document.Path).Compress {
+			content, err := compress(encoding, document.Content.Bytes())
+			if err != nil {
+				return nil, fmt.Errorf("error: %s, could not compress %s with %s", err, document.Path, encoding)
+			}
+			compressed = append(compressed, models.Document{
+				Path:            document.Path + encoding.Extension(),
+				MediaType:       document.MediaType,
+				Content:         content,
+				Source:          document.Source,
+				ContentEncoding: encoding,
+			})
+		}
+	}
+	return compressed, nil
+}
+
+func compress(encoding models.ContentEncoding, content []byte) (*bytes.Buffer, error) {
+	var compressed bytes.Buffer
+	var writer io.WriteCloser
+	switch encoding {
+	case models.GzipEncoding:
+		writer, _ = gzip.NewWriterLevel(&compressed, gzip.BestCompression)
+	case models.BrotliEncoding:
+		writer = brotli.NewWriterLevel(&compressed, brotli.BestCompression)
+	default:
+		return nil, fmt.Errorf("unknown encoding")
+	}
+	_, err := writer.Write(content)
+	if err != nil {
+		return nil, err
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+	return &compressed, nil
+}
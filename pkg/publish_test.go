@@ -0,0 +1,75 @@
+package pkg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/pdok/goas/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchPath(t *testing.T) {
+	assert.True(t, models.MatchPath("styles.json", "styles.json"))
+	assert.False(t, models.MatchPath("styles.json", "styles/night.json"))
+	assert.True(t, models.MatchPath("styles/*.mapbox.json", "styles/night.mapbox.json"))
+	assert.False(t, models.MatchPath("styles/*", "styles/night/metadata.json"))
+	assert.True(t, models.MatchPath("styles/**", "styles/night/metadata.json"))
+	assert.True(t, models.MatchPath("**/metadata.json", "metadata.json"))
+	assert.True(t, models.MatchPath("**/metadata.json", "collections/daraa/styles/night/metadata.json"))
+	assert.True(t, models.MatchPath("**", "index.json"))
+}
+
+func TestPublishRulesHeaders(t *testing.T) {
+	config, err := ParseConfig("../examples/config.yaml")
+	require.Nil(t, err)
+
+	assert.Equal(t, models.ObjectHeaders{
+		CacheControl: "public, max-age=60",
+		Compress:     []models.ContentEncoding{models.GzipEncoding, models.BrotliEncoding},
+		Metadata:     map[string]string{"publisher": "goas"},
+	}, config.Publish.Headers("styles.json"))
+	assert.Equal(t, models.ObjectHeaders{
+		CacheControl:    "public, max-age=60",
+		ContentEncoding: models.BrotliEncoding,
+		Metadata:        map[string]string{"publisher": "goas"},
+	}, config.Publish.Headers("styles.json.br"))
+	assert.Equal(t, "public, max-age=31536000, immutable", config.Publish.Headers("resources/thumbnail.png").CacheControl)
+	assert.Equal(t, models.ContentEncoding(""), config.Publish.Headers("resources/archive.gz").ContentEncoding, "only variants are encoded")
+}
+
+func TestCompressDocuments(t *testing.T) {
+	config, _ := ParseConfig("../examples/config.yaml")
+	documents := []models.Document{
+		{Path: "styles.json", MediaType: models.JsonMediaType, Content: bytes.NewBufferString(`{"styles": []}`)},
+		{Path: "styles.html", MediaType: models.HtmlMediaType, Content: bytes.NewBufferString("<html></html>")},
+	}
+
+	compressed, err := CompressDocuments(config.Publish, documents)
+	require.Nil(t, err)
+	require.Len(t, compressed, 4)
+	assert.Equal(t, "styles.json.gz", compressed[2].Path)
+	assert.Equal(t, models.JsonMediaType, compressed[2].MediaType)
+	assert.Equal(t, models.GzipEncoding, compressed[2].ContentEncoding)
+	gzipReader, err := gzip.NewReader(bytes.NewReader(compressed[2].Content.Bytes()))
+	require.Nil(t, err)
+	content, _ := io.ReadAll(gzipReader)
+	assert.Equal(t, `{"styles": []}`, string(content))
+	assert.Equal(t, "styles.json.br", compressed[3].Path)
+	content, _ = io.ReadAll(brotli.NewReader(bytes.NewReader(compressed[3].Content.Bytes())))
+	assert.Equal(t, `{"styles": []}`, string(content))
+
+	again, _ := CompressDocuments(config.Publish, documents)
+	assert.Equal(t, compressed[2].Content.Bytes(), again[2].Content.Bytes(), "compression is deterministic")
+}
+
+func TestValidateInvalidPublishRules(t *testing.T) {
+	err := validatePublishRules(models.PublishRules{
+		{Path: "styles.json", Compress: []models.ContentEncoding{"zstd"}, Metadata: map[string]string{"x-team": "geo"}},
+		{CacheControl: "no-cache"},
+	})
+	assert.EqualError(t, err, "publish rules incorrect; metadata key x-team of publish rule styles.json should only contain letters, digits and underscores, publish rule 2 has no path, unknown compression zstd of publish rule styles.json, choose from: [gzip,br]")
+}
@@ -2,13 +2,41 @@ package pkg
 
 import (
 	"bytes"
+	"embed"
 	"encoding/json"
 	"fmt"
 	"github.com/pdok/goas/pkg/models"
+	"html/template"
 	"strings"
 )
 
-type Renderer func(obj interface{}, path string) (*models.Document, error)
+type Renderer = models.Renderer
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+var htmlTemplates = template.Must(template.New("").Funcs(template.FuncMap{
+	"htmlHref":  htmlHref,
+	"isPreview": isPreview,
+}).ParseFS(templateFiles, "templates/*.html"))
+
+func init() {
+	MustRegisterRenderer(models.JsonFormat, jsonRenderer)
+	MustRegisterRenderer(models.HtmlFormat, htmlRenderer)
+}
+
+// RegisterRenderer registers a Renderer for an output format, e.g. from the init function of a package importing pkg.
+// Registered formats can be selected with the --formats flag.
+func RegisterRenderer(format models.Format, renderer Renderer) error {
+	return models.RegisterRenderer(format, renderer)
+}
+
+func MustRegisterRenderer(format models.Format, renderer Renderer) {
+	err := RegisterRenderer(format, renderer)
+	if err != nil {
+		panic(err)
+	}
+}
 
 func Render(obj interface{}, path string, format models.Format) (*models.Document, error) {
 	if !strings.HasSuffix(path, format.Extension) {
@@ -26,12 +54,11 @@ func Render(obj interface{}, path string, format models.Format) (*models.Documen
 }
 
 func getRenderer(format models.Format) (Renderer, error) {
-	switch format {
-	case models.JsonFormat:
-		return jsonRenderer, nil
-	default:
+	renderer, ok := models.GetRenderer(format)
+	if !ok {
 		return nil, fmt.Errorf("format: %v not implemented", format)
 	}
+	return renderer, nil
 }
 
 func jsonRenderer(obj interface{}, path string) (*models.Document, error) {
@@ -44,3 +71,41 @@ func jsonRenderer(obj interface{}, path string) (*models.Document, error) {
 	}
 	return &models.Document{Path: path, MediaType: models.JsonMediaType, Content: content}, nil
 }
+
+func htmlRenderer(obj interface{}, path string) (*models.Document, error) {
+	var templateName string
+	switch obj.(type) {
+	case models.Styles, *models.Styles:
+		templateName = "styles.html"
+	case models.StyleMetadata, *models.StyleMetadata:
+		templateName = "style_metadata.html"
+	case models.LandingPage, *models.LandingPage:
+		templateName = "landing_page.html"
+	case models.Conformance, *models.Conformance:
+		templateName = "conformance.html"
+	default:
+		return nil, fmt.Errorf("no html template known for %T", obj)
+	}
+	content := new(bytes.Buffer)
+	err := htmlTemplates.ExecuteTemplate(content, templateName, obj)
+	if err != nil {
+		return nil, fmt.Errorf("error: %v, could not render document to file", err)
+	}
+	return &models.Document{Path: path, MediaType: models.HtmlMediaType, Content: content}, nil
+}
+
+// htmlHref points links to generated style metadata (describedby, without a media type) to their html
+// representation, so the rendered pages can be browsed without content negotiation.
+func htmlHref(link models.Link) string {
+	if link.Href == nil {
+		return ""
+	}
+	if link.Type == nil && link.Rel == models.DescribedbyRelation {
+		return fmt.Sprintf("%s.%s", *link.Href, models.HtmlFormat.Extension)
+	}
+	return *link.Href
+}
+
+func isPreview(link models.Link) bool {
+	return link.Rel == models.PreviewRelation && link.Href != nil
+}
@@ -26,3 +26,59 @@ func TestRenderJson(t *testing.T) {
 	require.Equal(t, expected, result.Content.String())
 	require.Equal(t, result.Path, path)
 }
+
+func TestRenderHtmlStyles(t *testing.T) {
+	href := "https://example.org/catalog/1.0/styles/night/metadata"
+	preview := "https://example.org/catalog/1.0/resources/night.png"
+	obj := models.Styles{Default: "night", Styles: []models.Style{{Id: "night", Title: "Night", Links: []models.Link{
+		{Href: &href, Rel: models.DescribedbyRelation},
+		{Href: &preview, Rel: models.PreviewRelation},
+	}}}}
+	path := "styles.html"
+
+	result, err := Render(obj, "styles", models.HtmlFormat)
+
+	require.Nil(t, err)
+	require.Equal(t, models.HtmlMediaType, result.MediaType)
+	require.Equal(t, path, result.Path)
+	require.Contains(t, result.Content.String(), "<h2>Night</h2>")
+	require.Contains(t, result.Content.String(), `<a href="https://example.org/catalog/1.0/styles/night/metadata.html">`)
+	require.Contains(t, result.Content.String(), `<img class="preview" src="https://example.org/catalog/1.0/resources/night.png" alt="preview">`)
+}
+
+func TestRenderHtmlStyleMetadata(t *testing.T) {
+	config, _ := ParseConfig("../examples/config.yaml")
+	path := "styles/night/metadata.html"
+
+	result, err := Render(config.StylesMetadata[0], "styles/night/metadata", models.HtmlFormat)
+
+	require.Nil(t, err)
+	require.Equal(t, models.HtmlMediaType, result.MediaType)
+	require.Equal(t, path, result.Path)
+	require.Contains(t, result.Content.String(), "<title>Topographic night style</title>")
+	require.Contains(t, result.Content.String(), "<dt>License</dt><dd>MIT</dd>")
+	require.Contains(t, result.Content.String(), `<span class="keyword">TDS 6.1</span>`)
+	require.Contains(t, result.Content.String(), "<td>VegetationSrf</td>")
+	require.Contains(t, result.Content.String(), "<td>polygons</td>")
+}
+
+func TestRenderHtmlUnknownObject(t *testing.T) {
+	_, err := Render(TestStruct{}, "test", models.HtmlFormat)
+
+	require.NotNil(t, err)
+}
+
+func TestRegisterRendererTwice(t *testing.T) {
+	err := RegisterRenderer(models.JsonFormat, jsonRenderer)
+
+	require.NotNil(t, err)
+	require.Equal(t, "a renderer for format: json is already registered", err.Error())
+}
+
+func TestRenderUnknownFormat(t *testing.T) {
+	_, ok := models.GetFormat(models.SldFormat.Name)
+	require.False(t, ok)
+
+	_, err := Render(TestStruct{}, "test", models.SldFormat)
+	require.NotNil(t, err)
+}
@@ -0,0 +1,43 @@
+package sample
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// geoJsonFeature a GeoJSON Feature, or FeatureCollection (with a name as written by GDAL) - https://www.rfc-editor.org/rfc/rfc7946
+type geoJsonFeature struct {
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Geometry *struct {
+		Type string `json:"type"`
+	} `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+	Features   []geoJsonFeature       `json:"features"`
+}
+
+// ReadGeoJson infers the layer of a GeoJSON FeatureCollection or Feature
+func ReadGeoJson(content []byte) (Layer, error) {
+	var root geoJsonFeature
+	err := json.Unmarshal(content, &root)
+	if err != nil {
+		return Layer{}, fmt.Errorf("could not parse GeoJSON: %s", err)
+	}
+	features := root.Features
+	switch root.Type {
+	case "FeatureCollection":
+	case "Feature":
+		features = []geoJsonFeature{root}
+	default:
+		return Layer{}, fmt.Errorf("GeoJSON of type %s is not a FeatureCollection or Feature", root.Type)
+	}
+	collect := newCollector()
+	for _, feature := range features {
+		geometryTypeName := ""
+		if feature.Geometry != nil {
+			geometryTypeName = feature.Geometry.Type
+		}
+		collect.add(geometryTypeName, feature.Properties)
+	}
+	return collect.layer(root.Name), nil
+}
@@ -0,0 +1,174 @@
+package sample
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/pdok/goas/pkg/models"
+)
+
+// wkbGeometryTypes the (upper case) names of the WKB geometry type codes, without the Z and M offsets
+var wkbGeometryTypes = map[uint32]string{
+	1: "POINT", 2: "LINESTRING", 3: "POLYGON", 4: "MULTIPOINT", 5: "MULTILINESTRING", 6: "MULTIPOLYGON",
+	7: "GEOMETRYCOLLECTION", 8: "CIRCULARSTRING", 9: "COMPOUNDCURVE", 10: "CURVEPOLYGON", 11: "MULTICURVE",
+	12: "MULTISURFACE", 13: "CURVE", 14: "SURFACE",
+}
+
+// geoPackageEnvelopeSizes the size of the envelope of a GeoPackage geometry, by envelope contents indicator
+var geoPackageEnvelopeSizes = []int{0, 32, 48, 48, 64}
+
+// ReadGeoPackage infers the layers of the feature tables of a GeoPackage (the tables in gpkg_contents with data_type
+// features), in the order of gpkg_contents - https://www.geopackage.org/spec130/
+func ReadGeoPackage(content []byte) ([]Layer, error) {
+	db, err := openSqlite(content)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := db.tables()
+	if err != nil {
+		return nil, err
+	}
+	contentsTable, ok := tables["gpkg_contents"]
+	geometryColumnsTable, hasGeometryColumns := tables["gpkg_geometry_columns"]
+	if !ok || !hasGeometryColumns {
+		return nil, fmt.Errorf("not a GeoPackage, gpkg_contents and gpkg_geometry_columns tables not found")
+	}
+	var featureTables []string
+	err = db.records(contentsTable, func(record map[string]interface{}) error {
+		if name, ok := record["table_name"].(string); ok && record["data_type"] == "features" {
+			featureTables = append(featureTables, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	geometryColumns := make(map[string]map[string]interface{})
+	err = db.records(geometryColumnsTable, func(record map[string]interface{}) error {
+		if name, ok := record["table_name"].(string); ok {
+			geometryColumns[strings.ToLower(name)] = record
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var layers []Layer
+	for _, name := range featureTables {
+		table, ok := tables[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("feature table %s of the GeoPackage not found", name)
+		}
+		geometryColumn, _ := geometryColumns[strings.ToLower(name)]["column_name"].(string)
+		layer, err := readFeatureTable(db, table, geometryColumn)
+		if err != nil {
+			return nil, fmt.Errorf("could not read feature table %s of the GeoPackage: %s", name, err)
+		}
+		if layer.GeometryType == nil {
+			// a table without features still declares its geometry type
+			geometryTypeName, _ := geometryColumns[strings.ToLower(name)]["geometry_type_name"].(string)
+			if geometryType, ok := geometryTypes[strings.ToUpper(geometryTypeName)]; ok {
+				layer.GeometryType = &geometryType
+			}
+		}
+		layers = append(layers, layer)
+	}
+	return layers, nil
+}
+
+// readFeatureTable infers the layer of the features of a table, the primary key and geometry column are not properties
+func readFeatureTable(db *sqliteDatabase, table sqliteTable, geometryColumn string) (Layer, error) {
+	collect := newCollector()
+	columns := make(map[string]sqliteColumn)
+	for _, column := range table.columns {
+		if column.rowid || strings.EqualFold(column.name, geometryColumn) {
+			continue
+		}
+		columns[column.name] = column
+		collect.declare(column.name, declaredSchema(column.declaredType))
+	}
+	err := db.records(table, func(record map[string]interface{}) error {
+		geometryTypeName := ""
+		properties := make(map[string]interface{}, len(columns))
+		for name, value := range record {
+			if strings.EqualFold(name, geometryColumn) {
+				if blob, ok := value.([]byte); ok {
+					geometryTypeName = geoPackageGeometryType(blob)
+				}
+				continue
+			}
+			column, ok := columns[name]
+			if !ok {
+				continue
+			}
+			switch typed := value.(type) {
+			case []byte:
+				// blobs are described by their column only
+				continue
+			case int64:
+				if column.declaredType == "BOOLEAN" {
+					properties[name] = typed != 0
+					continue
+				}
+			}
+			properties[name] = value
+		}
+		collect.add(geometryTypeName, properties)
+		return nil
+	})
+	return collect.layer(table.name), err
+}
+
+// declaredSchema the type and format of a column of one of the data types of a GeoPackage, an empty schema for other
+// declared types
+func declaredSchema(declaredType string) models.PropertiesSchema {
+	base := declaredType
+	if i := strings.IndexAny(base, " ("); i >= 0 {
+		base = base[:i]
+	}
+	switch base {
+	case "BOOLEAN":
+		return models.PropertiesSchema{Type: "boolean"}
+	case "TINYINT", "SMALLINT", "MEDIUMINT":
+		return models.PropertiesSchema{Type: "integer", Format: "int32"}
+	case "INT", "INTEGER":
+		return models.PropertiesSchema{Type: "integer", Format: "int64"}
+	case "FLOAT":
+		return models.PropertiesSchema{Type: "number", Format: "float"}
+	case "DOUBLE", "REAL":
+		return models.PropertiesSchema{Type: "number", Format: "double"}
+	case "TEXT":
+		return models.PropertiesSchema{Type: "string"}
+	case "BLOB":
+		return models.PropertiesSchema{Type: "string", Format: "binary"}
+	case "DATE":
+		return models.PropertiesSchema{Type: "string", Format: "date"}
+	case "DATETIME":
+		return models.PropertiesSchema{Type: "string", Format: "date-time"}
+	default:
+		return models.PropertiesSchema{}
+	}
+}
+
+// geoPackageGeometryType the (upper case) geometry type name of a GeoPackage geometry blob: a GP header with an optional
+// envelope followed by WKB, empty when the blob is not a geometry
+func geoPackageGeometryType(blob []byte) string {
+	if len(blob) < 8 || blob[0] != 'G' || blob[1] != 'P' {
+		return ""
+	}
+	indicator := int(blob[3]>>1) & 7
+	if indicator >= len(geoPackageEnvelopeSizes) || len(blob) < 8+geoPackageEnvelopeSizes[indicator]+5 {
+		return ""
+	}
+	wkb := blob[8+geoPackageEnvelopeSizes[indicator]:]
+	var code uint32
+	if wkb[0] == 1 {
+		code = binary.LittleEndian.Uint32(wkb[1:5])
+	} else {
+		code = binary.BigEndian.Uint32(wkb[1:5])
+	}
+	// ignore the Z, M and SRID flags of extended WKB and the Z (1000), M (2000) and ZM (3000) offsets of ISO WKB
+	return wkbGeometryTypes[(code&0x0fffffff)%1000]
+}
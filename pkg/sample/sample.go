@@ -0,0 +1,173 @@
+package sample
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/pdok/goas/pkg/models"
+)
+
+// maxEnumValues the most distinct values of a string property that is still inferred as an enum
+const maxEnumValues = 10
+
+// Layer the geometry type and properties schema inferred from the features of a layer of sample data
+type Layer struct {
+	Name             string
+	GeometryType     *models.GeometryType     // nil without geometries
+	PropertiesSchema *models.PropertiesSchema // nil without properties
+}
+
+// geometryTypes the geometry type of the (upper case) GeoJSON, WKB and GeoPackage geometry type names
+var geometryTypes = map[string]models.GeometryType{
+	"POINT":              models.Points,
+	"MULTIPOINT":         models.Points,
+	"LINESTRING":         models.Lines,
+	"MULTILINESTRING":    models.Lines,
+	"CIRCULARSTRING":     models.Lines,
+	"COMPOUNDCURVE":      models.Lines,
+	"CURVE":              models.Lines,
+	"MULTICURVE":         models.Lines,
+	"POLYGON":            models.Polygons,
+	"MULTIPOLYGON":       models.Polygons,
+	"CURVEPOLYGON":       models.Polygons,
+	"SURFACE":            models.Polygons,
+	"MULTISURFACE":       models.Polygons,
+	"GEOMETRYCOLLECTION": models.Any,
+}
+
+// collector gathers the geometry types and property values of the features of a layer
+type collector struct {
+	geometryTypes map[models.GeometryType]bool
+	values        map[string][]interface{}
+	declared      map[string]models.PropertiesSchema
+}
+
+func newCollector() *collector {
+	return &collector{
+		geometryTypes: make(map[models.GeometryType]bool),
+		values:        make(map[string][]interface{}),
+		declared:      make(map[string]models.PropertiesSchema),
+	}
+}
+
+// declare a property with the type and format of its column, which take precedence over the values
+func (c *collector) declare(name string, schema models.PropertiesSchema) {
+	c.declared[name] = schema
+	if _, ok := c.values[name]; !ok {
+		c.values[name] = nil
+	}
+}
+
+// add a feature, with an empty geometry type name when it has no geometry
+func (c *collector) add(geometryTypeName string, properties map[string]interface{}) {
+	if geometryType, ok := geometryTypes[strings.ToUpper(geometryTypeName)]; ok {
+		c.geometryTypes[geometryType] = true
+	}
+	for name, value := range properties {
+		c.values[name] = append(c.values[name], value)
+	}
+}
+
+func (c *collector) layer(name string) Layer {
+	layer := Layer{Name: name}
+	switch len(c.geometryTypes) {
+	case 0:
+	case 1:
+		for geometryType := range c.geometryTypes {
+			layer.GeometryType = &geometryType
+		}
+	default:
+		mixed := models.Any
+		layer.GeometryType = &mixed
+	}
+	if len(c.values) > 0 {
+		layer.PropertiesSchema = &models.PropertiesSchema{Type: "object", Properties: make(map[string]*models.PropertiesSchema)}
+		for property, values := range c.values {
+			schema := inferSchema(values)
+			if declared, ok := c.declared[property]; ok && declared.Type != "" {
+				if declared.Type != schema.Type || declared.Format != "" {
+					// dates and the like are not enumerated
+					schema.Enum = nil
+				}
+				schema.Type = declared.Type
+				schema.Format = declared.Format
+			}
+			layer.PropertiesSchema.Properties[property] = schema
+		}
+	}
+	return layer
+}
+
+// inferSchema the schema the values have in common: their type (integer for whole numbers), nullable when one of them
+// is null, an enum for strings that repeat a few distinct values, and the schemas of the properties of objects and the
+// items of arrays
+func inferSchema(values []interface{}) *models.PropertiesSchema {
+	schema := &models.PropertiesSchema{}
+	types := make(map[string]bool)
+	distinct := make(map[string]bool)
+	var objects []map[string]interface{}
+	var items []interface{}
+	count := 0
+	for _, value := range values {
+		switch typed := value.(type) {
+		case nil:
+			schema.Nullable = true
+			continue
+		case string:
+			types["string"] = true
+			distinct[typed] = true
+		case bool:
+			types["boolean"] = true
+		case int64:
+			types["integer"] = true
+		case float64:
+			if typed == math.Trunc(typed) && !math.IsInf(typed, 0) {
+				types["integer"] = true
+			} else {
+				types["number"] = true
+			}
+		case map[string]interface{}:
+			types["object"] = true
+			objects = append(objects, typed)
+		case []interface{}:
+			types["array"] = true
+			items = append(items, typed...)
+		default:
+			continue
+		}
+		count++
+	}
+	if types["integer"] && types["number"] {
+		delete(types, "integer")
+	}
+	if len(types) != 1 {
+		return schema
+	}
+	for schemaType := range types {
+		schema.Type = schemaType
+	}
+	switch schema.Type {
+	case "string":
+		if len(distinct) <= maxEnumValues && count >= 2*len(distinct) {
+			for value := range distinct {
+				schema.Enum = append(schema.Enum, value)
+			}
+			sort.Slice(schema.Enum, func(i, j int) bool { return schema.Enum[i].(string) < schema.Enum[j].(string) })
+		}
+	case "object":
+		properties := make(map[string][]interface{})
+		for _, object := range objects {
+			for name, value := range object {
+				properties[name] = append(properties[name], value)
+			}
+		}
+		schema.Properties = make(map[string]*models.PropertiesSchema)
+		for name, propertyValues := range properties {
+			schema.Properties[name] = inferSchema(propertyValues)
+		}
+	case "array":
+		schema.Items = inferSchema(items)
+	}
+	return schema
+}
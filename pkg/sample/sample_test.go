@@ -0,0 +1,90 @@
+package sample
+
+import (
+	"os"
+	"testing"
+
+	"github.com/pdok/goas/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadGeoJson(t *testing.T) {
+	layer, err := ReadGeoJson([]byte(`{
+	  "type": "FeatureCollection",
+	  "name": "VegetationSrf",
+	  "features": [
+		{"type": "Feature", "geometry": {"type": "Polygon", "coordinates": []},
+		 "properties": {"F_CODE": "EC015", "HGT": 12, "DENSITY": 0.5, "tags": ["pine"], "source": {"year": 2019}}},
+		{"type": "Feature", "geometry": {"type": "MultiPolygon", "coordinates": []},
+		 "properties": {"F_CODE": "EC015", "HGT": 8, "DENSITY": 1, "tags": []}},
+		{"type": "Feature", "geometry": null,
+		 "properties": {"F_CODE": "EB010", "HGT": null, "DENSITY": "dense"}},
+		{"type": "Feature", "geometry": {"type": "Polygon", "coordinates": []},
+		 "properties": {"F_CODE": "EB010", "HGT": 3}}
+	  ]
+	}`))
+	require.Nil(t, err)
+	assert.Equal(t, "VegetationSrf", layer.Name)
+	assert.Equal(t, models.Polygons, *layer.GeometryType, "polygons and multipolygons, features without geometry are skipped")
+
+	properties := layer.PropertiesSchema.Properties
+	assert.Equal(t, "object", layer.PropertiesSchema.Type)
+	assert.Len(t, properties, 5)
+	assert.Equal(t, &models.PropertiesSchema{Type: "string", Enum: []interface{}{"EB010", "EC015"}}, properties["F_CODE"])
+	assert.Equal(t, &models.PropertiesSchema{Type: "integer", Nullable: true}, properties["HGT"])
+	assert.Equal(t, "", properties["DENSITY"].Type, "numbers and strings have no type in common")
+	assert.Equal(t, &models.PropertiesSchema{Type: "array", Items: &models.PropertiesSchema{Type: "string"}}, properties["tags"])
+	assert.Equal(t, "integer", properties["source"].Properties["year"].Type)
+}
+
+func TestReadGeoJsonInvalid(t *testing.T) {
+	_, err := ReadGeoJson([]byte(`{"type": "Point", "coordinates": [1, 2]}`))
+	assert.EqualError(t, err, "GeoJSON of type Point is not a FeatureCollection or Feature")
+}
+
+func TestReadGeoPackage(t *testing.T) {
+	content, err := os.ReadFile("../../examples/assets/sample/daraa.gpkg")
+	require.Nil(t, err)
+	layers, err := ReadGeoPackage(content)
+	require.Nil(t, err)
+	require.Len(t, layers, 2)
+
+	assert.Equal(t, "hydrographycrv", layers[0].Name)
+	assert.Equal(t, models.Lines, *layers[0].GeometryType)
+	properties := layers[0].PropertiesSchema.Properties
+	assert.Len(t, properties, 4, "the fid and geometry are not properties")
+	assert.Equal(t, &models.PropertiesSchema{Type: "string", Enum: []interface{}{"BH020", "BH140"}}, properties["F_CODE"])
+	assert.Equal(t, &models.PropertiesSchema{Type: "string", Nullable: true}, properties["ZI005_FNA"], "too many distinct names for an enum")
+	assert.Equal(t, &models.PropertiesSchema{Type: "number", Format: "double", Nullable: true}, properties["WID"])
+	assert.Equal(t, &models.PropertiesSchema{Type: "boolean"}, properties["PERENNIAL"])
+
+	assert.Equal(t, "SettlementPnt", layers[1].Name)
+	assert.Equal(t, models.Points, *layers[1].GeometryType)
+	assert.Equal(t, &models.PropertiesSchema{Type: "integer", Format: "int32"}, layers[1].PropertiesSchema.Properties["POP"])
+	assert.Equal(t, &models.PropertiesSchema{Type: "string", Format: "date", Nullable: true}, layers[1].PropertiesSchema.Properties["UPDATED"])
+}
+
+func TestReadGeoPackageInvalid(t *testing.T) {
+	_, err := ReadGeoPackage([]byte(`{"type": "FeatureCollection"}`))
+	assert.EqualError(t, err, "not an SQLite database")
+}
+
+func TestReadGeoPackageWal(t *testing.T) {
+	content, err := os.ReadFile("../../examples/assets/sample/daraa.gpkg")
+	require.Nil(t, err)
+	content[18], content[19] = 2, 2
+	_, err = ReadGeoPackage(content)
+	assert.EqualError(t, err, "SQLite database in WAL mode is not supported, checkpoint it first: PRAGMA journal_mode=DELETE")
+}
+
+func TestParseColumns(t *testing.T) {
+	columns, err := parseColumns(`CREATE TABLE "roads" (id INTEGER NOT NULL, "the geom" LINESTRING, [name] VARCHAR (80) DEFAULT 'a, b',
+		CONSTRAINT pk PRIMARY KEY (id))`)
+	require.Nil(t, err)
+	assert.Equal(t, []sqliteColumn{
+		{name: "id", declaredType: "INTEGER", rowid: true},
+		{name: "the geom", declaredType: "LINESTRING"},
+		{name: "name", declaredType: "VARCHAR (80)"},
+	}, columns)
+}
@@ -0,0 +1,391 @@
+package sample
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+const sqliteMagic = "SQLite format 3\x00"
+
+// sqliteDatabase a read-only view of the tables of an SQLite 3 database file - https://www.sqlite.org/fileformat2.html
+type sqliteDatabase struct {
+	content    []byte
+	pageSize   int
+	usableSize int
+}
+
+// sqliteTable a table of the schema (sqlite_master), with the columns of its CREATE TABLE statement
+type sqliteTable struct {
+	name     string
+	rootPage int
+	columns  []sqliteColumn
+}
+
+type sqliteColumn struct {
+	name         string
+	declaredType string // upper case, e.g. TEXT(10) or INTEGER
+	rowid        bool   // an INTEGER PRIMARY KEY, stored as the rowid instead of in the record
+}
+
+func openSqlite(content []byte) (*sqliteDatabase, error) {
+	if len(content) < 100 || string(content[:16]) != sqliteMagic {
+		return nil, fmt.Errorf("not an SQLite database")
+	}
+	pageSize := int(binary.BigEndian.Uint16(content[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, fmt.Errorf("invalid SQLite page size %d", pageSize)
+	}
+	// the file format version numbers are 2 in WAL mode, the latest changes are then in the -wal file next to it
+	if content[18] == 2 || content[19] == 2 {
+		return nil, fmt.Errorf("SQLite database in WAL mode is not supported, checkpoint it first: PRAGMA journal_mode=DELETE")
+	}
+	if encoding := binary.BigEndian.Uint32(content[56:60]); encoding > 1 {
+		return nil, fmt.Errorf("only UTF-8 SQLite databases are supported")
+	}
+	return &sqliteDatabase{content: content, pageSize: pageSize, usableSize: pageSize - int(content[20])}, nil
+}
+
+func (db *sqliteDatabase) page(number int) ([]byte, error) {
+	start := (number - 1) * db.pageSize
+	if number < 1 || start+db.pageSize > len(db.content) {
+		return nil, fmt.Errorf("SQLite page %d out of range", number)
+	}
+	return db.content[start : start+db.pageSize], nil
+}
+
+// tables the tables of the schema by lower case name, as SQLite names are case-insensitive
+func (db *sqliteDatabase) tables() (map[string]sqliteTable, error) {
+	tables := make(map[string]sqliteTable)
+	err := db.walk(1, 0, func(_ int64, values []interface{}) error {
+		// sqlite_master: type, name, tbl_name, rootpage, sql
+		if len(values) < 5 || values[0] != "table" {
+			return nil
+		}
+		name, _ := values[1].(string)
+		rootPage, _ := values[3].(int64)
+		sql, _ := values[4].(string)
+		columns, err := parseColumns(sql)
+		if err != nil {
+			return fmt.Errorf("could not read table %s: %s", name, err)
+		}
+		tables[strings.ToLower(name)] = sqliteTable{name: name, rootPage: int(rootPage), columns: columns}
+		return nil
+	})
+	return tables, err
+}
+
+// records visits the rows of the table in rowid order, by column name
+func (db *sqliteDatabase) records(table sqliteTable, visit func(record map[string]interface{}) error) error {
+	return db.walk(table.rootPage, 0, func(rowid int64, values []interface{}) error {
+		record := make(map[string]interface{}, len(table.columns))
+		for i, column := range table.columns {
+			switch {
+			case column.rowid:
+				record[column.name] = rowid
+			case i < len(values):
+				record[column.name] = values[i]
+			default:
+				// columns added after the row was written
+				record[column.name] = nil
+			}
+		}
+		return visit(record)
+	})
+}
+
+// walk visits the records of the table b-tree at the page, in rowid order
+func (db *sqliteDatabase) walk(number int, depth int, visit func(rowid int64, values []interface{}) error) error {
+	if depth > 32 {
+		return fmt.Errorf("SQLite b-tree too deep at page %d", number)
+	}
+	page, err := db.page(number)
+	if err != nil {
+		return err
+	}
+	header := page
+	if number == 1 {
+		// the database header precedes the b-tree page header of the first page
+		header = page[100:]
+	}
+	cellCount := int(binary.BigEndian.Uint16(header[3:5]))
+	switch header[0] {
+	case 0x0d:
+		// leaf: payload size, rowid, payload
+		for i := 0; i < cellCount; i++ {
+			cell, err := cellAt(page, header[8:], i)
+			if err != nil {
+				return err
+			}
+			payloadSize, n := sqliteVarint(cell)
+			cell = cell[n:]
+			rowid, m := sqliteVarint(cell)
+			if n == 0 || m == 0 {
+				return fmt.Errorf("invalid SQLite cell %d of page %d", i, number)
+			}
+			payload, err := db.payload(cell[m:], int(payloadSize))
+			if err != nil {
+				return err
+			}
+			values, err := decodeRecord(payload)
+			if err != nil {
+				return err
+			}
+			err = visit(int64(rowid), values)
+			if err != nil {
+				return err
+			}
+		}
+	case 0x05:
+		// interior: left child page, key; followed by the right-most child page
+		for i := 0; i < cellCount; i++ {
+			cell, err := cellAt(page, header[12:], i)
+			if err != nil || len(cell) < 4 {
+				return fmt.Errorf("invalid SQLite cell %d of page %d", i, number)
+			}
+			err = db.walk(int(binary.BigEndian.Uint32(cell)), depth+1, visit)
+			if err != nil {
+				return err
+			}
+		}
+		return db.walk(int(binary.BigEndian.Uint32(header[8:12])), depth+1, visit)
+	default:
+		return fmt.Errorf("SQLite page %d is not a table b-tree page", number)
+	}
+	return nil
+}
+
+// cellAt the content of the cell the i-th pointer of the cell pointer array refers to
+func cellAt(page []byte, pointers []byte, i int) ([]byte, error) {
+	if 2*i+2 > len(pointers) {
+		return nil, fmt.Errorf("SQLite cell pointer %d out of range", i)
+	}
+	offset := int(binary.BigEndian.Uint16(pointers[2*i:]))
+	if offset >= len(page) {
+		return nil, fmt.Errorf("SQLite cell offset %d out of range", offset)
+	}
+	return page[offset:], nil
+}
+
+// payload the payload of a cell, followed through its overflow pages when it does not fit the page
+func (db *sqliteDatabase) payload(cell []byte, size int) ([]byte, error) {
+	maxLocal := db.usableSize - 35
+	if size <= maxLocal {
+		if size > len(cell) {
+			return nil, fmt.Errorf("SQLite payload out of range")
+		}
+		return cell[:size], nil
+	}
+	minLocal := (db.usableSize-12)*32/255 - 23
+	local := minLocal + (size-minLocal)%(db.usableSize-4)
+	if local > maxLocal {
+		local = minLocal
+	}
+	if local+4 > len(cell) {
+		return nil, fmt.Errorf("SQLite payload out of range")
+	}
+	payload := make([]byte, 0, size)
+	payload = append(payload, cell[:local]...)
+	overflow := int(binary.BigEndian.Uint32(cell[local:]))
+	for pages := 0; len(payload) < size; pages++ {
+		if overflow == 0 || pages > len(db.content)/db.pageSize {
+			return nil, fmt.Errorf("SQLite overflow pages missing")
+		}
+		page, err := db.page(overflow)
+		if err != nil {
+			return nil, err
+		}
+		chunk := page[4:db.usableSize]
+		if remaining := size - len(payload); len(chunk) > remaining {
+			chunk = chunk[:remaining]
+		}
+		payload = append(payload, chunk...)
+		overflow = int(binary.BigEndian.Uint32(page))
+	}
+	return payload, nil
+}
+
+// decodeRecord the values of a record: nil, int64, float64, string or []byte
+func decodeRecord(payload []byte) ([]interface{}, error) {
+	headerSize, n := sqliteVarint(payload)
+	if n == 0 || int(headerSize) > len(payload) {
+		return nil, fmt.Errorf("invalid SQLite record header")
+	}
+	var serialTypes []uint64
+	for offset := n; offset < int(headerSize); {
+		serialType, m := sqliteVarint(payload[offset:headerSize])
+		if m == 0 {
+			return nil, fmt.Errorf("invalid SQLite record header")
+		}
+		serialTypes = append(serialTypes, serialType)
+		offset += m
+	}
+	body := payload[headerSize:]
+	values := make([]interface{}, len(serialTypes))
+	for i, serialType := range serialTypes {
+		size := serialSize(serialType)
+		if size > len(body) {
+			return nil, fmt.Errorf("SQLite record truncated")
+		}
+		field := body[:size]
+		body = body[size:]
+		switch {
+		case serialType == 0:
+			values[i] = nil
+		case serialType <= 6:
+			// big-endian two's complement integer of 1, 2, 3, 4, 6 or 8 bytes
+			value := int64(int8(field[0]))
+			for _, b := range field[1:] {
+				value = value<<8 | int64(b)
+			}
+			values[i] = value
+		case serialType == 7:
+			values[i] = math.Float64frombits(binary.BigEndian.Uint64(field))
+		case serialType == 8 || serialType == 9:
+			values[i] = int64(serialType - 8)
+		case serialType >= 12 && serialType%2 == 0:
+			values[i] = field
+		case serialType >= 13:
+			values[i] = string(field)
+		default:
+			return nil, fmt.Errorf("reserved SQLite serial type %d", serialType)
+		}
+	}
+	return values, nil
+}
+
+func serialSize(serialType uint64) int {
+	switch {
+	case serialType >= 12:
+		return int((serialType - 12) / 2)
+	case serialType == 5:
+		return 6
+	case serialType == 6 || serialType == 7:
+		return 8
+	case serialType >= 1 && serialType <= 4:
+		return int(serialType)
+	default:
+		return 0
+	}
+}
+
+// sqliteVarint a big-endian variable length integer of 1 to 9 bytes, 0 bytes read when the input is too short
+func sqliteVarint(b []byte) (uint64, int) {
+	var value uint64
+	for i := 0; i < 9 && i < len(b); i++ {
+		if i == 8 {
+			return value<<8 | uint64(b[i]), 9
+		}
+		value = value<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return value, i + 1
+		}
+	}
+	return 0, 0
+}
+
+// sqliteConstraints the keywords that end the type name of a column definition
+var sqliteConstraints = map[string]bool{
+	"CONSTRAINT": true, "PRIMARY": true, "NOT": true, "NULL": true, "UNIQUE": true, "CHECK": true, "DEFAULT": true,
+	"COLLATE": true, "REFERENCES": true, "GENERATED": true, "AS": true,
+}
+
+// parseColumns the columns of a CREATE TABLE statement
+func parseColumns(sql string) ([]sqliteColumn, error) {
+	start := strings.Index(sql, "(")
+	end := strings.LastIndex(sql, ")")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no column definitions in %s", sql)
+	}
+	var columns []sqliteColumn
+	var primaryKey []string
+	for _, definition := range splitDefinitions(sql[start+1 : end]) {
+		name, rest, quoted := sqlName(definition)
+		if !quoted && strings.EqualFold(name, "CONSTRAINT") {
+			// a named table constraint
+			_, constraint, _ := sqlName(rest)
+			name, rest, quoted = sqlName(constraint)
+		}
+		if !quoted {
+			switch strings.ToUpper(name) {
+			case "PRIMARY":
+				// table constraint PRIMARY KEY (column, ...)
+				if from, to := strings.Index(rest, "("), strings.LastIndex(rest, ")"); from >= 0 && to > from {
+					primaryKey = splitDefinitions(rest[from+1 : to])
+				}
+				continue
+			case "UNIQUE", "CHECK", "FOREIGN":
+				continue
+			}
+		}
+		var typeNames []string
+		for _, word := range strings.Fields(rest) {
+			if sqliteConstraints[strings.ToUpper(word)] {
+				break
+			}
+			typeNames = append(typeNames, word)
+		}
+		declaredType := strings.ToUpper(strings.Join(typeNames, " "))
+		rowid := declaredType == "INTEGER" && strings.Contains(strings.ToUpper(rest), "PRIMARY KEY")
+		columns = append(columns, sqliteColumn{name: name, declaredType: declaredType, rowid: rowid})
+	}
+	if len(primaryKey) == 1 {
+		key, _, _ := sqlName(primaryKey[0])
+		for i := range columns {
+			if strings.EqualFold(columns[i].name, key) && columns[i].declaredType == "INTEGER" {
+				columns[i].rowid = true
+			}
+		}
+	}
+	return columns, nil
+}
+
+// splitDefinitions splits at the commas outside parentheses and quotes
+func splitDefinitions(definitions string) []string {
+	var parts []string
+	depth := 0
+	var quote rune
+	start := 0
+	for i, r := range definitions {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '[':
+			quote = ']'
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(definitions[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, strings.TrimSpace(definitions[start:]))
+}
+
+// sqlName the (optionally quoted) name at the start of a definition and the rest of it
+func sqlName(definition string) (name string, rest string, quoted bool) {
+	definition = strings.TrimSpace(definition)
+	if definition == "" {
+		return "", "", false
+	}
+	closing := map[byte]byte{'"': '"', '`': '`', '[': ']', '\'': '\''}[definition[0]]
+	if closing != 0 {
+		if end := strings.IndexByte(definition[1:], closing); end >= 0 {
+			return definition[1 : end+1], definition[end+2:], true
+		}
+	}
+	if end := strings.IndexAny(definition, " \t\r\n("); end >= 0 {
+		return definition[:end], definition[end:], false
+	}
+	return definition, "", false
+}
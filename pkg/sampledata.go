@@ -0,0 +1,112 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pdok/goas/pkg/models"
+	"github.com/pdok/goas/pkg/sample"
+)
+
+// sampleDataExtensions the media type of local sample data files, by extension
+var sampleDataExtensions = map[string]models.MediaType{
+	".geojson": models.GeoJsonMediaType,
+	".json":    models.GeoJsonMediaType,
+	".gpkg":    models.GeoPackageMediaType,
+}
+
+// generateSampleData publishes the local sample data (GeoJSON or GeoPackage) of the layers of the style as enclosure
+// resources under resources/{styleId}/ - OGC API Styles Recommendation 2A - and infers the geometry type and properties
+// schema of the layers from it. The type and properties-schema of a layer in the config take precedence.
+func generateSampleData(stylesConfig *models.StylesConfig, styleMetadata *models.StyleMetadata, assetDir string) ([]models.Document, error) {
+	var documents []models.Document
+	published := make(map[string]bool)
+	for i := range styleMetadata.Layers {
+		layer := &styleMetadata.Layers[i]
+		if layer.SampleData == nil || layer.SampleData.AssetFilename == nil {
+			continue
+		}
+		link := *layer.SampleData
+		filename := *link.AssetFilename
+		mediaType, err := sampleDataMediaType(link)
+		if err != nil {
+			return nil, fmt.Errorf("sample data of layer %s of style %s: %s", layer.Id, styleMetadata.Id, err)
+		}
+		content, err := os.ReadFile(filepath.Join(assetDir, filename))
+		if err != nil {
+			return nil, fmt.Errorf("could not read sample data %s of layer %s of style %s", filename, layer.Id, styleMetadata.Id)
+		}
+		sampleLayer, err := readSampleLayer(content, mediaType, layer.Id)
+		if err != nil {
+			return nil, fmt.Errorf("could not read sample data %s of layer %s of style %s: %s", filename, layer.Id, styleMetadata.Id, err)
+		}
+
+		identifier := fmt.Sprintf("%s/%s", styleMetadata.Id, filepath.ToSlash(filename))
+		document := models.Document{
+			Path:      models.EnclosureRelation.MustToPath(identifier),
+			MediaType: mediaType,
+			Content:   bytes.NewBuffer(content),
+			Source:    filename,
+		}
+		link.Href = models.EnclosureRelation.MustToUrl(stylesConfig.BaseResource, identifier)
+		link.Rel = models.EnclosureRelation
+		link.Type = &mediaType
+		describeContent(&link, &document, stylesConfig.LinkChecksums)
+		layer.SampleData = &link
+		if !published[document.Path] {
+			// layers of one GeoPackage share the file
+			published[document.Path] = true
+			documents = append(documents, document)
+		}
+
+		if layer.GeometryType == nil {
+			layer.GeometryType = sampleLayer.GeometryType
+		}
+		if layer.PropertiesSchema == nil {
+			layer.PropertiesSchema = sampleLayer.PropertiesSchema
+		}
+	}
+	return documents, nil
+}
+
+// sampleDataMediaType the configured type of the sample data, or else the type of its extension
+func sampleDataMediaType(link models.Link) (models.MediaType, error) {
+	if link.Type != nil {
+		root, _ := link.Type.SplitParams()
+		if root != models.GeoJsonMediaType && root != models.GeoPackageMediaType {
+			return "", fmt.Errorf("type %s is not supported, choose from: [%s,%s]", *link.Type, models.GeoJsonMediaType, models.GeoPackageMediaType)
+		}
+		return root, nil
+	}
+	mediaType, ok := sampleDataExtensions[strings.ToLower(filepath.Ext(*link.AssetFilename))]
+	if !ok {
+		return "", fmt.Errorf("unknown format of %s, use a .geojson or .gpkg file or set the type", *link.AssetFilename)
+	}
+	return mediaType, nil
+}
+
+// readSampleLayer infers the layer from GeoJSON, or from the feature table of a GeoPackage named after the layer (or its
+// only feature table)
+func readSampleLayer(content []byte, mediaType models.MediaType, layerId string) (sample.Layer, error) {
+	if mediaType == models.GeoJsonMediaType {
+		return sample.ReadGeoJson(content)
+	}
+	layers, err := sample.ReadGeoPackage(content)
+	if err != nil {
+		return sample.Layer{}, err
+	}
+	var names []string
+	for _, layer := range layers {
+		if strings.EqualFold(layer.Name, layerId) {
+			return layer, nil
+		}
+		names = append(names, layer.Name)
+	}
+	if len(layers) == 1 {
+		return layers[0], nil
+	}
+	return sample.Layer{}, fmt.Errorf("feature table %s not found, choose from: [%s]", layerId, strings.Join(names, ","))
+}
@@ -0,0 +1,59 @@
+package pkg
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pdok/goas/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSampleData(t *testing.T) {
+	config, _ := ParseConfig("../examples/generate_config.yaml")
+	config.StylesMetadata[1].DeriveLayers = false
+	documents, err := GenerateDocuments(config, "../examples/assets", []models.Format{models.JsonFormat})
+	require.Nil(t, err)
+	var metadata models.StyleMetadata
+	samples := 0
+	for _, document := range documents {
+		switch document.Path {
+		case "styles/daraa-legacy/metadata.json":
+			require.Nil(t, json.Unmarshal(document.Content.Bytes(), &metadata))
+		case "resources/daraa-legacy/sample/daraa.gpkg":
+			samples++
+			assert.Equal(t, models.GeoPackageMediaType, document.MediaType)
+		}
+	}
+	assert.Equal(t, 1, samples, "the layers share the GeoPackage")
+
+	require.Len(t, metadata.Layers, 2)
+	hydrography := metadata.Layers[0]
+	assert.Equal(t, "https://example.org/catalog/1.0/resources/daraa-legacy/sample/daraa.gpkg", *hydrography.SampleData.Href)
+	assert.Equal(t, models.EnclosureRelation, hydrography.SampleData.Rel)
+	assert.True(t, *hydrography.SampleData.Length > 0)
+	assert.Equal(t, models.Lines, *hydrography.GeometryType)
+	assert.Equal(t, []interface{}{"BH020", "BH140"}, hydrography.PropertiesSchema.Properties["F_CODE"].Enum)
+	settlements := metadata.Layers[1]
+	assert.Equal(t, models.GeoPackageMediaType, *settlements.SampleData.Type, "the type follows from the extension")
+	assert.Equal(t, models.Points, *settlements.GeometryType)
+	assert.Equal(t, "integer", settlements.PropertiesSchema.Properties["POP"].Type)
+	assert.Nil(t, config.StylesMetadata[1].Layers[0].GeometryType, "the config is not changed")
+}
+
+func TestGenerateSampleDataConfiguredLayer(t *testing.T) {
+	config, _ := ParseConfig("../examples/generate_config.yaml")
+	points := models.Points
+	config.StylesMetadata[1].Layers[0].GeometryType = &points
+	config.StylesMetadata[1].Layers[0].Id = "rivers"
+	styleMetadata := copyStyleMetadata(config.StylesMetadata[1])
+	_, err := generateSampleData(config, &styleMetadata, "../examples/assets")
+	require.EqualError(t, err, "could not read sample data sample/daraa.gpkg of layer rivers of style daraa-legacy: feature table rivers not found, choose from: [hydrographycrv,SettlementPnt]")
+
+	styleMetadata = copyStyleMetadata(config.StylesMetadata[1])
+	styleMetadata.Layers[0].Id = "hydrographycrv"
+	_, err = generateSampleData(config, &styleMetadata, "../examples/assets")
+	require.Nil(t, err)
+	assert.Equal(t, models.Points, *styleMetadata.Layers[0].GeometryType, "the configured type takes precedence")
+	assert.NotNil(t, styleMetadata.Layers[0].PropertiesSchema)
+}
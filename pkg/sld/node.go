@@ -0,0 +1,274 @@
+package sld
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Node a generic, namespace aware, XML element. SLD and SE documents are read and written as a tree of nodes, since
+// their (nested) filters and mixed content do not map well onto fixed structs.
+type Node struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr
+	Content  string  // all text of the element
+	Children []*Node // child elements, for mixed content interleaved with text nodes (without XMLName)
+}
+
+var prefixes = map[string]string{
+	SldNamespace:   "sld",
+	SeNamespace:    "se",
+	OgcNamespace:   "ogc",
+	GmlNamespace:   "gml",
+	XlinkNamespace: "xlink",
+	XsiNamespace:   "xsi",
+}
+
+// NewNode creates an element in the given namespace with the given children
+func NewNode(space string, local string, children ...*Node) *Node {
+	node := &Node{XMLName: xml.Name{Space: space, Local: local}}
+	node.Add(children...)
+	return node
+}
+
+// NewTextNode creates an element in the given namespace containing only text
+func NewTextNode(space string, local string, text string) *Node {
+	return &Node{XMLName: xml.Name{Space: space, Local: local}, Content: text}
+}
+
+// NewText creates a text node, used for mixed content (e.g. a Label with literal text and PropertyNames)
+func NewText(text string) *Node {
+	return &Node{Content: text}
+}
+
+// Add appends the children that are not nil
+func (node *Node) Add(children ...*Node) *Node {
+	for _, child := range children {
+		if child != nil {
+			node.Children = append(node.Children, child)
+		}
+	}
+	return node
+}
+
+// SetAttr sets an attribute without namespace
+func (node *Node) SetAttr(space string, local string, value string) *Node {
+	for i, attr := range node.Attrs {
+		if attr.Name.Space == space && attr.Name.Local == local {
+			node.Attrs[i].Value = value
+			return node
+		}
+	}
+	node.Attrs = append(node.Attrs, xml.Attr{Name: xml.Name{Space: space, Local: local}, Value: value})
+	return node
+}
+
+// Attr returns the value of the attribute with the given local name
+func (node *Node) Attr(local string) (string, bool) {
+	for _, attr := range node.Attrs {
+		if attr.Name.Local == local && attr.Name.Space != "xmlns" {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// Child returns the first child element with the given local name, regardless of its namespace
+func (node *Node) Child(local string) *Node {
+	for _, child := range node.Children {
+		if child.XMLName.Local == local {
+			return child
+		}
+	}
+	return nil
+}
+
+// ChildrenNamed returns all child elements with the given local name, regardless of their namespace
+func (node *Node) ChildrenNamed(local string) (children []*Node) {
+	for _, child := range node.Children {
+		if child.XMLName.Local == local {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// Elements returns all child elements, skipping text nodes
+func (node *Node) Elements() (children []*Node) {
+	for _, child := range node.Children {
+		if child.XMLName.Local != "" {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// Text returns the trimmed text content
+func (node *Node) Text() string {
+	if node == nil {
+		return ""
+	}
+	return strings.TrimSpace(node.Content)
+}
+
+// Parse reads an XML document into a tree of nodes
+func Parse(content []byte) (*Node, error) {
+	var root Node
+	err := xml.Unmarshal(content, &root)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse xml: %s", err)
+	}
+	return &root, nil
+}
+
+func (node *Node) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	node.XMLName = start.Name
+	node.Attrs = start.Attr
+	var contents []*Node
+	mixed := false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			child := &Node{}
+			err = child.UnmarshalXML(decoder, t)
+			if err != nil {
+				return err
+			}
+			node.Children = append(node.Children, child)
+			contents = append(contents, child)
+		case xml.CharData:
+			text := string(t)
+			node.Content += text
+			contents = append(contents, NewText(text))
+			mixed = mixed || strings.TrimSpace(text) != ""
+		case xml.EndElement:
+			if mixed && node.Children != nil {
+				node.Children = contents
+			}
+			return nil
+		}
+	}
+}
+
+// Encode writes the tree of nodes as an indented XML document. Known namespaces are written with their usual prefix
+// (sld, se, ogc, ...) and declared on the root element.
+func (node *Node) Encode() (*bytes.Buffer, error) {
+	content := bytes.NewBufferString(xml.Header)
+	namespaces := make(map[string]bool)
+	node.collectNamespaces(namespaces)
+	var declarations []xml.Attr
+	for namespace := range namespaces {
+		prefix, ok := prefixes[namespace]
+		if !ok {
+			return nil, fmt.Errorf("unknown namespace: %s", namespace)
+		}
+		declarations = append(declarations, xml.Attr{Name: xml.Name{Local: "xmlns:" + prefix}, Value: namespace})
+	}
+	sort.Slice(declarations, func(i, j int) bool { return declarations[i].Name.Local < declarations[j].Name.Local })
+	err := node.encode(content, 0, declarations)
+	if err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+func (node *Node) collectNamespaces(namespaces map[string]bool) {
+	if node.XMLName.Space != "" {
+		namespaces[node.XMLName.Space] = true
+	}
+	for _, attr := range node.Attrs {
+		if attr.Name.Space != "" && attr.Name.Space != "xmlns" {
+			namespaces[attr.Name.Space] = true
+		}
+	}
+	for _, child := range node.Children {
+		child.collectNamespaces(namespaces)
+	}
+}
+
+func (node *Node) encode(w io.Writer, depth int, declarations []xml.Attr) error {
+	indent := strings.Repeat("  ", depth)
+	name := qualifiedName(node.XMLName)
+	var start strings.Builder
+	start.WriteString(indent + "<" + name)
+	for _, attr := range append(declarations, node.Attrs...) {
+		if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+			continue
+		}
+		start.WriteString(" " + qualifiedName(attr.Name) + `="`)
+		err := xml.EscapeText(&start, []byte(attr.Value))
+		if err != nil {
+			return err
+		}
+		start.WriteString(`"`)
+	}
+	_, err := io.WriteString(w, start.String())
+	if err != nil {
+		return err
+	}
+
+	elements := node.Elements()
+	switch {
+	case len(node.Children) == 0 && node.Text() == "":
+		_, err = io.WriteString(w, "/>\n")
+		return err
+	case len(node.Children) == 0:
+		_, err = io.WriteString(w, ">")
+		if err == nil {
+			err = xml.EscapeText(w, []byte(node.Text()))
+		}
+	case len(elements) < len(node.Children):
+		// mixed content, whitespace is significant so write it on a single line
+		_, err = io.WriteString(w, ">")
+		for _, child := range node.Children {
+			if err != nil {
+				return err
+			}
+			if child.XMLName.Local == "" {
+				err = xml.EscapeText(w, []byte(child.Content))
+			} else {
+				err = child.encodeInline(w)
+			}
+		}
+	default:
+		_, err = io.WriteString(w, ">\n")
+		for _, child := range node.Children {
+			if err != nil {
+				return err
+			}
+			err = child.encode(w, depth+1, nil)
+		}
+		if err == nil {
+			_, err = io.WriteString(w, indent)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "</"+name+">\n")
+	return err
+}
+
+func (node *Node) encodeInline(w io.Writer) error {
+	var element bytes.Buffer
+	err := node.encode(&element, 0, nil)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(bytes.TrimSpace(element.Bytes()))
+	return err
+}
+
+func qualifiedName(name xml.Name) string {
+	if prefix, ok := prefixes[name.Space]; ok {
+		return prefix + ":" + name.Local
+	}
+	return name.Local
+}
@@ -0,0 +1,60 @@
+package sld
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAndEncode(t *testing.T) {
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<StyledLayerDescriptor xmlns="http://www.opengis.net/sld" xmlns:ogc="http://www.opengis.net/ogc" version="1.0.0">
+  <NamedLayer>
+    <Name>roads</Name>
+    <UserStyle>
+      <FeatureTypeStyle>
+        <Rule>
+          <TextSymbolizer>
+            <Label>Road <ogc:PropertyName>name</ogc:PropertyName> (<ogc:PropertyName>ref</ogc:PropertyName>)</Label>
+          </TextSymbolizer>
+        </Rule>
+      </FeatureTypeStyle>
+    </UserStyle>
+  </NamedLayer>
+</StyledLayerDescriptor>`
+	root, err := Parse([]byte(content))
+	require.Nil(t, err)
+	version, err := Version(root)
+	require.Nil(t, err)
+	require.Equal(t, Version10, version)
+
+	label := root.Child("NamedLayer").Child("UserStyle").Child("FeatureTypeStyle").Child("Rule").Child("TextSymbolizer").Child("Label")
+	require.Len(t, label.Children, 5)
+	require.Equal(t, "Road ", label.Children[0].Content)
+	require.Equal(t, "name", label.Children[1].Text())
+
+	encoded, err := root.Encode()
+	require.Nil(t, err)
+	require.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<sld:StyledLayerDescriptor xmlns:ogc="http://www.opengis.net/ogc" xmlns:sld="http://www.opengis.net/sld" version="1.0.0">
+  <sld:NamedLayer>
+    <sld:Name>roads</sld:Name>
+    <sld:UserStyle>
+      <sld:FeatureTypeStyle>
+        <sld:Rule>
+          <sld:TextSymbolizer>
+            <sld:Label>Road <ogc:PropertyName>name</ogc:PropertyName> (<ogc:PropertyName>ref</ogc:PropertyName>)</sld:Label>
+          </sld:TextSymbolizer>
+        </sld:Rule>
+      </sld:FeatureTypeStyle>
+    </sld:UserStyle>
+  </sld:NamedLayer>
+</sld:StyledLayerDescriptor>
+`, encoded.String())
+}
+
+func TestVersionFromMediaType(t *testing.T) {
+	require.Equal(t, Version10, VersionFromMediaType(map[string]string{"version": "1.0"}))
+	require.Equal(t, Version11, VersionFromMediaType(map[string]string{"version": "1.1"}))
+	require.Equal(t, Version10, VersionFromMediaType(nil))
+}
@@ -0,0 +1,546 @@
+package sld
+
+import (
+	"embed"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// schemas condensed, hand maintained versions of the official SLD, SE and Filter Encoding schemas, so SLD documents can
+// be validated without fetching the schemas from schemas.opengis.net. The parts they do not check are listed in the
+// README, keep it in sync when changing them.
+//
+//go:embed schemas
+var schemas embed.FS
+
+const xsdNamespace = "http://www.w3.org/2001/XMLSchema"
+
+var schemaFiles = map[string]string{
+	Version10: "schemas/sld/1.0.0/StyledLayerDescriptor.xsd",
+	Version11: "schemas/sld/1.1.0/StyledLayerDescriptor.xsd",
+}
+
+// schema the subset of XML Schema used by the bundled schemas: (abstract) element declarations with substitution
+// groups, complex types with sequence, choice and any particles, extensions, attributes and builtin simple types
+type schema struct {
+	elements      map[xml.Name]*elementDecl
+	types         map[xml.Name]*complexType
+	substitutions map[xml.Name][]xml.Name // substitution group head -> members
+}
+
+type elementDecl struct {
+	name     xml.Name
+	abstract bool
+	typeName xml.Name // a builtin simple type or a named complex type
+	complex  *complexType
+}
+
+type complexType struct {
+	mixed        bool
+	base         xml.Name // the extended complex type, if any
+	content      *particle
+	attributes   []attributeDecl
+	anyAttribute bool
+}
+
+type attributeDecl struct {
+	name     string
+	typeName xml.Name
+	required bool
+	fixed    *string
+}
+
+type particleKind int
+
+const (
+	elementParticle particleKind = iota
+	sequenceParticle
+	choiceParticle
+	anyParticle
+)
+
+type particle struct {
+	kind     particleKind
+	element  *elementDecl // a local element declaration
+	ref      xml.Name     // or a reference to a global element declaration
+	children []*particle
+	min, max int // max is -1 for unbounded
+}
+
+// loadSchema reads the schema of an SLD version, including the imported SE and Filter Encoding schemas
+func loadSchema(version string) (*schema, error) {
+	file, ok := schemaFiles[version]
+	if !ok {
+		return nil, fmt.Errorf("unsupported SLD version: %s", version)
+	}
+	s := &schema{
+		elements:      make(map[xml.Name]*elementDecl),
+		types:         make(map[xml.Name]*complexType),
+		substitutions: make(map[xml.Name][]xml.Name),
+	}
+	return s, s.load(file, make(map[string]bool))
+}
+
+func (s *schema) load(file string, loaded map[string]bool) error {
+	if loaded[file] {
+		return nil
+	}
+	loaded[file] = true
+	content, err := schemas.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	root, err := Parse(content)
+	if err != nil {
+		return fmt.Errorf("could not read schema %s: %s", file, err)
+	}
+	targetNamespace, _ := root.Attr("targetNamespace")
+	reader := schemaReader{targetNamespace: targetNamespace, prefixes: make(map[string]string)}
+	for _, attr := range root.Attrs {
+		if attr.Name.Space == "xmlns" {
+			reader.prefixes[attr.Name.Local] = attr.Value
+		}
+	}
+	for _, child := range root.Elements() {
+		switch child.XMLName.Local {
+		case "import":
+			location, _ := child.Attr("schemaLocation")
+			err = s.load(path.Join(path.Dir(file), location), loaded)
+		case "element":
+			var decl *elementDecl
+			decl, err = reader.element(child)
+			if err == nil {
+				s.elements[decl.name] = decl
+				if group, ok := child.Attr("substitutionGroup"); ok {
+					head := reader.qname(group)
+					s.substitutions[head] = append(s.substitutions[head], decl.name)
+				}
+			}
+		case "complexType":
+			var definition *complexType
+			name, _ := child.Attr("name")
+			definition, err = reader.complexType(child)
+			s.types[xml.Name{Space: targetNamespace, Local: name}] = definition
+		case "annotation":
+		default:
+			err = fmt.Errorf("unsupported schema construct: %s", child.XMLName.Local)
+		}
+		if err != nil {
+			return fmt.Errorf("could not read schema %s: %s", file, err)
+		}
+	}
+	return nil
+}
+
+type schemaReader struct {
+	targetNamespace string
+	prefixes        map[string]string
+}
+
+func (r schemaReader) qname(value string) xml.Name {
+	prefix, local, found := strings.Cut(value, ":")
+	if !found {
+		return xml.Name{Local: value}
+	}
+	return xml.Name{Space: r.prefixes[prefix], Local: local}
+}
+
+func (r schemaReader) element(node *Node) (*elementDecl, error) {
+	name, _ := node.Attr("name")
+	abstract, _ := node.Attr("abstract")
+	decl := &elementDecl{name: xml.Name{Space: r.targetNamespace, Local: name}, abstract: abstract == "true"}
+	if typeName, ok := node.Attr("type"); ok {
+		decl.typeName = r.qname(typeName)
+	}
+	var err error
+	if inline := node.Child("complexType"); inline != nil {
+		decl.complex, err = r.complexType(inline)
+	}
+	return decl, err
+}
+
+func (r schemaReader) complexType(node *Node) (*complexType, error) {
+	mixed, _ := node.Attr("mixed")
+	definition := &complexType{mixed: mixed == "true"}
+	declarations := node
+	if content := node.Child("complexContent"); content != nil {
+		extension := content.Child("extension")
+		if extension == nil {
+			return nil, fmt.Errorf("unsupported complexContent, only extensions are supported")
+		}
+		base, _ := extension.Attr("base")
+		definition.base = r.qname(base)
+		declarations = extension
+	}
+	for _, child := range declarations.Elements() {
+		var err error
+		switch child.XMLName.Local {
+		case "sequence", "choice":
+			definition.content, err = r.particle(child)
+		case "attribute":
+			name, _ := child.Attr("name")
+			typeName, _ := child.Attr("type")
+			use, _ := child.Attr("use")
+			attribute := attributeDecl{name: name, typeName: r.qname(typeName), required: use == "required"}
+			if fixed, ok := child.Attr("fixed"); ok {
+				attribute.fixed = &fixed
+			}
+			definition.attributes = append(definition.attributes, attribute)
+		case "anyAttribute":
+			definition.anyAttribute = true
+		case "annotation":
+		default:
+			err = fmt.Errorf("unsupported schema construct: %s", child.XMLName.Local)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return definition, nil
+}
+
+func (r schemaReader) particle(node *Node) (*particle, error) {
+	var err error
+	p := &particle{min: 1, max: 1}
+	if value, ok := node.Attr("minOccurs"); ok {
+		p.min, err = strconv.Atoi(value)
+	}
+	if value, ok := node.Attr("maxOccurs"); ok && err == nil {
+		if value == "unbounded" {
+			p.max = -1
+		} else {
+			p.max, err = strconv.Atoi(value)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid occurrence of %s: %s", node.XMLName.Local, err)
+	}
+	switch node.XMLName.Local {
+	case "element":
+		p.kind = elementParticle
+		if ref, ok := node.Attr("ref"); ok {
+			p.ref = r.qname(ref)
+		} else {
+			p.element, err = r.element(node)
+		}
+	case "any":
+		p.kind = anyParticle
+	case "sequence", "choice":
+		p.kind = sequenceParticle
+		if node.XMLName.Local == "choice" {
+			p.kind = choiceParticle
+		}
+		for _, child := range node.Elements() {
+			var childParticle *particle
+			childParticle, err = r.particle(child)
+			if err != nil {
+				return nil, err
+			}
+			p.children = append(p.children, childParticle)
+		}
+	default:
+		err = fmt.Errorf("unsupported schema construct: %s", node.XMLName.Local)
+	}
+	return p, err
+}
+
+// declaration the (concrete) declaration of an element particle for an element name, either the declaration itself
+// or a member of its substitution group
+func (s *schema) declaration(p *particle, name xml.Name) *elementDecl {
+	head := p.element
+	if head == nil {
+		head = s.elements[p.ref]
+	}
+	if head == nil {
+		return nil
+	}
+	return s.substitute(head, name)
+}
+
+func (s *schema) substitute(head *elementDecl, name xml.Name) *elementDecl {
+	if head.name == name {
+		if head.abstract {
+			return nil
+		}
+		return head
+	}
+	for _, member := range s.substitutions[head.name] {
+		if decl := s.substitute(s.elements[member], name); decl != nil {
+			return decl
+		}
+	}
+	return nil
+}
+
+// find the declaration of a child element in a content model, nil for unknown elements or elements matching xs:any
+func (s *schema) find(p *particle, name xml.Name) *elementDecl {
+	switch p.kind {
+	case elementParticle:
+		return s.declaration(p, name)
+	case sequenceParticle, choiceParticle:
+		for _, child := range p.children {
+			if decl := s.find(child, name); decl != nil {
+				return decl
+			}
+		}
+	}
+	return nil
+}
+
+// resolve the content model, attributes and mixed content of a complex type, including those of the extended types
+func (s *schema) resolve(definition *complexType) (content *particle, attributes []attributeDecl, anyAttribute bool, mixed bool) {
+	content, attributes, anyAttribute, mixed = definition.content, definition.attributes, definition.anyAttribute, definition.mixed
+	base, ok := s.types[definition.base]
+	if !ok {
+		return content, attributes, anyAttribute, mixed
+	}
+	baseContent, baseAttributes, baseAnyAttribute, baseMixed := s.resolve(base)
+	if baseContent != nil && content != nil {
+		content = &particle{kind: sequenceParticle, children: []*particle{baseContent, content}, min: 1, max: 1}
+	} else if baseContent != nil {
+		content = baseContent
+	}
+	return content, append(baseAttributes, attributes...), anyAttribute || baseAnyAttribute, mixed || baseMixed
+}
+
+// validate checks a document against the schema, starting at its root element
+func (s *schema) validate(root *Node) []Problem {
+	rootPath := "/" + qualifiedName(root.XMLName)
+	decl, ok := s.elements[root.XMLName]
+	if !ok || decl.abstract {
+		return []Problem{{Path: rootPath, Message: fmt.Sprintf("unexpected root element {%s}%s", root.XMLName.Space, root.XMLName.Local)}}
+	}
+	v := &schemaValidator{schema: s}
+	v.element(root, decl, rootPath)
+	return v.problems
+}
+
+type schemaValidator struct {
+	schema   *schema
+	problems []Problem
+}
+
+func (v *schemaValidator) problem(path string, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *schemaValidator) element(node *Node, decl *elementDecl, path string) {
+	definition := decl.complex
+	if definition == nil {
+		definition = v.schema.types[decl.typeName]
+	}
+	if definition == nil {
+		if decl.typeName.Space != xsdNamespace {
+			// no type, the content is not restricted (xs:anyType)
+			return
+		}
+		if elements := node.Elements(); len(elements) > 0 {
+			v.problem(path, "unexpected element %s, only text is allowed", qualifiedName(elements[0].XMLName))
+		} else if err := checkSimpleValue(decl.typeName.Local, node.Text()); err != nil {
+			v.problem(path, "%s", err)
+		}
+		return
+	}
+
+	content, attributes, anyAttribute, mixed := v.schema.resolve(definition)
+	v.attributes(node, attributes, anyAttribute, path)
+	if text := strings.TrimSpace(node.Content); !mixed && text != "" {
+		v.problem(path, "unexpected text %q", text)
+	}
+	elements := node.Elements()
+	paths := elementPaths(path, elements)
+	if content == nil {
+		if len(elements) > 0 {
+			v.problem(paths[0], "unexpected element %s, no elements are allowed", qualifiedName(elements[0].XMLName))
+		}
+		return
+	}
+	m := &matcher{schema: v.schema, elements: elements, expected: make(map[int]map[string]bool)}
+	if !m.match(content, 0)[len(elements)] {
+		if m.furthest < len(elements) {
+			v.problem(paths[m.furthest], "unexpected element %s%s", qualifiedName(elements[m.furthest].XMLName), m.expectation(m.furthest))
+		} else {
+			v.problem(path, "missing element%s", m.expectation(len(elements)))
+		}
+	}
+	for i, child := range elements {
+		if childDecl := v.schema.find(content, child.XMLName); childDecl != nil {
+			v.element(child, childDecl, paths[i])
+		}
+	}
+}
+
+func (v *schemaValidator) attributes(node *Node, attributes []attributeDecl, anyAttribute bool, path string) {
+	declared := make(map[string]bool)
+	for _, attribute := range attributes {
+		declared[attribute.name] = true
+		value, ok := node.Attr(attribute.name)
+		if !ok {
+			if attribute.required {
+				v.problem(path, "missing attribute %s", attribute.name)
+			}
+			continue
+		}
+		if attribute.fixed != nil && value != *attribute.fixed {
+			v.problem(path, "attribute %s should be %s, found %s", attribute.name, *attribute.fixed, value)
+		} else if attribute.typeName.Space == xsdNamespace {
+			if err := checkSimpleValue(attribute.typeName.Local, value); err != nil {
+				v.problem(path, "attribute %s: %s", attribute.name, err)
+			}
+		}
+	}
+	if anyAttribute {
+		return
+	}
+	for _, attr := range node.Attrs {
+		switch {
+		case attr.Name.Space == "xmlns", attr.Name.Space == "" && attr.Name.Local == "xmlns", attr.Name.Space == XsiNamespace:
+		case attr.Name.Space != "" || !declared[attr.Name.Local]:
+			v.problem(path, "unexpected attribute %s", qualifiedName(attr.Name))
+		}
+	}
+}
+
+// matcher matches child elements against a content model, it returns all positions a particle can end at. The
+// furthest position an element matched, and the elements expected at each position, are kept for error messages.
+type matcher struct {
+	schema   *schema
+	elements []*Node
+	furthest int
+	expected map[int]map[string]bool
+}
+
+func (m *matcher) match(p *particle, start int) map[int]bool {
+	result := make(map[int]bool)
+	if p.min == 0 {
+		result[start] = true
+	}
+	current := map[int]bool{start: true}
+	seen := map[int]bool{start: true}
+	for count := 1; p.max < 0 || count <= p.max; count++ {
+		next := make(map[int]bool)
+		progress := false
+		for position := range current {
+			for end := range m.matchOnce(p, position) {
+				next[end] = true
+				if !seen[end] {
+					seen[end] = true
+					progress = true
+				}
+			}
+		}
+		if count >= p.min {
+			for end := range next {
+				result[end] = true
+			}
+		}
+		if len(next) == 0 || (!progress && count >= p.min) {
+			break
+		}
+		current = next
+	}
+	return result
+}
+
+func (m *matcher) matchOnce(p *particle, start int) map[int]bool {
+	switch p.kind {
+	case anyParticle:
+		if start < len(m.elements) {
+			return m.matched(start)
+		}
+	case elementParticle:
+		if start < len(m.elements) && m.schema.declaration(p, m.elements[start].XMLName) != nil {
+			return m.matched(start)
+		}
+		if m.expected[start] == nil {
+			m.expected[start] = make(map[string]bool)
+		}
+		name := p.ref
+		if p.element != nil {
+			name = p.element.name
+		}
+		m.expected[start][qualifiedName(name)] = true
+	case sequenceParticle:
+		positions := map[int]bool{start: true}
+		for _, child := range p.children {
+			next := make(map[int]bool)
+			for position := range positions {
+				for end := range m.match(child, position) {
+					next[end] = true
+				}
+			}
+			positions = next
+		}
+		return positions
+	case choiceParticle:
+		positions := make(map[int]bool)
+		for _, child := range p.children {
+			for end := range m.match(child, start) {
+				positions[end] = true
+			}
+		}
+		return positions
+	}
+	return nil
+}
+
+func (m *matcher) matched(position int) map[int]bool {
+	if position+1 > m.furthest {
+		m.furthest = position + 1
+	}
+	return map[int]bool{position + 1: true}
+}
+
+func (m *matcher) expectation(position int) string {
+	var names []string
+	for name := range m.expected[position] {
+		names = append(names, name)
+	}
+	if names == nil {
+		return ""
+	}
+	sort.Strings(names)
+	return ", expected " + strings.Join(names, " or ")
+}
+
+// checkSimpleValue checks the value of an element or attribute with a builtin simple type
+func checkSimpleValue(typeName string, value string) error {
+	value = strings.TrimSpace(value)
+	var err error
+	switch typeName {
+	case "double", "float", "decimal":
+		_, err = strconv.ParseFloat(value, 64)
+	case "integer", "int", "long":
+		_, err = strconv.ParseInt(value, 10, 64)
+	case "boolean":
+		if value != "true" && value != "false" && value != "1" && value != "0" {
+			err = fmt.Errorf("not a boolean")
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s: %q", typeName, value)
+	}
+	return nil
+}
+
+// elementPaths the XPath like paths of child elements, with a position for elements with siblings of the same name
+func elementPaths(parent string, elements []*Node) []string {
+	counts := make(map[xml.Name]int)
+	for _, element := range elements {
+		counts[element.XMLName]++
+	}
+	positions := make(map[xml.Name]int)
+	paths := make([]string, len(elements))
+	for i, element := range elements {
+		paths[i] = parent + "/" + qualifiedName(element.XMLName)
+		if counts[element.XMLName] > 1 {
+			positions[element.XMLName]++
+			paths[i] += fmt.Sprintf("[%d]", positions[element.XMLName])
+		}
+	}
+	return paths
+}
@@ -0,0 +1,100 @@
+package sld
+
+import (
+	"fmt"
+)
+
+const (
+	SldNamespace   = "http://www.opengis.net/sld"
+	SeNamespace    = "http://www.opengis.net/se"
+	OgcNamespace   = "http://www.opengis.net/ogc"
+	GmlNamespace   = "http://www.opengis.net/gml"
+	XlinkNamespace = "http://www.w3.org/1999/xlink"
+	XsiNamespace   = "http://www.w3.org/2001/XMLSchema-instance"
+
+	Version10 = "1.0.0"
+	Version11 = "1.1.0"
+)
+
+var schemaLocations = map[string]string{
+	Version10: "http://www.opengis.net/sld http://schemas.opengis.net/sld/1.0.0/StyledLayerDescriptor.xsd",
+	Version11: "http://www.opengis.net/sld http://schemas.opengis.net/sld/1.1.0/StyledLayerDescriptor.xsd",
+}
+
+// Version the version of a StyledLayerDescriptor root node
+func Version(root *Node) (string, error) {
+	if root.XMLName.Local != "StyledLayerDescriptor" {
+		return "", fmt.Errorf("root element should be StyledLayerDescriptor, found: %s", root.XMLName.Local)
+	}
+	version, ok := root.Attr("version")
+	if !ok {
+		return "", fmt.Errorf("StyledLayerDescriptor has no version attribute")
+	}
+	if _, ok = schemaLocations[version]; !ok {
+		return "", fmt.Errorf("unsupported SLD version: %s", version)
+	}
+	return version, nil
+}
+
+// VersionFromMediaType the SLD version of the version parameter of an SLD media type (e.g. version=1.1), defaults to 1.0.0
+func VersionFromMediaType(params map[string]string) string {
+	if params["version"] == "1.1" || params["version"] == Version11 {
+		return Version11
+	}
+	return Version10
+}
+
+// Writer builds the elements of an SLD document of a specific version. In SLD 1.0 the symbology elements are part
+// of the SLD namespace, SLD 1.1 takes them from Symbology Encoding (SE) 1.1 (and SvgParameter replaces CssParameter).
+type Writer struct {
+	Version string
+}
+
+// NewStyledLayerDescriptor creates the root element of the SLD
+func (w Writer) NewStyledLayerDescriptor(namedLayers ...*Node) *Node {
+	root := NewNode(SldNamespace, "StyledLayerDescriptor", namedLayers...)
+	root.SetAttr("", "version", w.Version)
+	root.SetAttr(XsiNamespace, "schemaLocation", schemaLocations[w.Version])
+	return root
+}
+
+// Sld creates an element of the SLD namespace
+func (w Writer) Sld(local string, children ...*Node) *Node {
+	return NewNode(SldNamespace, local, children...)
+}
+
+// Se creates a symbology element, in the SLD namespace for SLD 1.0 and the SE namespace for SLD 1.1
+func (w Writer) Se(local string, children ...*Node) *Node {
+	return NewNode(w.seNamespace(), local, children...)
+}
+
+// SeText creates a symbology element containing text
+func (w Writer) SeText(local string, text string) *Node {
+	return NewTextNode(w.seNamespace(), local, text)
+}
+
+// Parameter creates a CssParameter (SLD 1.0) or SvgParameter (SLD 1.1)
+func (w Writer) Parameter(name string, value string) *Node {
+	local := "CssParameter"
+	if w.Version == Version11 {
+		local = "SvgParameter"
+	}
+	return NewTextNode(w.seNamespace(), local, value).SetAttr("", "name", name)
+}
+
+// Ogc creates an element of the (filter encoding) OGC namespace
+func (w Writer) Ogc(local string, children ...*Node) *Node {
+	return NewNode(OgcNamespace, local, children...)
+}
+
+// OgcText creates an element of the OGC namespace containing text
+func (w Writer) OgcText(local string, text string) *Node {
+	return NewTextNode(OgcNamespace, local, text)
+}
+
+func (w Writer) seNamespace() string {
+	if w.Version == Version11 {
+		return SeNamespace
+	}
+	return SldNamespace
+}
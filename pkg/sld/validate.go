@@ -0,0 +1,144 @@
+package sld
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Problem a violation of the SLD schemas or of the symbology rules at an XPath like path in the document
+type Problem struct {
+	Path    string
+	Message string
+}
+
+func (problem Problem) String() string {
+	return fmt.Sprintf("%s: %s", problem.Path, problem.Message)
+}
+
+var hexColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+var colorParameters = map[string]bool{"fill": true, "stroke": true}
+
+var opacityParameters = map[string]bool{"fill-opacity": true, "stroke-opacity": true}
+
+// Validate checks an SLD 1.0 or SLD 1.1 (with SE 1.1) document against the bundled schemas, followed by the checks the
+// schemas can not express: hexadecimal colors, opacities between 0 and 1, non-negative widths and sizes and
+// non-negative, ascending, scale denominators.
+func Validate(content []byte) []Problem {
+	root, err := Parse(content)
+	if err != nil {
+		return []Problem{{Path: "/", Message: err.Error()}}
+	}
+	version, err := Version(root)
+	if err != nil {
+		return []Problem{{Path: "/", Message: err.Error()}}
+	}
+	s, err := loadSchema(version)
+	if err != nil {
+		return []Problem{{Path: "/", Message: fmt.Sprintf("could not load schema: %s", err)}}
+	}
+	problems := s.validate(root)
+	return append(problems, checkSymbology(root, "/"+qualifiedName(root.XMLName))...)
+}
+
+func checkSymbology(node *Node, path string) (problems []Problem) {
+	check := func(err error) {
+		if err != nil {
+			problems = append(problems, Problem{Path: path, Message: err.Error()})
+		}
+	}
+	switch node.XMLName.Local {
+	case "CssParameter", "SvgParameter":
+		name, _ := node.Attr("name")
+		if value, ok := literalValue(node); ok {
+			switch {
+			case colorParameters[name]:
+				if !hexColor.MatchString(value) {
+					check(fmt.Errorf("invalid %s color %q, expected a hexadecimal #RRGGBB color", name, value))
+				}
+			case opacityParameters[name]:
+				check(checkOpacity(value))
+			case name == "stroke-width":
+				check(checkNonNegative(value))
+			}
+		}
+	case "Opacity":
+		if value, ok := literalValue(node); ok {
+			check(checkOpacity(value))
+		}
+	case "Size", "Radius":
+		if value, ok := literalValue(node); ok {
+			check(checkNonNegative(value))
+		}
+	case "Rule":
+		problems = append(problems, checkScaleDenominators(node, path)...)
+	}
+	elements := node.Elements()
+	paths := elementPaths(path, elements)
+	for i, child := range elements {
+		problems = append(problems, checkSymbology(child, paths[i])...)
+	}
+	return problems
+}
+
+// checkScaleDenominators checks the scale denominators of a rule are not negative and the minimum (inclusive) is
+// less than the maximum (exclusive), otherwise the rule would never apply
+func checkScaleDenominators(rule *Node, path string) (problems []Problem) {
+	denominators := make(map[string]float64)
+	elements := rule.Elements()
+	paths := elementPaths(path, elements)
+	for i, element := range elements {
+		local := element.XMLName.Local
+		if local != "MinScaleDenominator" && local != "MaxScaleDenominator" {
+			continue
+		}
+		// invalid numbers are reported by the schema validation
+		if denominator, err := strconv.ParseFloat(element.Text(), 64); err == nil {
+			if denominator < 0 {
+				problems = append(problems, Problem{Path: paths[i], Message: fmt.Sprintf("scale denominator should not be negative, found %s", element.Text())})
+			}
+			denominators[local] = denominator
+		}
+	}
+	minimum, hasMinimum := denominators["MinScaleDenominator"]
+	maximum, hasMaximum := denominators["MaxScaleDenominator"]
+	if hasMinimum && hasMaximum && minimum >= maximum {
+		problems = append(problems, Problem{Path: path, Message: fmt.Sprintf("MinScaleDenominator %g should be less than MaxScaleDenominator %g", minimum, maximum)})
+	}
+	return problems
+}
+
+// literalValue the value of a parameter with a constant value, either as text or as a single ogc:Literal
+func literalValue(node *Node) (string, bool) {
+	elements := node.Elements()
+	switch {
+	case len(elements) == 0:
+		return node.Text(), true
+	case len(elements) == 1 && elements[0].XMLName.Local == "Literal" && node.Text() == "":
+		return elements[0].Text(), true
+	}
+	return "", false
+}
+
+func checkOpacity(value string) error {
+	opacity, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("invalid opacity %q", value)
+	}
+	if opacity < 0 || opacity > 1 {
+		return fmt.Errorf("opacity should be between 0 and 1, found %s", value)
+	}
+	return nil
+}
+
+func checkNonNegative(value string) error {
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", value)
+	}
+	if number < 0 {
+		return fmt.Errorf("should not be negative, found %s", value)
+	}
+	return nil
+}
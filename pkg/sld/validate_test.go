@@ -0,0 +1,100 @@
+package sld
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateExample(t *testing.T) {
+	content, err := os.ReadFile("../../examples/assets/daraa-sld.sld")
+	require.Nil(t, err)
+	require.Empty(t, Validate(content))
+}
+
+func TestValidate(t *testing.T) {
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<StyledLayerDescriptor xmlns="http://www.opengis.net/sld" xmlns:ogc="http://www.opengis.net/ogc" version="1.0.0">
+  <NamedLayer>
+    <Name>roads</Name>
+    <UserStyle>
+      <FeatureTypeStyle>
+        <Rule>
+          <Title>roads</Title>
+          <Name>roads</Name>
+          <MaxScaleDenominator>-100</MaxScaleDenominator>
+          <LineSymbolizer>
+            <Stroke>
+              <CssParameter name="stroke">red</CssParameter>
+              <CssParameter name="stroke-opacity">2</CssParameter>
+            </Stroke>
+          </LineSymbolizer>
+        </Rule>
+        <Rule>
+          <ogc:Filter>
+            <ogc:PropertyIsEqualTo>
+              <ogc:PropertyName>type</ogc:PropertyName>
+            </ogc:PropertyIsEqualTo>
+          </ogc:Filter>
+          <MinScaleDenominator>large</MinScaleDenominator>
+        </Rule>
+        <Rule priority="1">
+          <MinScaleDenominator>1000</MinScaleDenominator>
+          <MaxScaleDenominator>100</MaxScaleDenominator>
+          <PointSymbolizer>
+            <Graphic>
+              <Size>-3</Size>
+            </Graphic>
+          </PointSymbolizer>
+        </Rule>
+      </FeatureTypeStyle>
+    </UserStyle>
+  </NamedLayer>
+</StyledLayerDescriptor>`
+	var problems []string
+	for _, problem := range Validate([]byte(content)) {
+		problems = append(problems, problem.String())
+	}
+	rules := "/sld:StyledLayerDescriptor/sld:NamedLayer/sld:UserStyle/sld:FeatureTypeStyle/sld:Rule"
+	require.Equal(t, []string{
+		rules + "[1]/sld:Name: unexpected element sld:Name, expected ogc:Filter or sld:Abstract or sld:ElseFilter or sld:LegendGraphic or sld:MaxScaleDenominator or sld:MinScaleDenominator or sld:Symbolizer",
+		rules + "[2]: missing element, expected sld:MaxScaleDenominator or sld:Symbolizer",
+		rules + "[2]/ogc:Filter/ogc:PropertyIsEqualTo: missing element, expected ogc:expression",
+		rules + `[2]/sld:MinScaleDenominator: invalid double: "large"`,
+		rules + "[3]: unexpected attribute priority",
+		rules + "[1]/sld:MaxScaleDenominator: scale denominator should not be negative, found -100",
+		rules + `[1]/sld:LineSymbolizer/sld:Stroke/sld:CssParameter[1]: invalid stroke color "red", expected a hexadecimal #RRGGBB color`,
+		rules + "[1]/sld:LineSymbolizer/sld:Stroke/sld:CssParameter[2]: opacity should be between 0 and 1, found 2",
+		rules + "[3]: MinScaleDenominator 1000 should be less than MaxScaleDenominator 100",
+		rules + "[3]/sld:PointSymbolizer/sld:Graphic/sld:Size: should not be negative, found -3",
+	}, problems)
+}
+
+func TestValidateVersion11(t *testing.T) {
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<sld:StyledLayerDescriptor xmlns:sld="http://www.opengis.net/sld" xmlns:se="http://www.opengis.net/se" version="1.1.0">
+  <sld:NamedLayer>
+    <se:Name>roads</se:Name>
+    <sld:UserStyle>
+      <se:FeatureTypeStyle>
+        <se:Rule>
+          <se:LineSymbolizer>
+            <se:Stroke>
+              <sld:CssParameter name="stroke">#ff0000</sld:CssParameter>
+            </se:Stroke>
+          </se:LineSymbolizer>
+        </se:Rule>
+      </se:FeatureTypeStyle>
+    </sld:UserStyle>
+  </sld:NamedLayer>
+</sld:StyledLayerDescriptor>`
+	problems := Validate([]byte(content))
+	require.Len(t, problems, 1)
+	require.Equal(t, "/sld:StyledLayerDescriptor/sld:NamedLayer/sld:UserStyle/se:FeatureTypeStyle/se:Rule/se:LineSymbolizer/se:Stroke/sld:CssParameter: unexpected element sld:CssParameter, expected se:GraphicFill or se:GraphicStroke or se:SvgParameter", problems[0].String())
+}
+
+func TestValidateUnsupportedVersion(t *testing.T) {
+	problems := Validate([]byte(`<StyledLayerDescriptor xmlns="http://www.opengis.net/sld" version="2.0.0"/>`))
+	require.Equal(t, []Problem{{Path: "/", Message: "unsupported SLD version: 2.0.0"}}, problems)
+}
@@ -0,0 +1,240 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pdok/goas/pkg/models"
+)
+
+// spritePixelRatios the sprite sheets generated for each style with a sprite, 1x and 2x (sprite@2x.png)
+var spritePixelRatios = []int{1, 2}
+
+// spriteIcon the position of an icon in a sprite sheet - https://docs.mapbox.com/mapbox-gl-js/style-spec/sprite/
+type spriteIcon struct {
+	Width      int `json:"width"`
+	Height     int `json:"height"`
+	X          int `json:"x"`
+	Y          int `json:"y"`
+	PixelRatio int `json:"pixelRatio"`
+}
+
+type namedImage struct {
+	name  string
+	image image.Image
+}
+
+// spriteUrl the url of the sprite of a style, without extension as the Mapbox sprite property expects
+func spriteUrl(stylesConfig *models.StylesConfig, styleId string) string {
+	return *models.PreloadRelation.MustToUrl(stylesConfig.BaseResource, styleId+"/sprite")
+}
+
+// generateSprites packs the PNG icons in the sprite icons directory of the style into sprite sheets, at 1x and 2x. The
+// sheets and their index (sprite.png, sprite.json, sprite@2x.png and sprite@2x.json) are published as preload resources
+// under resources/{styleId}/. An icon name@2x.png, twice the size of name.png, is used for the 2x sheet, otherwise the
+// icon is scaled up.
+func generateSprites(stylesConfig *models.StylesConfig, styleMetadata models.StyleMetadata, assetDir string) ([]models.Document, []models.Link, error) {
+	icons, err := readSpriteIcons(filepath.Join(assetDir, styleMetadata.Sprite.Icons))
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not generate the sprite of style %s: %s", styleMetadata.Id, err)
+	}
+	var documents []models.Document
+	var links []models.Link
+	for _, pixelRatio := range spritePixelRatios {
+		var images []namedImage
+		for _, name := range sortedIconNames(icons) {
+			images = append(images, namedImage{name, icons[name].scaled(pixelRatio)})
+		}
+		sheet, index := packSprite(images, pixelRatio)
+
+		suffix := ""
+		if pixelRatio > 1 {
+			suffix = fmt.Sprintf("@%dx", pixelRatio)
+		}
+		var png bytes.Buffer
+		err = encodePng(&png, sheet)
+		if err != nil {
+			return nil, nil, err
+		}
+		indexContent, err := json.MarshalIndent(index, "", "  ")
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, resource := range []struct {
+			extension string
+			mediaType models.MediaType
+			content   *bytes.Buffer
+		}{{"png", models.PngMediaType, &png}, {"json", models.JsonMediaType, bytes.NewBuffer(indexContent)}} {
+			mediaType := resource.mediaType
+			identifier := fmt.Sprintf("%s/sprite%s.%s", styleMetadata.Id, suffix, resource.extension)
+			title := fmt.Sprintf("sprite of style %s (%dx)", styleMetadata.Id, pixelRatio)
+			document := models.Document{
+				Path:      models.PreloadRelation.MustToPath(identifier),
+				MediaType: mediaType,
+				Content:   resource.content,
+				Source:    styleMetadata.Sprite.Icons,
+			}
+			link := models.Link{
+				Href:  models.PreloadRelation.MustToUrl(stylesConfig.BaseResource, identifier),
+				Rel:   models.PreloadRelation,
+				Type:  &mediaType,
+				Title: &title,
+			}
+			describeContent(&link, &document, stylesConfig.LinkChecksums)
+			documents = append(documents, document)
+			links = append(links, link)
+		}
+	}
+	return documents, links, nil
+}
+
+// spriteIconImages an icon, with the 2x image if provided
+type spriteIconImages struct {
+	image   image.Image
+	image2x image.Image
+}
+
+func (icon spriteIconImages) scaled(pixelRatio int) image.Image {
+	if pixelRatio == 2 && icon.image2x != nil {
+		return icon.image2x
+	}
+	return scaleNearest(icon.image, pixelRatio)
+}
+
+func readSpriteIcons(dir string) (map[string]*spriteIconImages, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not read sprite icons directory %s", dir)
+	}
+	icons := make(map[string]*spriteIconImages)
+	for _, entry := range entries {
+		filename := entry.Name()
+		if entry.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(filename, ".svg") {
+			return nil, fmt.Errorf("icon %s is an SVG, only PNG icons are supported", filename)
+		}
+		if !strings.HasSuffix(filename, ".png") {
+			continue
+		}
+		file, err := os.Open(filepath.Join(dir, filename))
+		if err != nil {
+			return nil, err
+		}
+		img, err := png.Decode(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("icon %s is not a valid PNG: %s", filename, err)
+		}
+		name := strings.TrimSuffix(filename, ".png")
+		is2x := strings.HasSuffix(name, "@2x")
+		name = strings.TrimSuffix(name, "@2x")
+		if icons[name] == nil {
+			icons[name] = &spriteIconImages{}
+		}
+		if is2x {
+			icons[name].image2x = img
+		} else {
+			icons[name].image = img
+		}
+	}
+	for _, name := range sortedIconNames(icons) {
+		if icons[name].image == nil {
+			return nil, fmt.Errorf("icon %s@2x.png has no 1x icon %s.png", name, name)
+		}
+		if image2x := icons[name].image2x; image2x != nil {
+			size, size2x := icons[name].image.Bounds().Size(), image2x.Bounds().Size()
+			if size2x != size.Mul(2) {
+				return nil, fmt.Errorf("icon %s@2x.png is %dx%d, should be twice the %dx%d of %s.png",
+					name, size2x.X, size2x.Y, size.X, size.Y, name)
+			}
+		}
+	}
+	if len(icons) == 0 {
+		return nil, fmt.Errorf("no PNG icons found in %s", dir)
+	}
+	return icons, nil
+}
+
+func sortedIconNames(icons map[string]*spriteIconImages) []string {
+	names := make([]string, 0, len(icons))
+	for name := range icons {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// packSprite packs the images on shelves, tallest first, into a roughly square sheet. The images are separated by one
+// (scaled) pixel, so they do not bleed into each other when rendered with interpolation.
+func packSprite(images []namedImage, pixelRatio int) (*image.NRGBA, map[string]spriteIcon) {
+	padding := pixelRatio
+	sort.SliceStable(images, func(i, j int) bool {
+		return images[i].image.Bounds().Dy() > images[j].image.Bounds().Dy()
+	})
+	area, maxWidth := 0, 0
+	for _, img := range images {
+		bounds := img.image.Bounds()
+		area += (bounds.Dx() + padding) * (bounds.Dy() + padding)
+		if bounds.Dx() > maxWidth {
+			maxWidth = bounds.Dx()
+		}
+	}
+	sheetWidth := int(math.Ceil(math.Sqrt(float64(area))))
+	if sheetWidth < maxWidth {
+		sheetWidth = maxWidth
+	}
+
+	index := make(map[string]spriteIcon)
+	x, y, shelfHeight, width := 0, 0, 0, 0
+	for _, img := range images {
+		bounds := img.image.Bounds()
+		if x > 0 && x+bounds.Dx() > sheetWidth {
+			x, y, shelfHeight = 0, y+shelfHeight+padding, 0
+		}
+		index[img.name] = spriteIcon{Width: bounds.Dx(), Height: bounds.Dy(), X: x, Y: y, PixelRatio: pixelRatio}
+		if bounds.Dy() > shelfHeight {
+			shelfHeight = bounds.Dy()
+		}
+		if x+bounds.Dx() > width {
+			width = x + bounds.Dx()
+		}
+		x += bounds.Dx() + padding
+	}
+	sheet := image.NewNRGBA(image.Rect(0, 0, width, y+shelfHeight))
+	for _, img := range images {
+		icon := index[img.name]
+		draw.Draw(sheet, image.Rect(icon.X, icon.Y, icon.X+icon.Width, icon.Y+icon.Height), img.image, img.image.Bounds().Min, draw.Src)
+	}
+	return sheet, index
+}
+
+// scaleNearest scales the image up by repeating each pixel, which keeps the edges of icons sharp
+func scaleNearest(img image.Image, factor int) image.Image {
+	if factor == 1 {
+		return img
+	}
+	bounds := img.Bounds()
+	scaled := image.NewNRGBA(image.Rect(0, 0, bounds.Dx()*factor, bounds.Dy()*factor))
+	for y := 0; y < bounds.Dy()*factor; y++ {
+		for x := 0; x < bounds.Dx()*factor; x++ {
+			scaled.Set(x, y, img.At(bounds.Min.X+x/factor, bounds.Min.Y+y/factor))
+		}
+	}
+	return scaled
+}
+
+func encodePng(buffer *bytes.Buffer, img image.Image) error {
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	return encoder.Encode(buffer, img)
+}
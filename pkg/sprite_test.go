@@ -0,0 +1,77 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pdok/goas/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSprites(t *testing.T) {
+	config, _ := ParseConfig("../examples/generate_config.yaml")
+	documents, err := GenerateDocuments(config, "../examples/assets", []models.Format{models.JsonFormat})
+	require.Nil(t, err)
+
+	sprites := map[string]models.Document{}
+	for _, document := range documents {
+		sprites[document.Path] = document
+	}
+	for _, pixelRatio := range []struct {
+		suffix string
+		ratio  int
+	}{{"", 1}, {"@2x", 2}} {
+		sheet, err := png.Decode(bytes.NewReader(sprites["resources/daraa/sprite"+pixelRatio.suffix+".png"].Content.Bytes()))
+		require.Nil(t, err)
+		var index map[string]spriteIcon
+		require.Nil(t, json.Unmarshal(sprites["resources/daraa/sprite"+pixelRatio.suffix+".json"].Content.Bytes(), &index))
+		require.Len(t, index, 3)
+		assert.Equal(t, 16*pixelRatio.ratio, index["well"].Width)
+		assert.Equal(t, 14*pixelRatio.ratio, index["ruins"].Height)
+		for name, icon := range index {
+			assert.Equal(t, pixelRatio.ratio, icon.PixelRatio)
+			assert.True(t, image.Rect(icon.X, icon.Y, icon.X+icon.Width, icon.Y+icon.Height).In(sheet.Bounds()), name)
+			for other, otherIcon := range index {
+				if other != name {
+					assert.False(t, image.Rect(icon.X, icon.Y, icon.X+icon.Width, icon.Y+icon.Height).Overlaps(
+						image.Rect(otherIcon.X, otherIcon.Y, otherIcon.X+otherIcon.Width, otherIcon.Y+otherIcon.Height)), "%s overlaps %s", name, other)
+				}
+			}
+		}
+	}
+
+	var style map[string]interface{}
+	require.Nil(t, json.Unmarshal(sprites["styles/daraa.mapbox.json"].Content.Bytes(), &style))
+	assert.Equal(t, "https://example.org/catalog/1.0/resources/daraa/sprite", style["sprite"])
+
+	var metadata models.StyleMetadata
+	require.Nil(t, json.Unmarshal(sprites["styles/daraa/metadata.json"].Content.Bytes(), &metadata))
+	require.Len(t, metadata.Links, 5)
+	assert.Equal(t, models.PreloadRelation, metadata.Links[0].Rel)
+	assert.Equal(t, "https://example.org/catalog/1.0/resources/daraa/sprite.png", *metadata.Links[0].Href)
+	assert.Equal(t, sprites["resources/daraa/sprite.png"].Content.Len(), *metadata.Links[0].Length)
+}
+
+func TestReadSpriteIconsSvg(t *testing.T) {
+	dir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "marker.svg"), []byte("<svg/>"), 0644))
+	_, err := readSpriteIcons(dir)
+	assert.EqualError(t, err, "icon marker.svg is an SVG, only PNG icons are supported")
+}
+
+func TestReadSpriteIcons2xSize(t *testing.T) {
+	dir := t.TempDir()
+	for filename, size := range map[string]int{"marker.png": 8, "marker@2x.png": 12} {
+		var content bytes.Buffer
+		require.Nil(t, png.Encode(&content, image.NewNRGBA(image.Rect(0, 0, size, size))))
+		require.Nil(t, os.WriteFile(filepath.Join(dir, filename), content.Bytes(), 0644))
+	}
+	_, err := readSpriteIcons(dir)
+	assert.EqualError(t, err, "icon marker@2x.png is 12x12, should be twice the 8x8 of marker.png")
+}
@@ -0,0 +1,117 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/pdok/goas/pkg/convert"
+	"github.com/pdok/goas/pkg/mapbox"
+	"github.com/pdok/goas/pkg/models"
+	"github.com/pdok/goas/pkg/sld"
+)
+
+// stylesheetConverter converts the content of a source stylesheet to the encoding of the given stylesheet
+type stylesheetConverter func(source []byte, stylesheet models.StyleSheet, styleMetadata models.StyleMetadata) (*bytes.Buffer, error)
+
+// stylesheetConverters the supported conversions, by (root) media type of the source and the generated stylesheet
+var stylesheetConverters = map[models.MediaType]map[models.MediaType]stylesheetConverter{
+	models.MapboxMediaType: {models.SldMediaType: mapboxToSld},
+	models.SldMediaType:    {models.MapboxMediaType: sldToMapbox},
+}
+
+// generateConvertedStylesheet generates a stylesheet by converting the (template executed) asset of the stylesheet of
+// the same style it is generated from
+func generateConvertedStylesheet(stylesheet models.StyleSheet, styleMetadata models.StyleMetadata, assetDir string, stylesConfig *models.StylesConfig) (*models.Document, error) {
+	source, converter, err := findStylesheetConversion(stylesheet, styleMetadata, stylesConfig.AdditionalFormats)
+	if err != nil {
+		return nil, err
+	}
+	sourceDocument, err := generateAssetFromSource(source.Link, styleMetadata.Id, assetDir, stylesConfig, true)
+	if err != nil {
+		return nil, err
+	}
+	content, err := converter(sourceDocument.Content.Bytes(), stylesheet, styleMetadata)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate stylesheet %s of style %s from %s: %s", *stylesheet.Link.Type, styleMetadata.Id, *source.Link.AssetFilename, err)
+	}
+	path, err := stylesheet.Link.ToPath(styleMetadata.Id, stylesConfig.AdditionalFormats)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Document{Path: path, MediaType: *stylesheet.Link.Type, Content: content, Source: sourceDocument.Source}, nil
+}
+
+// findStylesheetConversion finds the stylesheet (with an asset) the stylesheet is generated from, by its (versioned)
+// format name, and the converter between them
+func findStylesheetConversion(stylesheet models.StyleSheet, styleMetadata models.StyleMetadata, additionalFormats []models.Format) (*models.StyleSheet, stylesheetConverter, error) {
+	if stylesheet.Link.Type == nil {
+		return nil, nil, fmt.Errorf("stylesheet generated from %s of style %s has no type", *stylesheet.GenerateFrom, styleMetadata.Id)
+	}
+	var source *models.StyleSheet
+	for i, candidate := range styleMetadata.Stylesheets {
+		if candidate.GenerateFrom != nil || candidate.Link.AssetFilename == nil || candidate.Link.Type == nil {
+			continue
+		}
+		if candidate.Link.Type.ToFormat(additionalFormats, true).Name == *stylesheet.GenerateFrom ||
+			candidate.Link.Type.ToFormat(additionalFormats, false).Name == *stylesheet.GenerateFrom {
+			source = &styleMetadata.Stylesheets[i]
+			break
+		}
+	}
+	if source == nil {
+		return nil, nil, fmt.Errorf("no stylesheet with an asset-filename and format %s found in style %s to generate %s from",
+			*stylesheet.GenerateFrom, styleMetadata.Id, *stylesheet.Link.Type)
+	}
+	sourceRoot, _ := source.Link.Type.SplitParams()
+	targetRoot, _ := stylesheet.Link.Type.SplitParams()
+	converter, ok := stylesheetConverters[sourceRoot][targetRoot]
+	if !ok {
+		return nil, nil, fmt.Errorf("cannot generate %s from %s in style %s", targetRoot, sourceRoot, styleMetadata.Id)
+	}
+	return source, converter, nil
+}
+
+func mapboxToSld(source []byte, stylesheet models.StyleSheet, styleMetadata models.StyleMetadata) (*bytes.Buffer, error) {
+	style, err := mapbox.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	_, params := stylesheet.Link.Type.SplitParams()
+	options := convert.SldOptions{Version: sld.VersionFromMediaType(params), Name: styleMetadata.Id, Zoom: convert.DefaultZoom}
+	if styleMetadata.Title != nil {
+		options.Title = *styleMetadata.Title
+	}
+	root, err := convert.MapboxToSld(style, options)
+	if err != nil {
+		return nil, err
+	}
+	return root.Encode()
+}
+
+func sldToMapbox(source []byte, stylesheet models.StyleSheet, styleMetadata models.StyleMetadata) (*bytes.Buffer, error) {
+	root, err := sld.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	options := convert.MapboxOptions{Name: styleMetadata.Id, Layers: make(map[string]convert.LayerSource)}
+	if styleMetadata.Title != nil {
+		options.Name = *styleMetadata.Title
+	}
+	if stylesheet.Mapbox != nil {
+		if stylesheet.Mapbox.Sprite != nil {
+			options.Sprite = *stylesheet.Mapbox.Sprite
+		}
+		if stylesheet.Mapbox.Glyphs != nil {
+			options.Glyphs = *stylesheet.Mapbox.Glyphs
+		}
+		options.Sources = stylesheet.Mapbox.Sources
+		for _, layer := range stylesheet.Mapbox.Layers {
+			options.Layers[layer.NamedLayer] = convert.LayerSource{Source: layer.Source, SourceLayer: layer.SourceLayer}
+		}
+	}
+	style, err := convert.SldToMapbox(root, options)
+	if err != nil {
+		return nil, err
+	}
+	return style.Encode()
+}
@@ -1,12 +1,27 @@
 package pkg
 
 import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
 	"fmt"
+	"github.com/pdok/goas/pkg/mapbox"
 	"github.com/pdok/goas/pkg/models"
+	"github.com/pdok/goas/pkg/sld"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
 	"strings"
 )
 
-func Validate(stylesConfig *models.StylesConfig) error {
+// stylesheetValidators check the (template executed or generated) content of a stylesheet, by root media type
+var stylesheetValidators = map[models.MediaType]func(content []byte) []string{
+	models.MapboxMediaType: validateMapboxStylesheet,
+	models.SldMediaType:    validateSldStylesheet,
+}
+
+func Validate(stylesConfig *models.StylesConfig, assetDir string) error {
 	var errors []string
 	err := validateUniqueStyles(stylesConfig)
 	if err != nil {
@@ -21,6 +36,35 @@ func Validate(stylesConfig *models.StylesConfig) error {
 		if err != nil {
 			errors = append(errors, err.Error())
 		}
+		generatedErr := validateGeneratedStylesheets(metadata, stylesConfig.AdditionalFormats)
+		if generatedErr != nil {
+			errors = append(errors, generatedErr.Error())
+		}
+		// incorrect generated stylesheets are already reported
+		err = validateStylesheetContents(metadata, assetDir, stylesConfig, generatedErr != nil)
+		if err != nil {
+			errors = append(errors, err.Error())
+		}
+		err = validatePropertiesSchemas(metadata)
+		if err != nil {
+			errors = append(errors, err.Error())
+		}
+		err = validateSampleData(metadata)
+		if err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+	err = validateCollections(stylesConfig)
+	if err != nil {
+		errors = append(errors, err.Error())
+	}
+	err = validatePublishRules(stylesConfig.Publish)
+	if err != nil {
+		errors = append(errors, err.Error())
+	}
+	err = validateEnvironments(stylesConfig)
+	if err != nil {
+		errors = append(errors, err.Error())
 	}
 
 	if errors != nil {
@@ -57,6 +101,107 @@ func validateStyleEncoding(metadata models.StyleMetadata) error {
 	return fmt.Errorf("requirement 3E fails; style %s stylesheet definition incorrect", metadata.Id)
 }
 
+// validateGeneratedStylesheets checks stylesheets with generate-from can be converted from another stylesheet of the style
+func validateGeneratedStylesheets(metadata models.StyleMetadata, additionalFormats []models.Format) error {
+	var errors []string
+	for _, stylesheet := range metadata.Stylesheets {
+		if stylesheet.GenerateFrom == nil {
+			continue
+		}
+		if stylesheet.Link.AssetFilename != nil {
+			errors = append(errors, fmt.Sprintf("stylesheet generated from %s of style %s should not have an asset-filename", *stylesheet.GenerateFrom, metadata.Id))
+			continue
+		}
+		_, _, err := findStylesheetConversion(stylesheet, metadata, additionalFormats)
+		if err != nil {
+			errors = append(errors, err.Error())
+			continue
+		}
+		if root, _ := stylesheet.Link.Type.SplitParams(); root == models.MapboxMediaType {
+			errors = append(errors, validateMapboxOptions(stylesheet.Mapbox, metadata.Id)...)
+		}
+	}
+	if errors != nil {
+		return fmt.Errorf("generated stylesheets incorrect; %s", strings.Join(errors, ", "))
+	}
+	return nil
+}
+
+// validateStylesheetContents checks the content of the stylesheets as they would be published, for the media types
+// with a stylesheet validator. The generated stylesheets are skipped when they are incorrect.
+func validateStylesheetContents(metadata models.StyleMetadata, assetDir string, stylesConfig *models.StylesConfig, skipGenerated bool) error {
+	var errors []string
+	for _, stylesheet := range metadata.Stylesheets {
+		if stylesheet.Link.Type == nil {
+			continue
+		}
+		root, _ := stylesheet.Link.Type.SplitParams()
+		validator, ok := stylesheetValidators[root]
+		if !ok {
+			continue
+		}
+		var name string
+		var document *models.Document
+		var err error
+		if stylesheet.GenerateFrom != nil {
+			if skipGenerated {
+				continue
+			}
+			name = fmt.Sprintf("%s (generated from %s)", root, *stylesheet.GenerateFrom)
+			document, err = generateConvertedStylesheet(stylesheet, metadata, assetDir, stylesConfig)
+		} else if stylesheet.Link.AssetFilename != nil {
+			name = *stylesheet.Link.AssetFilename
+			document, err = generateAssetFromSource(stylesheet.Link, metadata.Id, assetDir, stylesConfig, true)
+		} else {
+			continue
+		}
+		if err == nil {
+			// check the stylesheet as published, with the urls rewritten and the sprite and glyphs injected
+			err = finishStylesheet(&stylesheet, document, metadata, stylesConfig)
+		}
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("%s: %s", name, err))
+			continue
+		}
+		for _, problem := range validator(document.Content.Bytes()) {
+			errors = append(errors, fmt.Sprintf("%s#%s", name, problem))
+		}
+	}
+	if errors != nil {
+		return fmt.Errorf("style %s stylesheets incorrect; %s", metadata.Id, strings.Join(errors, ", "))
+	}
+	return nil
+}
+
+func validateMapboxStylesheet(content []byte) (problems []string) {
+	for _, problem := range mapbox.Validate(content) {
+		problems = append(problems, problem.String())
+	}
+	return problems
+}
+
+func validateSldStylesheet(content []byte) (problems []string) {
+	for _, problem := range sld.Validate(content) {
+		problems = append(problems, problem.String())
+	}
+	return problems
+}
+
+// validateMapboxOptions checks the sources of a generated Mapbox stylesheet, layers should be rendered from a known source
+func validateMapboxOptions(options *models.MapboxOptions, styleId string) (errors []string) {
+	if options == nil || len(options.Sources) == 0 {
+		return []string{fmt.Sprintf("mapbox stylesheet of style %s is generated without mapbox sources", styleId)}
+	}
+	for _, layer := range options.Layers {
+		if layer.Source == "" && len(options.Sources) > 1 {
+			errors = append(errors, fmt.Sprintf("named-layer %s of style %s needs a source, since there are multiple mapbox sources", layer.NamedLayer, styleId))
+		} else if _, ok := options.Sources[layer.Source]; layer.Source != "" && !ok {
+			errors = append(errors, fmt.Sprintf("source %s of named-layer %s of style %s not found in the mapbox sources", layer.Source, layer.NamedLayer, styleId))
+		}
+	}
+	return errors
+}
+
 // validateDefaultStyle Requirement 3G: The default member SHALL, if provided, be the id of one of the styles in the styles array.
 func validateDefaultStyle(stylesConfig *models.StylesConfig) error {
 	for _, metadata := range stylesConfig.StylesMetadata {
@@ -67,15 +212,297 @@ func validateDefaultStyle(stylesConfig *models.StylesConfig) error {
 	return fmt.Errorf("requirement 3G fails; default  %s not found in styles", stylesConfig.Default)
 }
 
+// validateCollections checks the collection ids are unique and the styles of each collection, like Requirement 3G for its default, exist.
+func validateCollections(stylesConfig *models.StylesConfig) error {
+	var errors []string
+	collectionSet := make(map[string]bool)
+	for _, collection := range stylesConfig.Collections {
+		if collectionSet[collection.Id] {
+			errors = append(errors, fmt.Sprintf("duplicate collection id: %s", collection.Id))
+		}
+		collectionSet[collection.Id] = true
+		defaultFound := collection.Default == ""
+		for _, styleId := range collection.Styles {
+			if _, ok := stylesConfig.GetStyleMetadata(styleId); !ok {
+				errors = append(errors, fmt.Sprintf("style %s of collection %s not found in styles", styleId, collection.Id))
+			}
+			if styleId == collection.Default {
+				defaultFound = true
+			}
+		}
+		if !defaultFound {
+			errors = append(errors, fmt.Sprintf("default %s of collection %s not found in its styles", collection.Default, collection.Id))
+		}
+	}
+	if errors != nil {
+		return fmt.Errorf("collections incorrect; %s", strings.Join(errors, ", "))
+	}
+	return nil
+}
+
+// metadataKeyPattern metadata keys valid for both S3 (HTTP headers) and Azure Blob (C# identifiers)
+var metadataKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validatePublishRules checks the publish rules have a path, known encodings and metadata keys valid on S3 and Azure Blob
+func validatePublishRules(rules models.PublishRules) error {
+	var errors []string
+	for i, rule := range rules {
+		if rule.Path == "" {
+			errors = append(errors, fmt.Sprintf("publish rule %d has no path", i+1))
+		}
+		for _, encoding := range rule.Compress {
+			if encoding.Extension() == "" {
+				errors = append(errors, fmt.Sprintf("unknown compression %s of publish rule %s, choose from: [gzip,br]", encoding, rule.Path))
+			}
+		}
+		for key := range rule.Metadata {
+			if !metadataKeyPattern.MatchString(key) {
+				errors = append(errors, fmt.Sprintf("metadata key %s of publish rule %s should only contain letters, digits and underscores", key, rule.Path))
+			}
+		}
+	}
+	if errors != nil {
+		sort.Strings(errors)
+		return fmt.Errorf("publish rules incorrect; %s", strings.Join(errors, ", "))
+	}
+	return nil
+}
+
+// validatePropertiesSchemas checks the properties schemas of the layers of the style are valid OpenAPI 3.0 schemas
+func validatePropertiesSchemas(metadata models.StyleMetadata) error {
+	var errors []string
+	for _, layer := range metadata.Layers {
+		if layer.PropertiesSchema == nil {
+			continue
+		}
+		for _, problem := range validateSchema(*layer.PropertiesSchema, "") {
+			errors = append(errors, fmt.Sprintf("layer %s properties-schema#%s", layer.Id, problem))
+		}
+	}
+	if errors != nil {
+		return fmt.Errorf("style %s properties schemas incorrect; %s", metadata.Id, strings.Join(errors, ", "))
+	}
+	return nil
+}
+
+// validateSampleData checks the local sample data of the layers of the style is GeoJSON or a GeoPackage, published as
+// an enclosure - OGC API Styles Recommendation 2A
+func validateSampleData(metadata models.StyleMetadata) error {
+	var errors []string
+	for _, layer := range metadata.Layers {
+		if layer.SampleData == nil || layer.SampleData.AssetFilename == nil {
+			continue
+		}
+		if layer.SampleData.Rel != "" && layer.SampleData.Rel != models.EnclosureRelation {
+			errors = append(errors, fmt.Sprintf("layer %s should have rel %s, not %s", layer.Id, models.EnclosureRelation, layer.SampleData.Rel))
+		}
+		if _, err := sampleDataMediaType(*layer.SampleData); err != nil {
+			errors = append(errors, fmt.Sprintf("layer %s %s", layer.Id, err))
+		}
+	}
+	if errors != nil {
+		return fmt.Errorf("style %s sample data incorrect; %s", metadata.Id, strings.Join(errors, ", "))
+	}
+	return nil
+}
+
+// validateSchema the problems of the schema, prefixed with the JSON pointer of the (nested) schema they are found in
+func validateSchema(schema models.PropertiesSchema, path string) []string {
+	var problems []string
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+	if schema.Type != "" && !contains(models.SchemaTypes, schema.Type) {
+		problem("unknown type %s, choose from: [%s]", schema.Type, strings.Join(models.SchemaTypes, ","))
+	}
+	if schema.Properties != nil && schema.Type != "" && schema.Type != "object" {
+		problem("properties only apply to type object, not %s", schema.Type)
+	}
+	for _, name := range schema.Required {
+		if _, ok := schema.Properties[name]; !ok {
+			problem("required property %s not found in the properties", name)
+		}
+	}
+	if schema.Type == "array" && schema.Items == nil {
+		problem("type array should have items")
+	}
+	if schema.Items != nil && schema.Type != "array" {
+		problem("items only apply to type array, not %s", schema.Type)
+	}
+	if (schema.Minimum != nil || schema.Maximum != nil) && schema.Type != "number" && schema.Type != "integer" {
+		problem("minimum and maximum only apply to type number or integer")
+	}
+	if schema.Minimum != nil && schema.Maximum != nil && *schema.Minimum > *schema.Maximum {
+		problem("minimum %v should not be greater than maximum %v", *schema.Minimum, *schema.Maximum)
+	}
+	for _, value := range schema.Enum {
+		if value == nil && !schema.Nullable {
+			problem("enum value null is only allowed when nullable")
+		} else if !enumValueMatches(value, schema.Type) {
+			problem("enum value %v is not a %s", value, schema.Type)
+		}
+	}
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if schema.Properties[name] == nil {
+			continue
+		}
+		problems = append(problems, validateSchema(*schema.Properties[name], path+"/properties/"+name)...)
+	}
+	if schema.Items != nil {
+		problems = append(problems, validateSchema(*schema.Items, path+"/items")...)
+	}
+	return problems
+}
+
+// enumValueMatches whether the (yaml decoded) enum value is null or a scalar of the schema type
+func enumValueMatches(value interface{}, schemaType string) bool {
+	switch typed := value.(type) {
+	case nil:
+		return true
+	case string:
+		return schemaType == "" || schemaType == "string"
+	case bool:
+		return schemaType == "" || schemaType == "boolean"
+	case int:
+		return schemaType == "" || schemaType == "number" || schemaType == "integer"
+	case float64:
+		return schemaType == "" || schemaType == "number" || (schemaType == "integer" && typed == float64(int64(typed)))
+	default:
+		return false
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// validateEnvironments checks the rewrites of the environments and that the environment generated for is configured
+func validateEnvironments(stylesConfig *models.StylesConfig) error {
+	var errors []string
+	if _, ok := stylesConfig.Environments[stylesConfig.Environment]; stylesConfig.Environment != "" && !ok {
+		var names []string
+		for name := range stylesConfig.Environments {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		errors = append(errors, fmt.Sprintf("environment %s not found, choose from: [%s]", stylesConfig.Environment, strings.Join(names, ",")))
+	}
+	for name, environment := range stylesConfig.Environments {
+		for i, rewrite := range environment.Rewrites {
+			if rewrite.From == "" {
+				errors = append(errors, fmt.Sprintf("rewrite %d of environment %s has no from", i+1, name))
+			}
+		}
+	}
+	if errors != nil {
+		sort.Strings(errors)
+		return fmt.Errorf("environments incorrect; %s", strings.Join(errors, ", "))
+	}
+	return nil
+}
+
+// contentSignatures the leading bytes of the content of binary media types
+var contentSignatures = map[models.MediaType][]string{
+	"image/png":       {"\x89PNG\r\n\x1a\n"},
+	"image/jpeg":      {"\xff\xd8\xff"},
+	"image/gif":       {"GIF87a", "GIF89a"},
+	"application/pdf": {"%PDF-"},
+}
+
+// ValidateDocuments Requirement 4B: The content of that response SHALL conform to the media type stated in the Content-Type header.
+// JSON media types should parse, XML media types should be well-formed and images should have the signature of their format.
+func ValidateDocuments(documents []models.Document) error {
+	var errors []string
+	for _, document := range documents {
+		err := validateDocumentContent(document)
+		if err == nil {
+			continue
+		}
+		if document.Source != "" {
+			errors = append(errors, fmt.Sprintf("asset %s (%s): %s", document.Source, document.Path, err))
+		} else {
+			errors = append(errors, fmt.Sprintf("%s: %s", document.Path, err))
+		}
+	}
+	if errors != nil {
+		return fmt.Errorf("requirement 4B fails; content does not conform to its media type: %s", strings.Join(errors, ", "))
+	}
+	return nil
+}
+
+func validateDocumentContent(document models.Document) error {
+	root, _ := document.MediaType.SplitParams()
+	content := document.Content.Bytes()
+	switch {
+	case root == models.JsonMediaType || strings.HasSuffix(string(root), "+json"):
+		var value interface{}
+		err := json.Unmarshal(content, &value)
+		if err != nil {
+			return fmt.Errorf("invalid json for media type %s: %s", document.MediaType, err)
+		}
+	case root == "application/xml" || root == "text/xml" || strings.HasSuffix(string(root), "+xml"):
+		err := checkWellFormedXml(content)
+		if err != nil {
+			return fmt.Errorf("xml is not well-formed for media type %s: %s", document.MediaType, err)
+		}
+	default:
+		signatures, ok := contentSignatures[root]
+		if !ok {
+			return nil
+		}
+		for _, signature := range signatures {
+			if bytes.HasPrefix(content, []byte(signature)) {
+				return nil
+			}
+		}
+		return fmt.Errorf("content is %s, not %s", http.DetectContentType(content), root)
+	}
+	return nil
+}
+
+func checkWellFormedXml(content []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	hasRoot := false
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if depth == 0 && hasRoot {
+				return fmt.Errorf("more than one root element")
+			}
+			hasRoot = true
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if depth == 0 && len(bytes.TrimSpace(t)) > 0 {
+				return fmt.Errorf("character data outside the root element")
+			}
+		}
+	}
+	if !hasRoot {
+		return fmt.Errorf("no root element")
+	}
+	return nil
+}
+
 // TODO possible validation todos?:
-// Requirement 4B The content of that response SHALL conform to the media type stated in the Content-Type header.
-
-// Recommendation 2A:
-//Sample data that can be used to illustrate the style SHOULD be represented as links with the following link relation types:
-//enclosure for links to sample data that may be downloaded (e.g. a GeoPackage);
-//collection for links to a Collection resource according to OGC API Common (e.g. /collections/{collectionId}; the collection may be available as features (tiled or not) or as gridded data);
-//start for links to a Features resource according to OGC API Features (e.g. /collections/{collectionId}/items; the response may contain a next link to additional features);
-//http://www.opengis.net/def/rel/ogc/1.0/tilesets-vector for a link to a Tile Sets resource (e.g. /collections/{collectionId}/tiles) with vector tiles.
-//http://www.opengis.net/def/rel/ogc/1.0/tilesets-coverage for a link to a Tile Sets resource (e.g. /collections/{collectionId}/tiles) with coverage tiles.
 
 // Recommendation 3A: If a style can be used to style multiple geospatial datasets that implement a common schema and where a canonical URI exists for the schema, a link with the link relation type http://www.opengis.net/def/rel/ogc/1.0/schema SHOULD be provided.
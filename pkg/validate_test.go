@@ -1,8 +1,13 @@
 package pkg
 
 import (
+	"bytes"
 	"github.com/pdok/goas/pkg/models"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -13,7 +18,7 @@ func ValidStyles() *models.StylesConfig {
 
 func TestValidateValidStyles(t *testing.T) {
 	stylesConfig := ValidStyles()
-	err := Validate(stylesConfig)
+	err := Validate(stylesConfig, "../examples/assets")
 	require.Nil(t, err)
 }
 
@@ -21,7 +26,7 @@ func TestValidateDuplicateStyles(t *testing.T) {
 	stylesConfig := ValidStyles()
 	expected := "validation errors found: requirement 3D fails; found styles with duplicate ids: night"
 	stylesConfig.StylesMetadata = append(stylesConfig.StylesMetadata, stylesConfig.StylesMetadata[0])
-	err := Validate(stylesConfig)
+	err := Validate(stylesConfig, "../examples/assets")
 	require.NotNil(t, err)
 	require.Equal(t, expected, err.Error())
 }
@@ -30,7 +35,7 @@ func TestValidateWrongStyleRelation(t *testing.T) {
 	stylesConfig := ValidStyles()
 	expected := "validation errors found: requirement 3E fails; style night stylesheet definition incorrect"
 	stylesConfig.StylesMetadata[0].Stylesheets = []models.StyleSheet{{Link: models.Link{Rel: models.SelfRelation}}}
-	err := Validate(stylesConfig)
+	err := Validate(stylesConfig, "../examples/assets")
 	require.NotNil(t, err)
 	require.Equal(t, expected, err.Error())
 }
@@ -39,7 +44,173 @@ func TestValidateUnknownDefaultStyle(t *testing.T) {
 	stylesConfig := ValidStyles()
 	stylesConfig.Default = "unknown"
 	expected := "validation errors found: requirement 3G fails; default  unknown not found in styles"
-	err := Validate(stylesConfig)
+	err := Validate(stylesConfig, "../examples/assets")
 	require.NotNil(t, err)
 	require.Equal(t, expected, err.Error())
 }
+
+func TestValidateUnknownCollectionStyle(t *testing.T) {
+	stylesConfig := ValidStyles()
+	stylesConfig.Collections = append(stylesConfig.Collections, models.Collection{Id: "daraa", Default: "day", Styles: []string{"day"}})
+	expected := "validation errors found: collections incorrect; duplicate collection id: daraa, style day of collection daraa not found in styles"
+	err := Validate(stylesConfig, "../examples/assets")
+	require.NotNil(t, err)
+	require.Equal(t, expected, err.Error())
+}
+
+func TestValidateUnknownCollectionDefaultStyle(t *testing.T) {
+	stylesConfig := ValidStyles()
+	stylesConfig.Collections[0].Default = "day"
+	expected := "validation errors found: collections incorrect; default day of collection daraa not found in its styles"
+	err := Validate(stylesConfig, "../examples/assets")
+	require.NotNil(t, err)
+	require.Equal(t, expected, err.Error())
+}
+
+func TestValidateGeneratedStylesheet(t *testing.T) {
+	stylesConfig, _ := ParseConfig("../examples/generate_config.yaml")
+	err := Validate(stylesConfig, "../examples/assets")
+	require.Nil(t, err)
+}
+
+func TestValidateGeneratedStylesheetWithoutSource(t *testing.T) {
+	stylesConfig, _ := ParseConfig("../examples/generate_config.yaml")
+	stylesConfig.StylesMetadata[0].Stylesheets = stylesConfig.StylesMetadata[0].Stylesheets[1:]
+	expected := "validation errors found: generated stylesheets incorrect; no stylesheet with an asset-filename and format mapbox found in style daraa to generate application/vnd.ogc.sld+xml;version=1.1 from"
+	err := Validate(stylesConfig, "../examples/assets")
+	require.NotNil(t, err)
+	require.Equal(t, expected, err.Error())
+}
+
+func TestValidateGeneratedMapboxStylesheetWithoutSources(t *testing.T) {
+	stylesConfig, _ := ParseConfig("../examples/generate_config.yaml")
+	stylesConfig.StylesMetadata[1].Stylesheets[1].Mapbox.Layers[0].Source = "unknown"
+	expected := "validation errors found: generated stylesheets incorrect; source unknown of named-layer SettlementPnt of style daraa-legacy not found in the mapbox sources"
+	err := Validate(stylesConfig, "../examples/assets")
+	require.NotNil(t, err)
+	require.Equal(t, expected, err.Error())
+
+	stylesConfig.StylesMetadata[1].Stylesheets[1].Mapbox = nil
+	expected = "validation errors found: generated stylesheets incorrect; mapbox stylesheet of style daraa-legacy is generated without mapbox sources"
+	err = Validate(stylesConfig, "../examples/assets")
+	require.NotNil(t, err)
+	require.Equal(t, expected, err.Error())
+}
+
+func TestValidateUnknownEnvironment(t *testing.T) {
+	stylesConfig, _ := ParseConfig("../examples/generate_config.yaml")
+	stylesConfig.Environment = "prod"
+	stylesConfig.Environments["acc"].Rewrites[1].From = ""
+	expected := "validation errors found: environments incorrect; environment prod not found, choose from: [acc], rewrite 2 of environment acc has no from"
+	err := Validate(stylesConfig, "../examples/assets")
+	require.NotNil(t, err)
+	require.Equal(t, expected, err.Error())
+}
+
+func TestValidateInvalidPropertiesSchema(t *testing.T) {
+	stylesConfig, _ := ParseConfig("../examples/config.yaml")
+	schema := stylesConfig.StylesMetadata[0].Layers[1].PropertiesSchema
+	schema.Required = append(schema.Required, "HYP")
+	schema.Properties["F_CODE"].Enum = append(schema.Properties["F_CODE"].Enum, 5)
+	maximum := -1.0
+	schema.Properties["WID"].Maximum = &maximum
+	schema.Properties["ZI005_FNA"].Type = "text"
+	expected := "validation errors found: style night properties schemas incorrect; " +
+		"layer hydrographycrv properties-schema#: required property HYP not found in the properties, " +
+		"layer hydrographycrv properties-schema#/properties/F_CODE: enum value 5 is not a string, " +
+		"layer hydrographycrv properties-schema#/properties/WID: minimum 0 should not be greater than maximum -1, " +
+		"layer hydrographycrv properties-schema#/properties/ZI005_FNA: unknown type text, choose from: [string,number,integer,boolean,array,object]"
+	err := Validate(stylesConfig, "../examples/assets")
+	require.NotNil(t, err)
+	require.Equal(t, expected, err.Error())
+}
+
+func TestValidateSampleData(t *testing.T) {
+	stylesConfig, _ := ParseConfig("../examples/generate_config.yaml")
+	stylesConfig.StylesMetadata[1].Layers[0].SampleData.Rel = models.StartRelation
+	csv := "sample/daraa.csv"
+	stylesConfig.StylesMetadata[1].Layers[1].SampleData.AssetFilename = &csv
+	expected := "validation errors found: style daraa-legacy sample data incorrect; layer hydrographycrv should have rel enclosure, not start, " +
+		"layer SettlementPnt unknown format of sample/daraa.csv, use a .geojson or .gpkg file or set the type"
+	err := Validate(stylesConfig, "../examples/assets")
+	require.NotNil(t, err)
+	require.Equal(t, expected, err.Error())
+}
+
+func TestValidateInvalidMapboxStylesheet(t *testing.T) {
+	stylesConfig := ValidStyles()
+	assetFilename := "daraa-sld.sld"
+	stylesConfig.StylesMetadata[0].Stylesheets[0].Link.AssetFilename = &assetFilename
+	err := Validate(stylesConfig, "../examples/assets")
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "style night stylesheets incorrect; daraa-sld.sld#: invalid json")
+}
+
+func TestValidateInvalidSldStylesheet(t *testing.T) {
+	stylesConfig := ValidStyles()
+	assetFilename := "mapbox-style.json"
+	stylesConfig.StylesMetadata[0].Stylesheets[1].Link.AssetFilename = &assetFilename
+	err := Validate(stylesConfig, "../examples/assets")
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "style night stylesheets incorrect; mapbox-style.json#/: could not parse xml")
+}
+
+func TestValidateMapboxStylesheetAsPublished(t *testing.T) {
+	assetDir := t.TempDir()
+	require.Nil(t, os.MkdirAll(filepath.Join(assetDir, "fonts"), os.ModePerm))
+	require.Nil(t, os.WriteFile(filepath.Join(assetDir, "fonts", "Go-Regular.ttf"), goregular.TTF, 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(assetDir, "fonts", "Go-Bold.ttf"), gobold.TTF, 0644))
+	stylesheet := `{"version": 8, "sources": {}, "glyphs": 5, "layers": [{"id": "background", "type": "background"}]}`
+	require.Nil(t, os.WriteFile(filepath.Join(assetDir, "night.json"), []byte(stylesheet), 0644))
+	configPath := filepath.Join(assetDir, "config.yaml")
+	require.Nil(t, os.WriteFile(configPath, []byte("default: night\n"+glyphsConfig), 0644))
+	config, err := ParseConfig(configPath)
+	require.Nil(t, err)
+	require.Nil(t, Validate(config, assetDir), "the glyphs of the published stylesheet are generated")
+
+	config.StylesMetadata[0].Glyphs = nil
+	err = Validate(config, assetDir)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "style night stylesheets incorrect; night.json#/glyphs: should be a string")
+}
+
+func TestValidateDocuments(t *testing.T) {
+	documents, err := GenerateDocuments(ValidStyles(), "../examples/assets", []models.Format{models.JsonFormat, models.HtmlFormat})
+	require.Nil(t, err)
+	require.Nil(t, ValidateDocuments(documents))
+}
+
+func TestValidateDocumentsMismatchingMediaType(t *testing.T) {
+	documents := []models.Document{
+		{Path: "resources/thumbnail.png", MediaType: "image/png", Content: bytes.NewBuffer([]byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00")), Source: "thumbnail.png"},
+		{Path: "styles/night.mapbox.json", MediaType: models.MapboxMediaType, Content: bytes.NewBufferString(`{"version": 8,`), Source: "mapbox-style.json"},
+		{Path: "styles/night.sld", MediaType: "application/vnd.ogc.sld+xml;version=1.0", Content: bytes.NewBufferString(`<StyledLayerDescriptor>`), Source: "ogc-sld.sld"},
+		{Path: "styles.json", MediaType: models.JsonMediaType, Content: bytes.NewBufferString(`{}`)},
+	}
+	err := ValidateDocuments(documents)
+	require.NotNil(t, err)
+	require.Equal(t, "requirement 4B fails; content does not conform to its media type: "+
+		"asset thumbnail.png (resources/thumbnail.png): content is image/jpeg, not image/png, "+
+		"asset mapbox-style.json (styles/night.mapbox.json): invalid json for media type application/vnd.mapbox.style+json: unexpected end of JSON input, "+
+		"asset ogc-sld.sld (styles/night.sld): xml is not well-formed for media type application/vnd.ogc.sld+xml;version=1.0: XML syntax error on line 1: unexpected EOF", err.Error())
+}
+
+func TestValidateDocumentsMultipleXmlRoots(t *testing.T) {
+	documents := []models.Document{
+		{Path: "styles/night.sld", MediaType: models.SldMediaType, Content: bytes.NewBufferString(`<StyledLayerDescriptor><NamedLayer/></StyledLayerDescriptor><StyledLayerDescriptor/>`)},
+	}
+	err := ValidateDocuments(documents)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "styles/night.sld: xml is not well-formed for media type "+string(models.SldMediaType)+": more than one root element")
+}
+
+func TestValidateDocumentsXmlCharDataOutsideRoot(t *testing.T) {
+	documents := []models.Document{
+		{Path: "styles/night.sld", MediaType: models.SldMediaType, Content: bytes.NewBufferString("<?xml version=\"1.0\"?>\n<StyledLayerDescriptor/>\n")},
+		{Path: "styles/day.sld", MediaType: models.SldMediaType, Content: bytes.NewBufferString(`<StyledLayerDescriptor/>garbage`)},
+	}
+	err := ValidateDocuments(documents)
+	require.NotNil(t, err)
+	require.NotContains(t, err.Error(), "styles/night.sld", "whitespace around the root element is allowed")
+	require.Contains(t, err.Error(), "styles/day.sld: xml is not well-formed for media type "+string(models.SldMediaType)+": character data outside the root element")
+}
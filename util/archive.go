@@ -0,0 +1,155 @@
+package util
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pdok/goas/pkg/models"
+)
+
+// archiveModTime the modification time of all entries, so the same documents always result in the same archive
+var archiveModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// ArchiveWriter collects the written files and writes them as a single .tar.gz or .zip archive when it is closed. The
+// archive is reproducible: the entries are sorted by path and have a fixed modification time, mode and owner. Since files
+// in an archive have no media type, the archive always contains a manifest (the generated manifest, if written).
+type ArchiveWriter struct {
+	ArchiveDestination string
+	files              map[string]archivedFile
+	mutex              sync.Mutex
+	stats              writeCounter
+}
+
+type archivedFile struct {
+	content   []byte
+	mediaType models.MediaType
+}
+
+func (a *ArchiveWriter) Write(filename string, buffer *bytes.Buffer, mediaType models.MediaType) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.files == nil {
+		a.files = make(map[string]archivedFile)
+	}
+	a.files[filename] = archivedFile{append([]byte(nil), buffer.Bytes()...), mediaType}
+	a.stats.written()
+	return nil
+}
+
+func (a *ArchiveWriter) Stats() WriteStats {
+	return a.stats.get()
+}
+
+// Close writes the archive with the written files and the manifest
+func (a *ArchiveWriter) Close() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	files := make(map[string]archivedFile)
+	manifest := models.Manifest{Files: []models.ManifestFile{}}
+	for filename, file := range a.files {
+		files[filename] = file
+		manifest.Files = append(manifest.Files, models.NewManifestFile(filename, file.mediaType, file.content))
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool { return manifest.Files[i].Path < manifest.Files[j].Path })
+	if _, ok := files[models.ManifestPath]; !ok {
+		content, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			return err
+		}
+		files[models.ManifestPath] = archivedFile{content, models.JsonMediaType}
+	}
+	filenames := make([]string, 0, len(files))
+	for filename := range files {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	dir, _ := filepath.Split(a.ArchiveDestination)
+	if dir != "" {
+		err := os.MkdirAll(dir, os.ModePerm)
+		if err != nil {
+			return fmt.Errorf("could not make dir for: %s : %s ", a.ArchiveDestination, err.Error())
+		}
+	}
+	log.Printf("writing archive: %s with %d files", a.ArchiveDestination, len(filenames))
+	archive, err := os.Create(a.ArchiveDestination)
+	if err != nil {
+		return fmt.Errorf("could create archive %s : %s ", a.ArchiveDestination, err.Error())
+	}
+	defer archive.Close()
+	if isZip(a.ArchiveDestination) {
+		err = writeZip(archive, filenames, files)
+	} else {
+		err = writeTarGz(archive, filenames, files)
+	}
+	if err != nil {
+		return fmt.Errorf("could not write archive %s : %s ", a.ArchiveDestination, err.Error())
+	}
+	return nil
+}
+
+func writeTarGz(out io.Writer, filenames []string, files map[string]archivedFile) error {
+	gzipWriter := gzip.NewWriter(out)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for _, filename := range filenames {
+		content := files[filename].content
+		err := tarWriter.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     filename,
+			Size:     int64(len(content)),
+			Mode:     0644,
+			ModTime:  archiveModTime,
+			Format:   tar.FormatPAX,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = tarWriter.Write(content)
+		if err != nil {
+			return err
+		}
+	}
+	err := tarWriter.Close()
+	if err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+func writeZip(out io.Writer, filenames []string, files map[string]archivedFile) error {
+	zipWriter := zip.NewWriter(out)
+	for _, filename := range filenames {
+		header := &zip.FileHeader{Name: filename, Method: zip.Deflate, Modified: archiveModTime}
+		header.SetMode(0644)
+		entry, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		_, err = entry.Write(files[filename].content)
+		if err != nil {
+			return err
+		}
+	}
+	return zipWriter.Close()
+}
+
+func isZip(archiveDestination string) bool {
+	return strings.HasSuffix(archiveDestination, ".zip")
+}
+
+// isArchive whether the archive destination has a supported extension: .tar.gz, .tgz or .zip
+func isArchive(archiveDestination string) bool {
+	return isZip(archiveDestination) || strings.HasSuffix(archiveDestination, ".tar.gz") || strings.HasSuffix(archiveDestination, ".tgz")
+}
@@ -0,0 +1,86 @@
+package util
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pdok/goas/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeArchive(t *testing.T, archiveDestination string) {
+	writer := &ArchiveWriter{ArchiveDestination: archiveDestination}
+	require.Nil(t, writer.Write("styles/night.sld", bytes.NewBufferString("<sld/>"), models.SldMediaType))
+	require.Nil(t, writer.Write("styles.json", bytes.NewBufferString(`{"styles": []}`), models.JsonMediaType))
+	require.Nil(t, writer.Close())
+	assert.Equal(t, WriteStats{Written: 2}, writer.Stats())
+}
+
+func TestArchiveWriterTarGz(t *testing.T) {
+	archiveDestination := filepath.Join(t.TempDir(), "bundle", "styles.tar.gz")
+	writeArchive(t, archiveDestination)
+
+	archive, err := os.Open(archiveDestination)
+	require.Nil(t, err)
+	defer archive.Close()
+	gzipReader, err := gzip.NewReader(archive)
+	require.Nil(t, err)
+	tarReader := tar.NewReader(gzipReader)
+	var names []string
+	contents := map[string][]byte{}
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		assert.Equal(t, archiveModTime, header.ModTime.UTC())
+		names = append(names, header.Name)
+		contents[header.Name], _ = io.ReadAll(tarReader)
+	}
+	assert.Equal(t, []string{models.ManifestPath, "styles.json", "styles/night.sld"}, names, "entries are sorted")
+	assert.Equal(t, "<sld/>", string(contents["styles/night.sld"]))
+	var manifest models.Manifest
+	require.Nil(t, json.Unmarshal(contents[models.ManifestPath], &manifest))
+	assert.Equal(t, []models.ManifestFile{
+		models.NewManifestFile("styles.json", models.JsonMediaType, []byte(`{"styles": []}`)),
+		models.NewManifestFile("styles/night.sld", models.SldMediaType, []byte("<sld/>")),
+	}, manifest.Files)
+
+	first, _ := os.ReadFile(archiveDestination)
+	writeArchive(t, archiveDestination)
+	second, _ := os.ReadFile(archiveDestination)
+	assert.Equal(t, first, second, "the archive is reproducible")
+}
+
+func TestArchiveWriterZip(t *testing.T) {
+	archiveDestination := filepath.Join(t.TempDir(), "styles.zip")
+	writeArchive(t, archiveDestination)
+
+	archive, err := zip.OpenReader(archiveDestination)
+	require.Nil(t, err)
+	defer archive.Close()
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	assert.Equal(t, []string{models.ManifestPath, "styles.json", "styles/night.sld"}, names)
+}
+
+func TestInitStorageArchive(t *testing.T) {
+	storageDest, _, archiveDest, _, _, err := initStorage("", "styles.zip", "", "", "", "", "", false, "", "", "")
+	assert.Nil(t, err)
+	assert.Equal(t, ARCHIVE, storageDest)
+	assert.Equal(t, "styles.zip", *archiveDest)
+
+	_, _, _, _, _, err = initStorage("", "styles.rar", "", "", "", "", "", false, "", "", "")
+	assert.EqualError(t, err, "archive destination styles.rar should end with .tar.gz, .tgz or .zip")
+}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/pdok/goas/pkg/models"
@@ -27,34 +28,50 @@ type Context struct {
 	S3                 *S3Context
 	AzureBlob          *AzureBlobContext
 	FileDestination    *string
+	ArchiveDestination *string
 	StorageDestination StorageDestination
 	AssetDir           string
 	ConfigPath         string
 	Formats            []models.Format
+	Sync               bool                // delete the files at the destination that are not generated
+	SyncThreshold      float64             // the maximum fraction of the existing files a sync may delete
+	Release            bool                // write to a new release and promote it, instead of writing to the live paths
+	KeepReleases       int                 // the number of releases kept at the destination
+	Concurrency        int                 // the number of documents written at the same time
+	Retries            int                 // the number of retries of a write that failed with a transient error
+	Publish            models.PublishRules // the headers and metadata of the written files, from the config
+	Environment        string              // the environment of the config the urls of the stylesheets are rewritten for
+	Ctx                context.Context     // cancelled on SIGINT, stops the writers
 }
 
 type StorageDestination string
 
 const (
 	FILE       StorageDestination = "FILE"
+	ARCHIVE    StorageDestination = "ARCHIVE"
 	S3         StorageDestination = "S3"
 	AZURE_BLOB StorageDestination = "AZURE_BLOB"
 )
 
 var DefaultFormats = []models.Format{models.JsonFormat}
 
+// CreateStorageContext creates a context with only the storage destination and sync options, for commands without assets
+// and config
+func CreateStorageContext(c *cli.Context) (*Context, error) {
+	storageDest, fileDest, archiveDest, s3Context, azureBlobContext, err := initStorageFromFlags(c)
+	if err != nil {
+		return nil, err
+	}
+	syncThreshold, err := syncThresholdFromFlags(c)
+	if err != nil {
+		return nil, err
+	}
+	return &Context{S3: &s3Context, AzureBlob: &azureBlobContext, FileDestination: fileDest, ArchiveDestination: archiveDest,
+		StorageDestination: storageDest, Sync: c.Bool("sync"), SyncThreshold: syncThreshold, Ctx: c.Context}, nil
+}
+
 func CreateContext(c *cli.Context) (*Context, error) {
-	storageDest, fileDest, s3Context, azureBlobContext, err := initStorage(
-		c.String("file-destination"),
-		c.String("s3-endpoint"),
-		c.String("s3-secret"),
-		c.String("s3-bucket"),
-		c.String("s3-access-key"),
-		c.String("s3-prefix"),
-		c.Bool("s3-secure"),
-		c.String("azure-storage-connection-string"),
-		c.String("azure-storage-container"),
-		c.String("azure-storage-blobs-prefix"))
+	storageDest, fileDest, archiveDest, s3Context, azureBlobContext, err := initStorageFromFlags(c)
 	if err != nil {
 		return nil, err
 	}
@@ -71,31 +88,81 @@ func CreateContext(c *cli.Context) (*Context, error) {
 	for _, format := range strings.Split(c.String("formats"), ",") {
 		if format != "" {
 			f, ok := models.GetFormat(format)
-			if ok {
-				formats = append(formats, f)
+			if !ok {
+				return nil, fmt.Errorf("unknown format: %s, choose from: [%s]", format, strings.Join(models.RenderFormatNames(), ","))
 			}
+			formats = append(formats, f)
 		}
 	}
 	if formats == nil {
 		formats = DefaultFormats
 	}
 
-	return &Context{&s3Context, &azureBlobContext, fileDest,
-		storageDest, assetDir, configPath, formats}, nil
+	syncThreshold, err := syncThresholdFromFlags(c)
+	if err != nil {
+		return nil, err
+	}
+
+	keepReleases := c.Int("keep-releases")
+	if keepReleases < 1 {
+		return nil, fmt.Errorf("keep releases should be at least 1, found: %d", keepReleases)
+	}
+
+	concurrency := c.Int("concurrency")
+	if concurrency < 1 {
+		return nil, fmt.Errorf("concurrency should be at least 1, found: %d", concurrency)
+	}
+	retries := c.Int("retries")
+	if retries < 0 {
+		return nil, fmt.Errorf("retries should not be negative, found: %d", retries)
+	}
+
+	return &Context{&s3Context, &azureBlobContext, fileDest, archiveDest,
+		storageDest, assetDir, configPath, formats, c.Bool("sync"), syncThreshold, c.Bool("release"), keepReleases,
+		concurrency, retries, nil, c.String("environment"), c.Context}, nil
 }
 
-func initStorage(fileDestination string, s3Endpoint string, s3SecretKey string, s3Bucket string,
+func syncThresholdFromFlags(c *cli.Context) (float64, error) {
+	syncThreshold := c.Float64("sync-threshold")
+	if syncThreshold < 0 || syncThreshold > 1 {
+		return 0, fmt.Errorf("sync threshold should be between 0 and 1, found: %v", syncThreshold)
+	}
+	return syncThreshold, nil
+}
+
+func initStorageFromFlags(c *cli.Context) (StorageDestination, *string, *string, S3Context, AzureBlobContext, error) {
+	return initStorage(
+		c.String("file-destination"),
+		c.String("archive-destination"),
+		c.String("s3-endpoint"),
+		c.String("s3-secret"),
+		c.String("s3-bucket"),
+		c.String("s3-access-key"),
+		c.String("s3-prefix"),
+		c.Bool("s3-secure"),
+		c.String("azure-storage-connection-string"),
+		c.String("azure-storage-container"),
+		c.String("azure-storage-blobs-prefix"))
+}
+
+func initStorage(fileDestination string, archiveDestination string, s3Endpoint string, s3SecretKey string, s3Bucket string,
 	s3AccessKey string, s3Prefix string, s3Secure bool, azureConnectionString string,
-	azureContainer string, azurePrefix string) (StorageDestination, *string, S3Context, AzureBlobContext, error) {
+	azureContainer string, azurePrefix string) (StorageDestination, *string, *string, S3Context, AzureBlobContext, error) {
 
 	var s3Context S3Context
 	var azureBlobContext AzureBlobContext
-	var fileDest *string
+	var fileDest, archiveDest *string
 
 	var storageDestination StorageDestination
 	if fileDestination != "" {
 		storageDestination = FILE
 		fileDest = &fileDestination
+	} else if archiveDestination != "" {
+		if !isArchive(archiveDestination) {
+			return "", nil, nil, S3Context{}, AzureBlobContext{}, fmt.Errorf("archive destination %s should end with .tar.gz, .tgz or .zip", archiveDestination)
+		}
+		storageDestination = ARCHIVE
+		archiveDest = &archiveDestination
 	} else if s3Endpoint != "" && s3SecretKey != "" && s3Bucket != "" && s3AccessKey != "" && s3Prefix != "" {
 		storageDestination = S3
 		if !strings.HasSuffix(s3Prefix, "/") {
@@ -109,7 +176,7 @@ func initStorage(fileDestination string, s3Endpoint string, s3SecretKey string,
 		}
 		azureBlobContext = AzureBlobContext{azureConnectionString, azureContainer, azurePrefix}
 	} else {
-		return "", nil, S3Context{}, AzureBlobContext{}, errors.New("provide either a valid file destination, S3 config or Azure Blob config")
+		return "", nil, nil, S3Context{}, AzureBlobContext{}, errors.New("provide either a valid file destination, archive destination, S3 config or Azure Blob config")
 	}
-	return storageDestination, fileDest, s3Context, azureBlobContext, nil
+	return storageDestination, fileDest, archiveDest, s3Context, azureBlobContext, nil
 }
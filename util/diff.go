@@ -0,0 +1,236 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pdok/goas/pkg/models"
+)
+
+// diffContext the number of unchanged lines around the changes of a unified diff
+const diffContext = 3
+
+// DiffSummary the paths added, changed and removed (at the destination) by writing the generated documents
+type DiffSummary struct {
+	Added     []string
+	Changed   []string
+	Removed   []string
+	Unchanged int
+}
+
+// Empty whether writing the documents would not change anything at the destination
+func (summary DiffSummary) Empty() bool {
+	return len(summary.Added) == 0 && len(summary.Changed) == 0 && len(summary.Removed) == 0
+}
+
+// Diff compares the generated documents with the files at the destination of the reader, without writing anything.
+// The added, changed and removed paths are printed to out, followed by a unified diff of the changed text documents.
+// Removed paths are the files that --sync would delete.
+func Diff(reader Reader, documents []models.Document, out io.Writer) (DiffSummary, error) {
+	var summary DiffSummary
+	var diffs []string
+	generated := make([]string, 0, len(documents))
+	sorted := append([]models.Document(nil), documents...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+	for _, document := range sorted {
+		generated = append(generated, document.Path)
+		existing, mediaType, err := reader.Read(document.Path)
+		if errors.Is(err, ErrNotFound) {
+			summary.Added = append(summary.Added, document.Path)
+			continue
+		}
+		if err != nil {
+			return summary, err
+		}
+		sameType := mediaType == "" || mediaType == document.MediaType
+		if sameType && bytes.Equal(existing.Bytes(), document.Content.Bytes()) {
+			summary.Unchanged++
+			continue
+		}
+		summary.Changed = append(summary.Changed, document.Path)
+		if !sameType {
+			diffs = append(diffs, fmt.Sprintf("%s: media type %s -> %s\n", document.Path, mediaType, document.MediaType))
+		}
+		if document.ContentEncoding == "" && isText(document.MediaType) {
+			diffs = append(diffs, UnifiedDiff(document.Path, existing.String(), document.Content.String()))
+		} else if !bytes.Equal(existing.Bytes(), document.Content.Bytes()) {
+			diffs = append(diffs, fmt.Sprintf("%s: binary content changed (%d -> %d bytes)\n", document.Path, existing.Len(), document.Content.Len()))
+		}
+	}
+	existing, err := reader.List()
+	if err != nil {
+		return summary, err
+	}
+	summary.Removed = PlanSync(existing, generated)
+
+	var report strings.Builder
+	for _, path := range summary.Added {
+		report.WriteString("added:   " + path + "\n")
+	}
+	for _, path := range summary.Changed {
+		report.WriteString("changed: " + path + "\n")
+	}
+	for _, path := range summary.Removed {
+		report.WriteString("removed: " + path + "\n")
+	}
+	for _, diff := range diffs {
+		report.WriteString("\n" + diff)
+	}
+	report.WriteString(fmt.Sprintf("\n%d added, %d changed, %d removed, %d unchanged\n",
+		len(summary.Added), len(summary.Changed), len(summary.Removed), summary.Unchanged))
+	_, err = io.WriteString(out, report.String())
+	return summary, err
+}
+
+// isText whether documents of the media type are shown as a unified diff
+func isText(mediaType models.MediaType) bool {
+	root, _ := mediaType.SplitParams()
+	switch {
+	case strings.HasPrefix(string(root), "text/"),
+		strings.HasSuffix(string(root), "json"),
+		strings.HasSuffix(string(root), "xml"),
+		strings.HasSuffix(string(root), "yaml"):
+		return true
+	}
+	return false
+}
+
+type lineEdit struct {
+	op   byte // ' ' unchanged, '-' removed or '+' added
+	line string
+}
+
+// UnifiedDiff a unified diff (with 3 lines of context) of the existing and generated content of a path
+func UnifiedDiff(path string, existing string, generated string) string {
+	edits := diffLines(splitLines(existing), splitLines(generated))
+	var diff strings.Builder
+	diff.WriteString(fmt.Sprintf("--- a/%s\n+++ b/%s\n", path, path))
+	// line numbers (1-based) in the existing and generated content at the start of each edit
+	oldLines, newLines := make([]int, len(edits)+1), make([]int, len(edits)+1)
+	oldLines[0], newLines[0] = 1, 1
+	for i, edit := range edits {
+		oldLines[i+1], newLines[i+1] = oldLines[i], newLines[i]
+		if edit.op != '+' {
+			oldLines[i+1]++
+		}
+		if edit.op != '-' {
+			newLines[i+1]++
+		}
+	}
+	for start := 0; start < len(edits); {
+		if edits[start].op == ' ' {
+			start++
+			continue
+		}
+		// a hunk from the context before the change, up to a run of unchanged lines longer than twice the context
+		first := start - diffContext
+		if first < 0 {
+			first = 0
+		}
+		end, unchanged := start, 0
+		for end < len(edits) && unchanged <= 2*diffContext {
+			if edits[end].op == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+			end++
+		}
+		end -= unchanged - diffContext
+		if end > len(edits) {
+			end = len(edits)
+		}
+		diff.WriteString(fmt.Sprintf("@@ -%s +%s @@\n",
+			hunkRange(oldLines[first], oldLines[end]-oldLines[first]), hunkRange(newLines[first], newLines[end]-newLines[first])))
+		for _, edit := range edits[first:end] {
+			diff.WriteString(string(edit.op) + edit.line + "\n")
+		}
+		start = end
+	}
+	return diff.String()
+}
+
+func hunkRange(start int, count int) string {
+	if count == 0 {
+		// an empty range is written as the line before it
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// diffLines the shortest edit script between two lists of lines (Myers' algorithm)
+func diffLines(a []string, b []string) []lineEdit {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, offset)
+			}
+		}
+	}
+	return nil
+}
+
+func backtrack(a []string, b []string, trace [][]int, offset int) []lineEdit {
+	var edits []lineEdit
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var previousK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			previousK = k + 1
+		} else {
+			previousK = k - 1
+		}
+		previousX := v[offset+previousK]
+		previousY := previousX - previousK
+		for x > previousX && y > previousY {
+			x--
+			y--
+			edits = append(edits, lineEdit{' ', a[x]})
+		}
+		if d > 0 {
+			if x == previousX {
+				edits = append(edits, lineEdit{'+', b[previousY]})
+			} else {
+				edits = append(edits, lineEdit{'-', a[previousX]})
+			}
+		}
+		x, y = previousX, previousY
+	}
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
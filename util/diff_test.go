@@ -0,0 +1,69 @@
+package util
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pdok/goas/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	existing := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	generated := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\n"
+	assert.Equal(t, `--- a/styles.json
++++ b/styles.json
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -11,3 +11,4 @@
+ k
+ l
+ m
++n
+`, UnifiedDiff("styles.json", existing, generated))
+}
+
+func TestDiff(t *testing.T) {
+	writer := &FileWriter{FileDestination: t.TempDir()}
+	assert.Nil(t, writer.Write("styles.json", bytes.NewBufferString("{\n  \"styles\": []\n}\n"), models.JsonMediaType))
+	assert.Nil(t, writer.Write("styles/night.sld", bytes.NewBufferString("<sld/>\n"), models.SldMediaType))
+	assert.Nil(t, writer.Write("styles/old.sld", bytes.NewBufferString("<sld/>\n"), models.SldMediaType))
+
+	documents := []models.Document{
+		{Path: "styles/night.sld", MediaType: models.SldMediaType, Content: bytes.NewBufferString("<sld/>\n")},
+		{Path: "styles.json", MediaType: models.JsonMediaType, Content: bytes.NewBufferString("{\n  \"styles\": [\"night\"]\n}\n")},
+		{Path: "resources/night.png", MediaType: models.PngMediaType, Content: bytes.NewBufferString("\x89PNG")},
+	}
+	var out bytes.Buffer
+	summary, err := Diff(writer, documents, &out)
+	assert.Nil(t, err)
+	assert.Equal(t, DiffSummary{
+		Added:     []string{"resources/night.png"},
+		Changed:   []string{"styles.json"},
+		Removed:   []string{"styles/old.sld"},
+		Unchanged: 1,
+	}, summary)
+	assert.Equal(t, `added:   resources/night.png
+changed: styles.json
+removed: styles/old.sld
+
+--- a/styles.json
++++ b/styles.json
+@@ -1,3 +1,3 @@
+ {
+-  "styles": []
++  "styles": ["night"]
+ }
+
+1 added, 1 changed, 1 removed, 1 unchanged
+`, out.String())
+	// nothing is written
+	filenames, err := writer.List()
+	assert.Nil(t, err)
+	assert.Len(t, filenames, 3)
+}
@@ -0,0 +1,150 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/minio/minio-go/v7"
+	"github.com/pdok/goas/pkg/models"
+)
+
+// DefaultConcurrency the default number of documents written at the same time
+const DefaultConcurrency = 8
+
+// DefaultRetries the default number of times a failed write is retried, when the error is retryable
+const DefaultRetries = 3
+
+// DefaultBackoff the wait before the first retry, doubled for every next retry
+const DefaultBackoff = 500 * time.Millisecond
+
+// maxBackoff the maximum wait between two retries
+const maxBackoff = 30 * time.Second
+
+// WriteOptions how WriteDocuments writes the documents
+type WriteOptions struct {
+	Concurrency int           // the number of workers writing documents
+	Retries     int           // the number of retries of a write that failed with a retryable error, the S3 and Azure Blob clients do not retry writes themselves
+	Backoff     time.Duration // the wait before the first retry
+}
+
+// PathError a document that could not be written
+type PathError struct {
+	Path string
+	Err  error
+}
+
+// WriteErrors the documents that could not be written, sorted by path
+type WriteErrors []PathError
+
+func (errs WriteErrors) Error() string {
+	var report strings.Builder
+	report.WriteString(fmt.Sprintf("%d files could not be written:", len(errs)))
+	for _, err := range errs {
+		report.WriteString(fmt.Sprintf("\n  - %s: %s", err.Path, err.Err))
+	}
+	return report.String()
+}
+
+// WriteDocuments writes the documents with a pool of workers. Writes that fail with a retryable (transient) error are
+// retried with exponential backoff. All documents are attempted, unless the context is cancelled (e.g. on SIGINT), and
+// the documents that could not be written (including the documents not written because of the cancellation) are
+// returned as WriteErrors. The writer should be safe for concurrent use.
+func WriteDocuments(ctx context.Context, writer Writer, documents []models.Document, options WriteOptions) error {
+	concurrency := options.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	jobs := make(chan models.Document)
+	var mutex sync.Mutex
+	var errs WriteErrors
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for document := range jobs {
+				err := writeWithRetry(ctx, writer, document, options)
+				if err != nil {
+					mutex.Lock()
+					errs = append(errs, PathError{document.Path, err})
+					mutex.Unlock()
+				}
+			}
+		}()
+	}
+	for _, document := range documents {
+		jobs <- document
+	}
+	close(jobs)
+	wg.Wait()
+
+	if errs == nil {
+		return nil
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Path < errs[j].Path })
+	return errs
+}
+
+func writeWithRetry(ctx context.Context, writer Writer, document models.Document, options WriteOptions) error {
+	backoff := options.Backoff
+	for attempt := 0; ; attempt++ {
+		if ctx.Err() != nil {
+			return fmt.Errorf("not written: %s", ctx.Err())
+		}
+		// every attempt writes the complete content, the writers consume the buffer
+		err := writer.Write(document.Path, bytes.NewBuffer(document.Content.Bytes()), document.MediaType)
+		if err == nil {
+			return nil
+		}
+		if attempt >= options.Retries || !IsRetryable(err) {
+			if attempt > 0 {
+				return fmt.Errorf("%s (after %d retries)", err, attempt)
+			}
+			return err
+		}
+		log.Printf("warning: writing %s failed, retrying in %s: %s", document.Path, backoff, err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("not written: %s", ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// IsRetryable whether the error is transient, i.e. a network error, throttling or a server error of S3 or Azure Blob
+func IsRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var s3Error minio.ErrorResponse
+	if errors.As(err, &s3Error) {
+		switch s3Error.Code {
+		case "SlowDown", "InternalError", "RequestTimeout", "ServiceUnavailable":
+			return true
+		}
+		return s3Error.StatusCode == 429 || s3Error.StatusCode >= 500
+	}
+	if bloberror.HasCode(err, bloberror.ServerBusy, bloberror.InternalError, bloberror.OperationTimedOut) {
+		return true
+	}
+	var azureError *azcore.ResponseError
+	if errors.As(err, &azureError) {
+		return azureError.StatusCode == 429 || azureError.StatusCode >= 500
+	}
+	var netError net.Error
+	return errors.As(err, &netError)
+}
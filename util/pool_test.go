@@ -0,0 +1,93 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/minio/minio-go/v7"
+	"github.com/pdok/goas/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyWriter fails the first writes of a path with the error given for that path
+type flakyWriter struct {
+	mutex    sync.Mutex
+	failures map[string]int
+	err      map[string]error
+	attempts map[string]int
+	written  map[string]string
+}
+
+func newFlakyWriter() *flakyWriter {
+	return &flakyWriter{failures: map[string]int{}, err: map[string]error{}, attempts: map[string]int{}, written: map[string]string{}}
+}
+
+func (w *flakyWriter) Write(filename string, buffer *bytes.Buffer, _ models.MediaType) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.attempts[filename]++
+	if w.attempts[filename] <= w.failures[filename] {
+		return fmt.Errorf("error: %w, could not write file %s", w.err[filename], filename)
+	}
+	w.written[filename] = buffer.String()
+	return nil
+}
+
+func (w *flakyWriter) Stats() WriteStats {
+	return WriteStats{Written: len(w.written)}
+}
+
+func documents(paths ...string) []models.Document {
+	var documents []models.Document
+	for _, path := range paths {
+		documents = append(documents, models.Document{Path: path, MediaType: models.JsonMediaType, Content: bytes.NewBufferString(path)})
+	}
+	return documents
+}
+
+func TestWriteDocumentsRetries(t *testing.T) {
+	writer := newFlakyWriter()
+	slowDown := minio.ErrorResponse{Code: "SlowDown", StatusCode: 503}
+	writer.failures["a.json"], writer.err["a.json"] = 2, slowDown
+	writer.failures["b.json"], writer.err["b.json"] = 10, slowDown
+	writer.failures["c.json"], writer.err["c.json"] = 1, minio.ErrorResponse{Code: "AccessDenied", StatusCode: 403}
+
+	err := WriteDocuments(context.Background(), writer, documents("d.json", "c.json", "b.json", "a.json"),
+		WriteOptions{Concurrency: 2, Retries: 3, Backoff: time.Millisecond})
+
+	var errs WriteErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Equal(t, []string{"b.json", "c.json"}, []string{errs[0].Path, errs[1].Path})
+	assert.Equal(t, map[string]int{"a.json": 3, "b.json": 4, "c.json": 1, "d.json": 1}, writer.attempts)
+	assert.Equal(t, "a.json", writer.written["a.json"], "a retry writes the complete content")
+	assert.Equal(t, 2, writer.Stats().Written)
+	assert.Contains(t, err.Error(), "2 files could not be written:\n  - b.json: ")
+	assert.Contains(t, err.Error(), "(after 3 retries)")
+}
+
+func TestWriteDocumentsCancelled(t *testing.T) {
+	writer := newFlakyWriter()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := WriteDocuments(ctx, writer, documents("a.json", "b.json"), WriteOptions{Concurrency: 1, Retries: 3, Backoff: time.Millisecond})
+
+	assert.EqualError(t, err, "2 files could not be written:\n  - a.json: not written: context canceled\n  - b.json: not written: context canceled")
+	assert.Empty(t, writer.written)
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, IsRetryable(fmt.Errorf("error: %w", minio.ErrorResponse{Code: "InternalError", StatusCode: 500})))
+	assert.True(t, IsRetryable(minio.ErrorResponse{Code: "TooManyRequests", StatusCode: 429}))
+	assert.False(t, IsRetryable(minio.ErrorResponse{Code: "NoSuchBucket", StatusCode: 404}))
+	assert.True(t, IsRetryable(fmt.Errorf("error: %w", &azcore.ResponseError{StatusCode: 503})))
+	assert.False(t, IsRetryable(&azcore.ResponseError{ErrorCode: "BlobNotFound", StatusCode: 404}))
+	assert.False(t, IsRetryable(fmt.Errorf("error: %w", context.Canceled)))
+	assert.False(t, IsRetryable(errors.New("could not make dir")))
+}
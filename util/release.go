@@ -0,0 +1,318 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pdok/goas/pkg/models"
+)
+
+// ReleasesDir the directory (below the file destination or prefix) with a directory per release
+const ReleasesDir = "_releases"
+
+// currentRelease the pointer to the release that is live
+const currentRelease = ReleasesDir + "/current"
+
+// DefaultKeepReleases the default number of releases kept at the destination
+const DefaultKeepReleases = 5
+
+// Store a destination that can be written, read, listed and pruned, needed for releases
+type Store interface {
+	Syncer
+	Reader
+}
+
+// NewStore creates the Store for the storage destination of the context
+func NewStore(ctx *Context) (Store, error) {
+	writer, err := NewWriter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	store, ok := writer.(Store)
+	if !ok {
+		return nil, fmt.Errorf("storage destination %s does not support releases", ctx.StorageDestination)
+	}
+	return store, nil
+}
+
+// NewReleaseId the id of a release created at the given time (to the millisecond), release ids sort by time
+func NewReleaseId(now time.Time) string {
+	return now.UTC().Format("20060102T150405.000Z")
+}
+
+// releaseWriter writes the files of a release to _releases/{release}/ instead of the live paths
+type releaseWriter struct {
+	store   Store
+	release string
+}
+
+// NewReleaseWriter creates a Writer for the files of a new release, which is not live until it is promoted
+func NewReleaseWriter(store Store, release string) Writer {
+	return &releaseWriter{store, release}
+}
+
+func (w *releaseWriter) Write(filename string, buffer *bytes.Buffer, mediaType models.MediaType) error {
+	return w.store.Write(releasePath(w.release, filename), buffer, mediaType)
+}
+
+func (w *releaseWriter) Stats() WriteStats {
+	return w.store.Stats()
+}
+
+func releasePath(release string, filename string) string {
+	return ReleasesDir + "/" + release + "/" + filename
+}
+
+// livePath the path a file of a release is promoted to, or the path itself for other files
+func livePath(filename string) string {
+	parts := strings.SplitN(filename, "/", 3)
+	if len(parts) == 3 && parts[0] == ReleasesDir {
+		return parts[2]
+	}
+	return filename
+}
+
+// Releases returns the releases at the destination (oldest first) and the release that is live, if any
+func Releases(store Store) (releases []string, current string, err error) {
+	filenames, err := store.List()
+	if err != nil {
+		return nil, "", err
+	}
+	releaseSet := make(map[string]bool)
+	for _, filename := range filenames {
+		if !strings.HasPrefix(filename, ReleasesDir+"/") || filename == currentRelease {
+			continue
+		}
+		release := strings.SplitN(strings.TrimPrefix(filename, ReleasesDir+"/"), "/", 2)[0]
+		if !releaseSet[release] {
+			releaseSet[release] = true
+			releases = append(releases, release)
+		}
+	}
+	sort.Strings(releases)
+	pointer, _, err := store.Read(currentRelease)
+	if err != nil && err != ErrNotFound {
+		return nil, "", err
+	}
+	if pointer != nil {
+		current = strings.TrimSpace(pointer.String())
+	}
+	return releases, current, nil
+}
+
+// Promote makes a release live. The files of the release are copied to the live paths (skipping unchanged files), with
+// sync the live files that are not part of the release are deleted (within the sync threshold, like Sync) and finally
+// the pointer _releases/current is switched to the release. The live files are not switched atomically: when promoting
+// fails halfway the live files touched so far are restored to the previously current release before the error is
+// returned, when the process is killed halfway they stay half updated while the pointer still names the previous
+// release.
+func Promote(store Store, release string, sync bool, threshold float64) error {
+	filenames, err := store.List()
+	if err != nil {
+		return err
+	}
+	releaseFiles := filesOfRelease(filenames, release)
+	if releaseFiles == nil {
+		return fmt.Errorf("release %s not found", release)
+	}
+	_, previous, err := Releases(store)
+	if err != nil {
+		return err
+	}
+	live := liveFiles(filenames)
+	var stale []string
+	if sync {
+		stale = PlanSync(live, releaseFiles)
+		if len(stale) > 0 {
+			err = checkSyncThreshold(stale, live, threshold)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	log.Printf("promoting release %s with %d files", release, len(releaseFiles))
+	p := newPromotion(store, previous, filesOfRelease(filenames, previous), live)
+	for _, filename := range releaseFiles {
+		err = p.write(filename, release)
+		if err != nil {
+			return p.fail(release, err)
+		}
+	}
+	for _, filename := range stale {
+		err = p.delete(filename)
+		if err != nil {
+			return p.fail(release, err)
+		}
+	}
+	err = store.Write(currentRelease, bytes.NewBufferString(release+"\n"), "text/plain")
+	if err != nil {
+		return p.fail(release, err)
+	}
+	return nil
+}
+
+// filesOfRelease the (live) paths of the files of a release, nil when the release does not exist
+func filesOfRelease(filenames []string, release string) []string {
+	if release == "" {
+		return nil
+	}
+	releasePrefix := ReleasesDir + "/" + release + "/"
+	var releaseFiles []string
+	for _, filename := range filenames {
+		if strings.HasPrefix(filename, releasePrefix) {
+			releaseFiles = append(releaseFiles, strings.TrimPrefix(filename, releasePrefix))
+		}
+	}
+	return releaseFiles
+}
+
+// liveBackup the content of a live file that is not part of the previous release, kept to restore it
+type liveBackup struct {
+	content   *bytes.Buffer
+	mediaType models.MediaType
+}
+
+// promotion tracks the live files touched by promoting a release, to restore them when promoting fails
+type promotion struct {
+	store         Store
+	previous      string          // the previously current release, empty without one
+	previousFiles map[string]bool // the files of the previous release
+	existing      map[string]bool // the live files before promoting
+	touched       []string
+	backups       map[string]liveBackup
+}
+
+func newPromotion(store Store, previous string, previousFiles []string, liveFiles []string) *promotion {
+	p := &promotion{store: store, previous: previous, previousFiles: make(map[string]bool),
+		existing: make(map[string]bool), backups: make(map[string]liveBackup)}
+	for _, filename := range previousFiles {
+		p.previousFiles[filename] = true
+	}
+	for _, filename := range liveFiles {
+		p.existing[filename] = true
+	}
+	return p
+}
+
+// touch registers a live file before it is overwritten or deleted, a live file that cannot be restored from the
+// previous release is backed up first
+func (p *promotion) touch(filename string) error {
+	if p.existing[filename] && !p.previousFiles[filename] {
+		content, mediaType, err := p.store.Read(filename)
+		if err != nil {
+			return err
+		}
+		p.backups[filename] = liveBackup{content, mediaType}
+	}
+	p.touched = append(p.touched, filename)
+	return nil
+}
+
+func (p *promotion) write(filename string, release string) error {
+	content, mediaType, err := p.store.Read(releasePath(release, filename))
+	if err != nil {
+		return err
+	}
+	err = p.touch(filename)
+	if err != nil {
+		return err
+	}
+	return p.store.Write(filename, content, mediaType)
+}
+
+func (p *promotion) delete(filename string) error {
+	err := p.touch(filename)
+	if err != nil {
+		return err
+	}
+	return p.store.Delete(filename)
+}
+
+// restore the touched live files from the previous release or their backup, the files that did not exist are deleted
+func (p *promotion) restore() error {
+	for _, filename := range p.touched {
+		var err error
+		if p.previousFiles[filename] {
+			var content *bytes.Buffer
+			var mediaType models.MediaType
+			content, mediaType, err = p.store.Read(releasePath(p.previous, filename))
+			if err == nil {
+				err = p.store.Write(filename, content, mediaType)
+			}
+		} else if backup, ok := p.backups[filename]; ok {
+			err = p.store.Write(filename, backup.content, backup.mediaType)
+		} else if _, _, readErr := p.store.Read(filename); readErr != ErrNotFound {
+			err = p.store.Delete(filename)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fail restores the live files and returns the error of promoting the release
+func (p *promotion) fail(release string, err error) error {
+	restoreErr := p.restore()
+	if restoreErr != nil {
+		return fmt.Errorf("promoting release %s failed: %s, restoring the live files failed as well, they are partially updated: %s", release, err, restoreErr)
+	}
+	if p.previous == "" {
+		return fmt.Errorf("promoting release %s failed, the live files are restored: %s", release, err)
+	}
+	return fmt.Errorf("promoting release %s failed, the live files are restored to release %s: %s", release, p.previous, err)
+}
+
+// Rollback promotes a previous release, by default the release before the current one
+func Rollback(store Store, release string, sync bool, threshold float64) error {
+	releases, current, err := Releases(store)
+	if err != nil {
+		return err
+	}
+	log.Printf("releases: %s, current: %s", strings.Join(releases, ", "), current)
+	if release == "" {
+		for _, previous := range releases {
+			if previous >= current && current != "" {
+				break
+			}
+			release = previous
+		}
+		if release == "" {
+			return fmt.Errorf("no release before the current release %s to roll back to", current)
+		}
+	}
+	return Promote(store, release, sync, threshold)
+}
+
+// PruneReleases deletes all but the newest releases, the current release is always kept
+func PruneReleases(store Store, keep int) error {
+	releases, current, err := Releases(store)
+	if err != nil || len(releases) <= keep {
+		return err
+	}
+	pruned := make(map[string]bool)
+	for _, release := range releases[:len(releases)-keep] {
+		if release != current {
+			pruned[release] = true
+		}
+	}
+	filenames, err := store.List()
+	if err != nil {
+		return err
+	}
+	for _, filename := range filenames {
+		parts := strings.SplitN(filename, "/", 3)
+		if len(parts) == 3 && parts[0] == ReleasesDir && pruned[parts[1]] {
+			err = store.Delete(filename)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
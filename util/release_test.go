@@ -0,0 +1,121 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pdok/goas/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeRelease(t *testing.T, store Store, release string, files map[string]string) {
+	writer := NewReleaseWriter(store, release)
+	for filename, content := range files {
+		assert.Nil(t, writer.Write(filename, bytes.NewBufferString(content), models.JsonMediaType))
+	}
+}
+
+func readFile(t *testing.T, store Store, filename string) string {
+	content, _, err := store.Read(filename)
+	if err == ErrNotFound {
+		return ""
+	}
+	assert.Nil(t, err)
+	return content.String()
+}
+
+func TestNewReleaseId(t *testing.T) {
+	assert.Equal(t, "20261017T093000.250Z", NewReleaseId(time.Date(2026, 10, 17, 11, 30, 0, 250e6, time.FixedZone("CEST", 2*60*60))))
+	assert.Equal(t, "20261017T093000.000Z", NewReleaseId(time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)), "fixed width, to sort by time")
+}
+
+func TestPromoteAndRollback(t *testing.T) {
+	store := &FileWriter{FileDestination: t.TempDir()}
+	writeRelease(t, store, "20261017T090000Z", map[string]string{"styles.json": "v1", "styles/old.sld": "old"})
+	assert.Nil(t, Promote(store, "20261017T090000Z", true, DefaultSyncThreshold))
+	writeRelease(t, store, "20261017T100000Z", map[string]string{"styles.json": "v2", "styles/night.sld": "night"})
+	assert.Equal(t, "v1", readFile(t, store, "styles.json"), "a release is not live until it is promoted")
+	assert.Nil(t, Promote(store, "20261017T100000Z", true, DefaultSyncThreshold))
+
+	releases, current, err := Releases(store)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"20261017T090000Z", "20261017T100000Z"}, releases)
+	assert.Equal(t, "20261017T100000Z", current)
+	assert.Equal(t, "v2", readFile(t, store, "styles.json"))
+	assert.Equal(t, "night", readFile(t, store, "styles/night.sld"))
+	assert.Equal(t, "", readFile(t, store, "styles/old.sld"))
+
+	assert.Nil(t, Rollback(store, "", true, DefaultSyncThreshold))
+	_, current, err = Releases(store)
+	assert.Nil(t, err)
+	assert.Equal(t, "20261017T090000Z", current)
+	assert.Equal(t, "v1", readFile(t, store, "styles.json"))
+	assert.Equal(t, "old", readFile(t, store, "styles/old.sld"))
+	assert.Equal(t, "", readFile(t, store, "styles/night.sld"))
+
+	assert.EqualError(t, Rollback(store, "", true, DefaultSyncThreshold), "no release before the current release 20261017T090000Z to roll back to")
+	assert.EqualError(t, Promote(store, "20261017T110000Z", true, DefaultSyncThreshold), "release 20261017T110000Z not found")
+}
+
+func TestPromoteWithoutSync(t *testing.T) {
+	store := &FileWriter{FileDestination: t.TempDir()}
+	writeRelease(t, store, "20261017T090000Z", map[string]string{"styles.json": "v1", "styles/old.sld": "old"})
+	assert.Nil(t, Promote(store, "20261017T090000Z", false, DefaultSyncThreshold))
+	writeRelease(t, store, "20261017T100000Z", map[string]string{"styles.json": "v2"})
+	assert.Nil(t, Promote(store, "20261017T100000Z", false, DefaultSyncThreshold))
+	assert.Equal(t, "old", readFile(t, store, "styles/old.sld"), "live files are only deleted with sync")
+
+	writeRelease(t, store, "20261017T110000Z", map[string]string{"index.json": "v3"})
+	assert.EqualError(t, Promote(store, "20261017T110000Z", true, DefaultSyncThreshold),
+		"sync aborted, deleting 2 of 2 files (100%) exceeds the sync threshold of 50%")
+	assert.Equal(t, "", readFile(t, store, "index.json"), "nothing is promoted when the sync threshold is exceeded")
+}
+
+// failingStore fails to write one file
+type failingStore struct {
+	Store
+	filename string
+}
+
+func (s *failingStore) Write(filename string, buffer *bytes.Buffer, mediaType models.MediaType) error {
+	if filename == s.filename {
+		return errors.New("connection reset")
+	}
+	return s.Store.Write(filename, buffer, mediaType)
+}
+
+func TestPromoteRestoresPreviousRelease(t *testing.T) {
+	files := &FileWriter{FileDestination: t.TempDir()}
+	assert.Nil(t, files.Write("index.html", bytes.NewBufferString("home"), models.HtmlMediaType))
+	writeRelease(t, files, "20261017T090000Z", map[string]string{"styles.json": "v1", "styles/old.sld": "old"})
+	assert.Nil(t, Promote(files, "20261017T090000Z", false, DefaultSyncThreshold))
+	writeRelease(t, files, "20261017T100000Z", map[string]string{"styles.json": "v2", "styles/night.sld": "night",
+		"index.html": "new home", "styles/z.sld": "z"})
+
+	store := &failingStore{files, "styles/z.sld"}
+	assert.EqualError(t, Promote(store, "20261017T100000Z", true, 1),
+		"promoting release 20261017T100000Z failed, the live files are restored to release 20261017T090000Z: connection reset")
+	_, current, err := Releases(store)
+	assert.Nil(t, err)
+	assert.Equal(t, "20261017T090000Z", current)
+	assert.Equal(t, "v1", readFile(t, store, "styles.json"))
+	assert.Equal(t, "old", readFile(t, store, "styles/old.sld"))
+	assert.Equal(t, "home", readFile(t, store, "index.html"), "a live file outside the previous release is restored")
+	assert.Equal(t, "", readFile(t, store, "styles/night.sld"), "a file that was not live is deleted again")
+}
+
+func TestPruneReleases(t *testing.T) {
+	store := &FileWriter{FileDestination: t.TempDir()}
+	for _, release := range []string{"20261017T090000Z", "20261017T100000Z", "20261017T110000Z"} {
+		writeRelease(t, store, release, map[string]string{"styles.json": release})
+	}
+	assert.Nil(t, Promote(store, "20261017T090000Z", false, DefaultSyncThreshold))
+
+	assert.Nil(t, PruneReleases(store, 1))
+	releases, current, err := Releases(store)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"20261017T090000Z", "20261017T110000Z"}, releases, "the current release is never pruned")
+	assert.Equal(t, "20261017T090000Z", current)
+}
@@ -0,0 +1,83 @@
+package util
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// DefaultSyncThreshold the default maximum fraction of the existing files a sync may delete
+const DefaultSyncThreshold = 0.5
+
+// PlanSync returns the existing files that are not generated (anymore), sorted. The releases are never part of the plan.
+func PlanSync(existing []string, generated []string) []string {
+	generatedSet := make(map[string]bool)
+	for _, filename := range generated {
+		generatedSet[filename] = true
+	}
+	var stale []string
+	for _, filename := range existing {
+		if !generatedSet[filename] && !strings.HasPrefix(filename, ReleasesDir+"/") {
+			stale = append(stale, filename)
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
+
+// liveFiles the files that are not part of the releases, the releases never count towards the sync threshold
+func liveFiles(filenames []string) []string {
+	var live []string
+	for _, filename := range filenames {
+		if !strings.HasPrefix(filename, ReleasesDir+"/") {
+			live = append(live, filename)
+		}
+	}
+	return live
+}
+
+// Sync deletes the files at the destination of the writer that are not generated. The deletion plan is printed first,
+// the sync fails without deleting anything when more than the threshold (a fraction of the existing files) would be
+// deleted, e.g. when goas runs with the wrong config or prefix.
+func Sync(writer Writer, generated []string, threshold float64) error {
+	syncer, ok := writer.(Syncer)
+	if !ok {
+		return fmt.Errorf("the storage destination does not support sync")
+	}
+	filenames, err := syncer.List()
+	if err != nil {
+		return err
+	}
+	existing := liveFiles(filenames)
+	stale := PlanSync(existing, generated)
+	if len(stale) == 0 {
+		log.Printf("sync: all %d existing files are generated, nothing to delete", len(existing))
+		return nil
+	}
+	err = checkSyncThreshold(stale, existing, threshold)
+	if err != nil {
+		return err
+	}
+	for _, filename := range stale {
+		err = syncer.Delete(filename)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSyncThreshold prints the deletion plan and fails when more than the threshold (a fraction of the existing files)
+// would be deleted
+func checkSyncThreshold(stale []string, existing []string, threshold float64) error {
+	log.Printf("sync: %d of %d existing files are not generated and will be deleted:", len(stale), len(existing))
+	for _, filename := range stale {
+		log.Printf("  - %s", filename)
+	}
+	fraction := float64(len(stale)) / float64(len(existing))
+	if fraction > threshold {
+		return fmt.Errorf("sync aborted, deleting %d of %d files (%.0f%%) exceeds the sync threshold of %.0f%%", len(stale), len(existing), fraction*100, threshold*100)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package util
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pdok/goas/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanSync(t *testing.T) {
+	existing := []string{"styles.json", "styles/old.sld", "styles/night.sld", "styles/old/metadata.json"}
+	generated := []string{"styles.json", "styles/night.sld", "styles/night/metadata.json"}
+	assert.Equal(t, []string{"styles/old.sld", "styles/old/metadata.json"}, PlanSync(existing, generated))
+}
+
+func TestSyncFileDestination(t *testing.T) {
+	writer := &FileWriter{FileDestination: t.TempDir()}
+	for _, filename := range []string{"styles.json", "styles/night.sld", "styles/old.sld", "styles/old/metadata.json"} {
+		assert.Nil(t, writer.Write(filename, bytes.NewBufferString("{}"), models.JsonMediaType))
+	}
+
+	err := Sync(writer, []string{"styles.json", "styles/night.sld"}, DefaultSyncThreshold)
+	assert.Nil(t, err)
+
+	filenames, err := writer.List()
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"styles.json", "styles/night.sld"}, filenames)
+	_, err = os.Stat(filepath.Join(writer.FileDestination, "styles/old"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSyncExceedsThreshold(t *testing.T) {
+	writer := &FileWriter{FileDestination: t.TempDir()}
+	for _, filename := range []string{"styles.json", "styles/night.sld", "styles/old.sld"} {
+		assert.Nil(t, writer.Write(filename, bytes.NewBufferString("{}"), models.JsonMediaType))
+	}
+
+	err := Sync(writer, []string{"styles.json"}, 0.5)
+	assert.EqualError(t, err, "sync aborted, deleting 2 of 3 files (67%) exceeds the sync threshold of 50%")
+
+	filenames, err := writer.List()
+	assert.Nil(t, err)
+	assert.Len(t, filenames, 3)
+}
+
+func TestSyncThresholdIgnoresReleases(t *testing.T) {
+	writer := &FileWriter{FileDestination: t.TempDir()}
+	for _, filename := range []string{"styles.json", "styles/night.sld", "_releases/a/styles.json", "_releases/a/styles/night.sld",
+		"_releases/b/styles.json", "_releases/b/styles/night.sld"} {
+		assert.Nil(t, writer.Write(filename, bytes.NewBufferString("{}"), models.JsonMediaType))
+	}
+
+	err := Sync(writer, []string{"index.json"}, 0.5)
+	assert.EqualError(t, err, "sync aborted, deleting 2 of 2 files (100%) exceeds the sync threshold of 50%")
+}
+
+func TestListMissingFileDestination(t *testing.T) {
+	writer := &FileWriter{FileDestination: filepath.Join(t.TempDir(), "missing")}
+	filenames, err := writer.List()
+	assert.Nil(t, err)
+	assert.Empty(t, filenames)
+}
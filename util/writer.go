@@ -3,40 +3,158 @@ package util
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 	"github.com/pdok/goas/pkg/models"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 )
 
 type Writer interface {
 	Write(filename string, buffer *bytes.Buffer, mediaType models.MediaType) error
+	// Stats returns the number of files written and skipped, since they were unchanged
+	Stats() WriteStats
 }
 
+// WriteStats counts the files written by a Writer and the files skipped, since the file at the destination has the same
+// content (and media type)
+type WriteStats struct {
+	Written int
+	Skipped int
+}
+
+// writeCounter counts the WriteStats of a Writer, safe for concurrent writes
+type writeCounter struct {
+	mutex sync.Mutex
+	stats WriteStats
+}
+
+func (c *writeCounter) written() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.stats.Written++
+}
+
+func (c *writeCounter) skipped() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.stats.Skipped++
+}
+
+func (c *writeCounter) get() WriteStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.stats
+}
+
+// checksumMetadata the user metadata of S3 objects with the MD5 checksum of the content, the ETag is only the MD5
+// checksum for objects uploaded in a single part without KMS encryption
+const checksumMetadata = "Goas-Md5"
+
+// headersMetadata the user metadata with the checksum of the headers (from the publish rules) an object is written with,
+// so an object is also written again when only its headers changed
+const headersMetadata = "Goas-Headers"
+
+// azureHeadersMetadata headersMetadata on Azure Blob, where metadata keys should be C# identifiers
+const azureHeadersMetadata = "GoasHeaders"
+
+// headersChecksum the MD5 checksum of the headers, empty when there are no headers
+func headersChecksum(headers models.ObjectHeaders) string {
+	if headers.CacheControl == "" && headers.ContentEncoding == "" && len(headers.Metadata) == 0 && len(headers.Tags) == 0 {
+		return ""
+	}
+	// maps are printed sorted by key
+	checksum := md5.Sum([]byte(fmt.Sprintf("%q %q %v %v", headers.CacheControl, headers.ContentEncoding, headers.Metadata, headers.Tags)))
+	return hex.EncodeToString(checksum[:])
+}
+
+// userMetadata the metadata of the publish rules, with the checksums goas uses to skip unchanged objects
+func userMetadata(headers models.ObjectHeaders, checksumKey string, checksum string, headersKey string) map[string]string {
+	metadata := map[string]string{}
+	for key, value := range headers.Metadata {
+		metadata[key] = value
+	}
+	if checksumKey != "" {
+		metadata[checksumKey] = checksum
+	}
+	if headersChecksum := headersChecksum(headers); headersChecksum != "" {
+		metadata[headersKey] = headersChecksum
+	}
+	return metadata
+}
+
+// lookupMetadata a metadata value by case-insensitive key, since the keys are returned canonicalized as HTTP headers
+func lookupMetadata(metadata map[string]string, key string) string {
+	for k, value := range metadata {
+		if strings.EqualFold(k, key) {
+			return value
+		}
+	}
+	return ""
+}
+
+// Lister lists the files at the destination of a Writer
+type Lister interface {
+	// List returns the filenames (relative to the destination, like the filenames written) of all files at the destination
+	List() ([]string, error)
+}
+
+// Syncer a Writer that can list and delete the files at its destination, used to prune files that are not generated anymore
+type Syncer interface {
+	Writer
+	Lister
+	Delete(filename string) error
+}
+
+// Reader the read counterpart of a Writer, used to compare the generated documents with the files at the destination
+type Reader interface {
+	Lister
+	// Read returns the content and media type (if the destination keeps it) of a file, or ErrNotFound
+	Read(filename string) (*bytes.Buffer, models.MediaType, error)
+}
+
+// ErrNotFound the file does not exist at the destination
+var ErrNotFound = errors.New("file not found")
+
 type S3Writer struct {
 	minioClient *minio.Client
 	s3Bucket    string
 	s3Prefix    string
+	rules       models.PublishRules
 	ctx         context.Context
+	stats       writeCounter
 }
 
 type AzureBlobWriter struct {
-	blobClient    *azblob.Client
-	blobContainer string
-	blobPrefix    string
-	ctx           context.Context
+	blobClient      *azblob.Client
+	containerClient *container.Client
+	blobContainer   string
+	blobPrefix      string
+	rules           models.PublishRules
+	ctx             context.Context
+	stats           writeCounter
 }
 
 type FileWriter struct {
 	FileDestination string
+	stats           writeCounter
 }
 
-func (m S3Writer) Write(filename string, buffer *bytes.Buffer, mediaType models.MediaType) error {
+func (m *S3Writer) Write(filename string, buffer *bytes.Buffer, mediaType models.MediaType) error {
 	key := m.s3Prefix + filename
 	var opts minio.PutObjectOptions
 	if mediaType != "" {
@@ -44,32 +162,188 @@ func (m S3Writer) Write(filename string, buffer *bytes.Buffer, mediaType models.
 	} else {
 		opts = minio.PutObjectOptions{}
 	}
+	headers := m.rules.Headers(livePath(filename))
+	checksum := md5.Sum(buffer.Bytes())
+	if m.unchanged(key, hex.EncodeToString(checksum[:]), mediaType, headers) {
+		log.Printf("skipping unchanged S3 object: %s", key)
+		m.stats.skipped()
+		return nil
+	}
+	opts.CacheControl = headers.CacheControl
+	opts.ContentEncoding = string(headers.ContentEncoding)
+	opts.UserTags = headers.Tags
+	opts.UserMetadata = userMetadata(headers, checksumMetadata, hex.EncodeToString(checksum[:]), headersMetadata)
+	// a single PUT of the (not seekable) buffer is not retried by minio, writeWithRetry retries it
+	opts.DisableMultipart = true
 	log.Printf("writing to S3: %s with mediaType: %s", key, mediaType)
 	_, err := m.minioClient.PutObject(m.ctx, m.s3Bucket, key, buffer, int64(buffer.Len()), opts)
 	if err != nil {
-		return fmt.Errorf("error: %s, could not write file %s to S3", err, filename)
+		return fmt.Errorf("error: %w, could not write file %s to S3", err, filename)
 	}
+	m.stats.written()
 	return nil
 }
 
-func (m AzureBlobWriter) Write(filename string, buffer *bytes.Buffer, mediaType models.MediaType) error {
+// unchanged checks the existing object has the same checksum (from its metadata or else its ETag), media type and headers
+func (m *S3Writer) unchanged(key string, checksum string, mediaType models.MediaType, headers models.ObjectHeaders) bool {
+	info, err := m.minioClient.StatObject(m.ctx, m.s3Bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return false
+	}
+	existing, ok := info.UserMetadata[checksumMetadata]
+	if !ok {
+		existing = strings.Trim(info.ETag, `"`)
+	}
+	sameHeaders := lookupMetadata(info.UserMetadata, headersMetadata) == headersChecksum(headers)
+	return existing == checksum && (mediaType == "" || info.ContentType == string(mediaType)) && sameHeaders
+}
+
+func (m *S3Writer) Stats() WriteStats {
+	return m.stats.get()
+}
+
+func (m *S3Writer) List() ([]string, error) {
+	var filenames []string
+	for object := range m.minioClient.ListObjects(m.ctx, m.s3Bucket, minio.ListObjectsOptions{Prefix: m.s3Prefix, Recursive: true}) {
+		if object.Err != nil {
+			return nil, fmt.Errorf("error: %s, could not list files on S3", object.Err)
+		}
+		filenames = append(filenames, strings.TrimPrefix(object.Key, m.s3Prefix))
+	}
+	return filenames, nil
+}
+
+func (m *S3Writer) Read(filename string) (*bytes.Buffer, models.MediaType, error) {
+	key := m.s3Prefix + filename
+	info, err := m.minioClient.StatObject(m.ctx, m.s3Bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, "", ErrNotFound
+		}
+		return nil, "", fmt.Errorf("error: %s, could not read file %s from S3", err, filename)
+	}
+	object, err := m.minioClient.GetObject(m.ctx, m.s3Bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, "", fmt.Errorf("error: %s, could not read file %s from S3", err, filename)
+	}
+	defer object.Close()
+	var content bytes.Buffer
+	_, err = content.ReadFrom(object)
+	if err != nil {
+		return nil, "", fmt.Errorf("error: %s, could not read file %s from S3", err, filename)
+	}
+	return &content, models.MediaType(info.ContentType), nil
+}
+
+func (m *S3Writer) Delete(filename string) error {
+	key := m.s3Prefix + filename
+	log.Printf("deleting from S3: %s", key)
+	err := m.minioClient.RemoveObject(m.ctx, m.s3Bucket, key, minio.RemoveObjectOptions{})
+	if err != nil {
+		return fmt.Errorf("error: %s, could not delete file %s from S3", err, filename)
+	}
+	return nil
+}
+
+func (m *AzureBlobWriter) Write(filename string, buffer *bytes.Buffer, mediaType models.MediaType) error {
 	key := m.blobPrefix + filename
-	var opts azblob.UploadBufferOptions
+	headers := m.rules.Headers(livePath(filename))
+	checksum := md5.Sum(buffer.Bytes())
+	if m.unchanged(key, checksum[:], mediaType, headers) {
+		log.Printf("skipping unchanged Azure Blob: %s", key)
+		m.stats.skipped()
+		return nil
+	}
+	opts := azblob.UploadBufferOptions{
+		HTTPHeaders: &blob.HTTPHeaders{BlobContentMD5: checksum[:]},
+		Metadata:    userMetadata(headers, "", "", azureHeadersMetadata),
+		Tags:        headers.Tags,
+	}
 	if mediaType != "" {
 		contentType := string(mediaType)
-		opts = azblob.UploadBufferOptions{HTTPHeaders: &blob.HTTPHeaders{BlobContentType: &contentType}}
-	} else {
-		opts = azblob.UploadBufferOptions{}
+		opts.HTTPHeaders.BlobContentType = &contentType
+	}
+	if headers.CacheControl != "" {
+		opts.HTTPHeaders.BlobCacheControl = &headers.CacheControl
+	}
+	if headers.ContentEncoding != "" {
+		contentEncoding := string(headers.ContentEncoding)
+		opts.HTTPHeaders.BlobContentEncoding = &contentEncoding
 	}
 	log.Printf("writing to Azure Blob: %s with mediaType: %s", key, mediaType)
-	_, err := m.blobClient.UploadBuffer(m.ctx, m.blobContainer, key, buffer.Bytes(), &opts)
+	// the SDK does not retry the upload, writeWithRetry retries it
+	ctx := runtime.WithRetryOptions(m.ctx, policy.RetryOptions{MaxRetries: -1})
+	_, err := m.blobClient.UploadBuffer(ctx, m.blobContainer, key, buffer.Bytes(), &opts)
+	if err != nil {
+		return fmt.Errorf("error: %w, could not write file %s to Azure Blob", err, filename)
+	}
+	m.stats.written()
+	return nil
+}
+
+// unchanged checks the existing blob has the same Content-MD5, media type and headers
+func (m *AzureBlobWriter) unchanged(key string, checksum []byte, mediaType models.MediaType, headers models.ObjectHeaders) bool {
+	properties, err := m.containerClient.NewBlobClient(key).GetProperties(m.ctx, nil)
+	if err != nil {
+		return false
+	}
+	sameType := mediaType == "" || (properties.ContentType != nil && *properties.ContentType == string(mediaType))
+	sameHeaders := lookupMetadata(properties.Metadata, azureHeadersMetadata) == headersChecksum(headers)
+	return bytes.Equal(properties.ContentMD5, checksum) && sameType && sameHeaders
+}
+
+func (m *AzureBlobWriter) Stats() WriteStats {
+	return m.stats.get()
+}
+
+func (m *AzureBlobWriter) List() ([]string, error) {
+	var filenames []string
+	pager := m.blobClient.NewListBlobsFlatPager(m.blobContainer, &azblob.ListBlobsFlatOptions{Prefix: &m.blobPrefix})
+	for pager.More() {
+		page, err := pager.NextPage(m.ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error: %s, could not list files on Azure Blob", err)
+		}
+		for _, item := range page.Segment.BlobItems {
+			filenames = append(filenames, strings.TrimPrefix(*item.Name, m.blobPrefix))
+		}
+	}
+	return filenames, nil
+}
+
+func (m *AzureBlobWriter) Read(filename string) (*bytes.Buffer, models.MediaType, error) {
+	key := m.blobPrefix + filename
+	response, err := m.blobClient.DownloadStream(m.ctx, m.blobContainer, key, nil)
 	if err != nil {
-		return fmt.Errorf("error: %s, could not write file %s to Azure Blob", err, filename)
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return nil, "", ErrNotFound
+		}
+		return nil, "", fmt.Errorf("error: %s, could not read file %s from Azure Blob", err, filename)
+	}
+	defer response.Body.Close()
+	var content bytes.Buffer
+	_, err = content.ReadFrom(response.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("error: %s, could not read file %s from Azure Blob", err, filename)
+	}
+	var mediaType models.MediaType
+	if response.ContentType != nil {
+		mediaType = models.MediaType(*response.ContentType)
+	}
+	return &content, mediaType, nil
+}
+
+func (m *AzureBlobWriter) Delete(filename string) error {
+	key := m.blobPrefix + filename
+	log.Printf("deleting from Azure Blob: %s", key)
+	_, err := m.blobClient.DeleteBlob(m.ctx, m.blobContainer, key, nil)
+	if err != nil {
+		return fmt.Errorf("error: %s, could not delete file %s from Azure Blob", err, filename)
 	}
 	return nil
 }
 
-func (f FileWriter) makeDirIfNotExists(path string) error {
+func (f *FileWriter) makeDirIfNotExists(path string) error {
 	dir, _ := filepath.Split(path)
 	err := os.MkdirAll(dir, os.ModePerm)
 
@@ -80,30 +354,87 @@ func (f FileWriter) makeDirIfNotExists(path string) error {
 	}
 }
 
-func (f FileWriter) Write(path string, buffer *bytes.Buffer, _ models.MediaType) error {
+func (f *FileWriter) Write(path string, buffer *bytes.Buffer, _ models.MediaType) error {
 	filename := filepath.Join(f.FileDestination, path)
 	err := f.makeDirIfNotExists(filename)
 	if err != nil {
 		return fmt.Errorf("could not make dir for: %s : %s ", filename, err.Error())
 	}
-	if _, err := os.Stat(filename); os.IsExist(err) {
-		if err != nil {
-			return err
-		}
+	if existing, err := os.ReadFile(filename); err == nil && bytes.Equal(existing, buffer.Bytes()) {
+		log.Printf("skipping unchanged: %s", filename)
+		f.stats.skipped()
+		return nil
 	}
 	log.Printf("writing: %s", filename)
 	fileWriter, err := os.Create(filename)
 	if err != nil {
 		return fmt.Errorf("could create file %s : %s ", filename, err.Error())
 	}
+	defer fileWriter.Close()
 	_, err = buffer.WriteTo(fileWriter)
 	if err != nil {
 		return fmt.Errorf("could not write to file %s : %s ", filename, err.Error())
 	}
+	f.stats.written()
+	return nil
+}
+
+func (f *FileWriter) Stats() WriteStats {
+	return f.stats.get()
+}
+
+func (f *FileWriter) List() ([]string, error) {
+	var filenames []string
+	err := filepath.WalkDir(f.FileDestination, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == f.FileDestination {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		filename, err := filepath.Rel(f.FileDestination, path)
+		if err != nil {
+			return err
+		}
+		filenames = append(filenames, filepath.ToSlash(filename))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list files in %s : %s ", f.FileDestination, err.Error())
+	}
+	return filenames, nil
+}
+
+// Read returns the content of a file, without media type since files on disk have none
+func (f *FileWriter) Read(path string) (*bytes.Buffer, models.MediaType, error) {
+	filename := filepath.Join(f.FileDestination, path)
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", ErrNotFound
+		}
+		return nil, "", fmt.Errorf("could not read file %s : %s ", filename, err.Error())
+	}
+	return bytes.NewBuffer(content), "", nil
+}
+
+func (f *FileWriter) Delete(path string) error {
+	filename := filepath.Join(f.FileDestination, path)
+	log.Printf("deleting: %s", filename)
+	err := os.Remove(filename)
+	if err != nil {
+		return fmt.Errorf("could not delete file %s : %s ", filename, err.Error())
+	}
+	// remove the directories left empty, removing a directory that is not empty fails
+	for dir := filepath.Dir(filename); dir != filepath.Clean(f.FileDestination) && os.Remove(dir) == nil; dir = filepath.Dir(dir) {
+	}
 	return nil
 }
 
-func newS3Writer(s3Endpoint string, s3AccessKey string, s3SecretKey string, s3Bucket string, s3Prefix string, s3Secure bool) (Writer, error) {
+func newS3Writer(ctx context.Context, s3Endpoint string, s3AccessKey string, s3SecretKey string, s3Bucket string, s3Prefix string, s3Secure bool, rules models.PublishRules) (Writer, error) {
 	minioClient, err := minio.New(s3Endpoint, &minio.Options{
 		Creds:  credentials.NewStaticV4(s3AccessKey, s3SecretKey, ""),
 		Secure: s3Secure,
@@ -112,36 +443,49 @@ func newS3Writer(s3Endpoint string, s3AccessKey string, s3SecretKey string, s3Bu
 		return nil, err
 	}
 	return &S3Writer{
-		minioClient,
-		s3Bucket,
-		s3Prefix,
-		context.Background(),
+		minioClient: minioClient,
+		s3Bucket:    s3Bucket,
+		s3Prefix:    s3Prefix,
+		rules:       rules,
+		ctx:         ctx,
 	}, nil
 }
 
-func newAzureBlobWriter(connectionString string, container string, prefix string) (Writer, error) {
+func newAzureBlobWriter(ctx context.Context, connectionString string, containerName string, prefix string, rules models.PublishRules) (Writer, error) {
 	blobClient, err := azblob.NewClientFromConnectionString(connectionString, nil)
 	if err != nil {
 		return nil, err
 	}
+	containerClient, err := container.NewClientFromConnectionString(connectionString, containerName, nil)
+	if err != nil {
+		return nil, err
+	}
 	return &AzureBlobWriter{
-		blobClient,
-		container,
-		prefix,
-		context.Background(),
+		blobClient:      blobClient,
+		containerClient: containerClient,
+		blobContainer:   containerName,
+		blobPrefix:      prefix,
+		rules:           rules,
+		ctx:             ctx,
 	}, nil
 }
 
 func NewWriter(ctx *Context) (writer Writer, err error) {
+	writerCtx := ctx.Ctx
+	if writerCtx == nil {
+		writerCtx = context.Background()
+	}
 	if ctx.StorageDestination == FILE {
-		writer = &FileWriter{*ctx.FileDestination}
+		writer = &FileWriter{FileDestination: *ctx.FileDestination}
+	} else if ctx.StorageDestination == ARCHIVE {
+		writer = &ArchiveWriter{ArchiveDestination: *ctx.ArchiveDestination}
 	} else if ctx.StorageDestination == S3 {
-		writer, err = newS3Writer(ctx.S3.Endpoint, ctx.S3.AccessKey, ctx.S3.SecretKey, ctx.S3.Bucket, ctx.S3.Prefix, ctx.S3.Secure)
+		writer, err = newS3Writer(writerCtx, ctx.S3.Endpoint, ctx.S3.AccessKey, ctx.S3.SecretKey, ctx.S3.Bucket, ctx.S3.Prefix, ctx.S3.Secure, ctx.Publish)
 		if err != nil {
 			return nil, err
 		}
 	} else if ctx.StorageDestination == AZURE_BLOB {
-		writer, err = newAzureBlobWriter(ctx.AzureBlob.ConnectionString, ctx.AzureBlob.Container, ctx.AzureBlob.Prefix)
+		writer, err = newAzureBlobWriter(writerCtx, ctx.AzureBlob.ConnectionString, ctx.AzureBlob.Container, ctx.AzureBlob.Prefix, ctx.Publish)
 		if err != nil {
 			return nil, err
 		}
@@ -150,3 +494,16 @@ func NewWriter(ctx *Context) (writer Writer, err error) {
 	}
 	return writer, nil
 }
+
+// NewReader creates the Reader for the storage destination of the context
+func NewReader(ctx *Context) (Reader, error) {
+	writer, err := NewWriter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reader, ok := writer.(Reader)
+	if !ok {
+		return nil, fmt.Errorf("storage destination %s can not be read", ctx.StorageDestination)
+	}
+	return reader, nil
+}
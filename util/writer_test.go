@@ -8,8 +8,13 @@ import (
 	"github.com/docker/go-connections/nat"
 	"github.com/pdok/goas/pkg/models"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 )
 
@@ -35,17 +40,18 @@ func TestWriteToAzureBlob(t *testing.T) {
 	}()
 	blobClient := setupBlobs(t, port, ctx)
 
-	storageDest, _, _, azureBlobContext, err := initStorage("", "", "", "", "", "", false, getConnectionString(port), bucket, "foo")
+	storageDest, _, _, _, azureBlobContext, err := initStorage("", "", "", "", "", "", "", false, getConnectionString(port), bucket, "foo")
 	if err != nil {
 		t.Fatalf("Failed to init storage")
 	}
-	writer, err := NewWriter(&Context{nil, &azureBlobContext, nil, storageDest, "", "", nil})
+	writer, err := NewWriter(&Context{nil, &azureBlobContext, nil, nil, storageDest, "", "", nil, false, DefaultSyncThreshold, false, DefaultKeepReleases, DefaultConcurrency, DefaultRetries, nil, "", nil})
 	if err != nil {
 		t.Fatalf("Failed to init writer")
 	}
 
 	// when
 	writer.Write("bar.json", bytes.NewBuffer(expected), models.JsonMediaType)
+	writer.Write("bar.json", bytes.NewBuffer(expected), models.JsonMediaType)
 
 	// then
 	var actual = make([]byte, len(expected))
@@ -54,6 +60,45 @@ func TestWriteToAzureBlob(t *testing.T) {
 		t.Fatalf("error %v", err)
 	}
 	assert.Equal(t, expected, actual)
+	assert.Equal(t, WriteStats{Written: 1, Skipped: 1}, writer.Stats())
+}
+
+func TestWriteFileSkipsUnchanged(t *testing.T) {
+	writer := &FileWriter{FileDestination: t.TempDir()}
+	assert.Nil(t, writer.Write("styles.json", bytes.NewBufferString(`{"styles": []}`), models.JsonMediaType))
+	assert.Nil(t, writer.Write("styles.json", bytes.NewBufferString(`{"styles": []}`), models.JsonMediaType))
+	assert.Nil(t, writer.Write("styles.json", bytes.NewBufferString(`{"styles": [{"id": "night"}]}`), models.JsonMediaType))
+	assert.Equal(t, WriteStats{Written: 2, Skipped: 1}, writer.Stats())
+}
+
+func TestWritersDoNotRetryWrites(t *testing.T) {
+	var writes int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			atomic.AddInt32(&writes, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case http.MethodGet:
+			// the location of the S3 bucket
+			w.Write([]byte(`<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/"></LocationConstraint>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	port := nat.Port(server.URL[strings.LastIndex(server.URL, ":")+1:])
+
+	s3Writer, err := newS3Writer(gocontext.Background(), strings.TrimPrefix(server.URL, "http://"), "key", "secret", bucket, "", false, nil)
+	require.Nil(t, err)
+	azureWriter, err := newAzureBlobWriter(gocontext.Background(), getConnectionString(port), bucket, "", nil)
+	require.Nil(t, err)
+	for _, writer := range []Writer{s3Writer, azureWriter} {
+		atomic.StoreInt32(&writes, 0)
+		err = writer.Write("styles.json", bytes.NewBufferString(`{"styles": []}`), models.JsonMediaType)
+		require.NotNil(t, err)
+		assert.True(t, IsRetryable(err), "a server error is retried by writeWithRetry")
+		assert.Equal(t, int32(1), atomic.LoadInt32(&writes), "%T retried the write itself", writer)
+	}
 }
 
 func setupBlobs(t *testing.T, port nat.Port, ctx gocontext.Context) *azblob.Client {
@@ -95,3 +140,15 @@ func setupAzurite(t *testing.T, ctx gocontext.Context) (nat.Port, testcontainers
 	}
 	return port, container, err
 }
+
+func TestUserMetadata(t *testing.T) {
+	rules := models.PublishRules{{Path: "styles/**", CacheControl: "public, max-age=60", Metadata: map[string]string{"team": "geo"}}}
+	headers := rules.Headers(livePath("_releases/20261017T093000Z/styles/night.json"))
+	assert.Equal(t, "public, max-age=60", headers.CacheControl)
+
+	metadata := userMetadata(headers, checksumMetadata, "8d7f", headersMetadata)
+	assert.Equal(t, "geo", metadata["team"])
+	assert.Equal(t, "8d7f", metadata[checksumMetadata])
+	assert.Equal(t, headersChecksum(headers), lookupMetadata(map[string]string{"Goas-headers": metadata[headersMetadata]}, headersMetadata))
+	assert.Equal(t, "", headersChecksum(rules.Headers("index.json")), "objects without headers have no headers checksum")
+}